@@ -0,0 +1,27 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+)
+
+// RequireToken rejects all requests not carrying the token as bearer token.
+// An empty token disables the check
+func RequireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+
+	expected := []byte("Bearer " + token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/service"
+)
+
+var errMissingName = errors.New("name must not be empty")
+
+func (s *Server) getLists(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := page(r)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := r.URL.Query().Get("name")
+
+	// without the name filter only the requested page has to be read
+	if name == "" {
+		items, total, err := s.storage.GetListsPage(r.Context(), offset, limit)
+
+		if err != nil {
+			writeStorageError(w, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, &Page{
+			Items:  items,
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		})
+
+		return
+	}
+
+	all, err := s.storage.GetLists(r.Context())
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	matching := []*lists.List{}
+
+	for _, list := range all {
+		if strings.Contains(strings.ToLower(list.Name), strings.ToLower(name)) {
+			matching = append(matching, list)
+		}
+	}
+
+	start, end := service.Bounds(len(matching), offset, limit)
+
+	writeJSON(w, http.StatusOK, &Page{
+		Items:  matching[start:end],
+		Total:  len(matching),
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (s *Server) createList(w http.ResponseWriter, r *http.Request) {
+	list := &lists.List{}
+	err := readJSON(w, r, list)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if strings.TrimSpace(list.Name) == "" {
+		writeError(w, http.StatusBadRequest, errMissingName)
+		return
+	}
+
+	list, err = s.storage.StoreList(r.Context(), list)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, list)
+}
+
+func (s *Server) getList(w http.ResponseWriter, r *http.Request, id string) {
+	list, err := s.storage.GetList(r.Context(), id)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) updateList(w http.ResponseWriter, r *http.Request, id string) {
+	existing, err := s.storage.GetList(r.Context(), id)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	list := &lists.List{}
+	err = readJSON(w, r, list)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if strings.TrimSpace(list.Name) == "" {
+		writeError(w, http.StatusBadRequest, errMissingName)
+		return
+	}
+
+	list.ID = existing.ID
+	list.CreatedAt = existing.CreatedAt
+
+	list, err = s.storage.UpdateList(r.Context(), list)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) deleteList(w http.ResponseWriter, r *http.Request, id string) {
+	err := s.storage.DeleteList(r.Context(), id)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteLists(w http.ResponseWriter, r *http.Request) {
+	err := s.storage.DeleteLists(r.Context())
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,195 @@
+// Package api contains the versioned JSON REST API served by "go2todo serve"
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+var errInvalidPage = errors.New("limit and offset must be positive numbers")
+
+type Server struct {
+	storage service.Interface
+}
+
+func NewServer(storage service.Interface) *Server {
+	return &Server{
+		storage: storage,
+	}
+}
+
+// Page is the envelope of all collection responses
+type Page struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP routes the request, all routes live below /v1/
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/v1/openapi.yaml" {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPISpec)
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, "/v1/") {
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+		return
+	}
+
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/"), "/"), "/")
+
+	switch {
+	case len(path) == 1 && path[0] == "lists":
+		s.routeMethod(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:    s.getLists,
+			http.MethodPost:   s.createList,
+			http.MethodDelete: s.deleteLists,
+		})
+
+	case len(path) == 2 && path[0] == "lists":
+		s.routeMethod(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:    func(w http.ResponseWriter, r *http.Request) { s.getList(w, r, path[1]) },
+			http.MethodPut:    func(w http.ResponseWriter, r *http.Request) { s.updateList(w, r, path[1]) },
+			http.MethodDelete: func(w http.ResponseWriter, r *http.Request) { s.deleteList(w, r, path[1]) },
+		})
+
+	case len(path) == 3 && path[0] == "lists" && path[2] == "tasks":
+		s.routeMethod(w, r, map[string]http.HandlerFunc{
+			http.MethodGet:    func(w http.ResponseWriter, r *http.Request) { s.getListTasks(w, r, path[1]) },
+			http.MethodPost:   func(w http.ResponseWriter, r *http.Request) { s.createTask(w, r, path[1]) },
+			http.MethodDelete: func(w http.ResponseWriter, r *http.Request) { s.deleteListTasks(w, r, path[1]) },
+		})
+
+	case len(path) == 1 && path[0] == "tasks":
+		s.routeMethod(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: s.getTasks,
+		})
+
+	case len(path) == 2 && path[0] == "tasks":
+		handlers := map[string]http.HandlerFunc{
+			http.MethodGet:    func(w http.ResponseWriter, r *http.Request) { s.getTask(w, r, path[1]) },
+			http.MethodPut:    func(w http.ResponseWriter, r *http.Request) { s.updateTask(w, r, path[1]) },
+			http.MethodDelete: func(w http.ResponseWriter, r *http.Request) { s.deleteTask(w, r, path[1]) },
+		}
+
+		// tasks are created in their list, so POST is free for the batch and a task named batch stays reachable
+		if path[1] == "batch" {
+			handlers[http.MethodPost] = s.applyTasks
+		}
+
+		s.routeMethod(w, r, handlers)
+
+	case len(path) == 1 && path[0] == "watch":
+		s.routeMethod(w, r, map[string]http.HandlerFunc{
+			http.MethodGet: s.watch,
+		})
+
+	default:
+		writeError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+func (s *Server) routeMethod(w http.ResponseWriter, r *http.Request, handlers map[string]http.HandlerFunc) {
+	handler, ok := handlers[r.Method]
+
+	if !ok {
+		writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+		return
+	}
+
+	handler(w, r)
+}
+
+// page reads the limit and offset query parameters
+func page(r *http.Request) (int, int, error) {
+	limit, offset := defaultLimit, 0
+
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+
+		if err != nil || parsed <= 0 {
+			return 0, 0, errInvalidPage
+		}
+
+		limit = parsed
+	}
+
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	if value := r.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.Atoi(value)
+
+		if err != nil || parsed < 0 {
+			return 0, 0, errInvalidPage
+		}
+
+		offset = parsed
+	}
+
+	return limit, offset, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, &errorBody{Error: err.Error()})
+}
+
+// writeStorageError maps the errors of the storage layer to status codes
+func writeStorageError(w http.ResponseWriter, err error) {
+	if errors.Is(err, tasks.ErrNotFound) || errors.Is(err, lists.ErrNotFound) {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	veto := &service.VetoError{}
+
+	if errors.As(err, &veto) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+
+	syntax := &query.SyntaxError{}
+
+	if errors.Is(err, service.ErrSmartList) || errors.As(err, &syntax) {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeError(w, http.StatusInternalServerError, err)
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	defer r.Body.Close()
+	return json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,386 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julez-dev/go2todo/api"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service/fake"
+)
+
+const token = "secret"
+
+type listsPage struct {
+	Items  []*lists.List `json:"items"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+type tasksPage struct {
+	Items  []*tasks.Task `json:"items"`
+	Total  int           `json:"total"`
+	Limit  int           `json:"limit"`
+	Offset int           `json:"offset"`
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func newServer(t *testing.T) (*httptest.Server, *fake.Service) {
+	t.Helper()
+
+	storage := fake.New()
+	server := httptest.NewServer(api.RequireToken(token, api.NewServer(storage)))
+	t.Cleanup(server.Close)
+
+	return server, storage
+}
+
+// do sends the request with the token, decodes the JSON response into out and returns the status
+func do(t *testing.T, server *httptest.Server, method, path, body string, out interface{}) int {
+	t.Helper()
+
+	var reader io.Reader
+
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, server.URL+path, reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := server.Client().Do(req)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer resp.Body.Close()
+
+	if out != nil {
+		err = json.NewDecoder(resp.Body).Decode(out)
+
+		if err != nil {
+			t.Fatalf("%s %s: decoding the response: %v", method, path, err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func TestLists(t *testing.T) {
+	server, _ := newServer(t)
+
+	created := &lists.List{}
+
+	if status := do(t, server, http.MethodPost, "/v1/lists", `{"name": "Work"}`, created); status != http.StatusCreated {
+		t.Fatalf("POST /v1/lists = %d, want 201", status)
+	}
+
+	if created.ID == "" || created.Name != "Work" {
+		t.Fatalf("created = %+v, want Work with an ID", created)
+	}
+
+	got := &lists.List{}
+
+	if status := do(t, server, http.MethodGet, "/v1/lists/"+created.ID, "", got); status != http.StatusOK || got.Name != "Work" {
+		t.Errorf("GET = %d %+v, want 200 Work", status, got)
+	}
+
+	updated := &lists.List{}
+
+	if status := do(t, server, http.MethodPut, "/v1/lists/"+created.ID, `{"name": "Office"}`, updated); status != http.StatusOK || updated.Name != "Office" || updated.ID != created.ID {
+		t.Errorf("PUT = %d %+v, want 200 Office", status, updated)
+	}
+
+	if status := do(t, server, http.MethodDelete, "/v1/lists/"+created.ID, "", nil); status != http.StatusNoContent {
+		t.Errorf("DELETE = %d, want 204", status)
+	}
+
+	missing := &errorBody{}
+
+	if status := do(t, server, http.MethodGet, "/v1/lists/"+created.ID, "", missing); status != http.StatusNotFound || missing.Error != lists.ErrNotFound.Error() {
+		t.Errorf("GET deleted = %d %+v, want 404 with %q", status, missing, lists.ErrNotFound)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server, storage := newServer(t)
+	list, _ := storage.StoreList(context.Background(), &lists.List{Name: "Work"})
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		err                string
+	}{
+		{http.MethodPost, "/v1/lists", `{"name": " "}`, http.StatusBadRequest, "name must not be empty"},
+		{http.MethodPost, "/v1/lists", `{"name": `, http.StatusBadRequest, "unexpected EOF"},
+		{http.MethodPost, "/v1/lists/" + list.ID + "/tasks", `{"text": ""}`, http.StatusBadRequest, "text must not be empty"},
+		{http.MethodPost, "/v1/lists/missing/tasks", `{"text": "x"}`, http.StatusNotFound, lists.ErrNotFound.Error()},
+		{http.MethodGet, "/v1/tasks/missing", "", http.StatusNotFound, tasks.ErrNotFound.Error()},
+		{http.MethodGet, "/v1/tasks?query=due<", "", http.StatusBadRequest, ""},
+		{http.MethodGet, "/v1/lists?limit=0", "", http.StatusBadRequest, "limit and offset must be positive numbers"},
+		{http.MethodGet, "/v1/lists?offset=-1", "", http.StatusBadRequest, "limit and offset must be positive numbers"},
+		{http.MethodPatch, "/v1/lists", "", http.StatusMethodNotAllowed, "method not allowed"},
+		{http.MethodGet, "/v1/unknown", "", http.StatusNotFound, "not found"},
+		{http.MethodGet, "/lists", "", http.StatusNotFound, "not found"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			body := &errorBody{}
+			status := do(t, server, test.method, test.path, test.body, body)
+
+			if status != test.status {
+				t.Errorf("status = %d, want %d", status, test.status)
+			}
+
+			if body.Error == "" || (test.err != "" && body.Error != test.err) {
+				t.Errorf("error = %q, want %q", body.Error, test.err)
+			}
+		})
+	}
+}
+
+func TestPagination(t *testing.T) {
+	server, storage := newServer(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		_, err := storage.StoreList(ctx, &lists.List{Name: fmt.Sprintf("List %d", i)})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query         string
+		names         []string
+		limit, offset int
+	}{
+		{"", []string{"List 1", "List 2", "List 3", "List 4", "List 5"}, 50, 0},
+		{"?limit=2&offset=1", []string{"List 2", "List 3"}, 2, 1},
+		{"?limit=2&offset=4", []string{"List 5"}, 2, 4},
+		{"?offset=10", []string{}, 50, 10},
+		{"?limit=1000", []string{"List 1", "List 2", "List 3", "List 4", "List 5"}, 500, 0},
+		// the name filter pages in memory
+		{"?name=list&limit=2&offset=3", []string{"List 4", "List 5"}, 2, 3},
+		{"?name=3", []string{"List 3"}, 50, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			page := &listsPage{}
+
+			if status := do(t, server, http.MethodGet, "/v1/lists"+test.query, "", page); status != http.StatusOK {
+				t.Fatalf("status = %d, want 200", status)
+			}
+
+			names := []string{}
+
+			for _, list := range page.Items {
+				names = append(names, list.Name)
+			}
+
+			if fmt.Sprint(names) != fmt.Sprint(test.names) {
+				t.Errorf("items = %v, want %v", names, test.names)
+			}
+
+			total := 5
+
+			if test.query == "?name=3" {
+				total = 1
+			}
+
+			if page.Total != total || page.Limit != test.limit || page.Offset != test.offset {
+				t.Errorf("total, limit, offset = %d, %d, %d, want %d, %d, %d", page.Total, page.Limit, page.Offset, total, test.limit, test.offset)
+			}
+		})
+	}
+}
+
+func TestTasks(t *testing.T) {
+	server, storage := newServer(t)
+	list, _ := storage.StoreList(context.Background(), &lists.List{Name: "Work"})
+	path := "/v1/lists/" + list.ID + "/tasks"
+
+	for _, text := range []string{"write report", "call Bob", "write mail"} {
+		task := &tasks.Task{}
+
+		// the ID is assigned by the server
+		if status := do(t, server, http.MethodPost, path, `{"id": "mine", "text": "`+text+`"}`, task); status != http.StatusCreated {
+			t.Fatalf("POST = %d, want 201", status)
+		}
+
+		if task.ID == "mine" || task.ListID != list.ID {
+			t.Errorf("created = %+v, want a server ID in %s", task, list.ID)
+		}
+	}
+
+	page := &tasksPage{}
+
+	if status := do(t, server, http.MethodGet, path+"?limit=2&offset=1", "", page); status != http.StatusOK || page.Total != 3 || len(page.Items) != 2 || page.Items[0].Text != "call Bob" {
+		t.Errorf("page = %d %+v, want call Bob and write mail of 3", status, page)
+	}
+
+	page = &tasksPage{}
+
+	if status := do(t, server, http.MethodGet, path+"?q=WRITE", "", page); status != http.StatusOK || page.Total != 2 {
+		t.Errorf("q=WRITE = %d %+v, want the 2 write tasks", status, page)
+	}
+
+	task := page.Items[0]
+	updated := &tasks.Task{}
+
+	if status := do(t, server, http.MethodPut, "/v1/tasks/"+task.ID, `{"text": "write report", "completed": true}`, updated); status != http.StatusOK || !updated.Completed || updated.ListID != list.ID {
+		t.Errorf("PUT = %d %+v, want the completed task in its list", status, updated)
+	}
+
+	page = &tasksPage{}
+
+	if status := do(t, server, http.MethodGet, path+"?completed=true", "", page); status != http.StatusOK || page.Total != 1 || page.Items[0].ID != task.ID {
+		t.Errorf("completed=true = %d %+v, want write report", status, page)
+	}
+
+	page = &tasksPage{}
+
+	if status := do(t, server, http.MethodGet, "/v1/tasks?query=done", "", page); status != http.StatusOK || page.Total != 1 {
+		t.Errorf("query=done = %d %+v, want write report", status, page)
+	}
+
+	if status := do(t, server, http.MethodDelete, path, "", nil); status != http.StatusNoContent {
+		t.Errorf("DELETE = %d, want 204", status)
+	}
+
+	page = &tasksPage{}
+
+	if status := do(t, server, http.MethodGet, "/v1/tasks", "", page); status != http.StatusOK || page.Total != 0 || page.Items == nil {
+		t.Errorf("GET after DELETE = %d %+v, want an empty page", status, page)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	server, _ := newServer(t)
+
+	tests := []struct {
+		name, header string
+		status       int
+	}{
+		{"missing", "", http.StatusUnauthorized},
+		{"wrong", "Bearer wrong", http.StatusUnauthorized},
+		{"not bearer", token, http.StatusUnauthorized},
+		{"valid", "Bearer " + token, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/lists", nil)
+
+			if test.header != "" {
+				req.Header.Set("Authorization", test.header)
+			}
+
+			resp, err := server.Client().Do(req)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer resp.Body.Close()
+
+			if resp.StatusCode != test.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, test.status)
+			}
+
+			if test.status != http.StatusUnauthorized {
+				return
+			}
+
+			body := &errorBody{}
+			json.NewDecoder(resp.Body).Decode(body)
+
+			if resp.Header.Get("WWW-Authenticate") != "Bearer" || body.Error == "" {
+				t.Errorf("header %q and error %q, want Bearer and an error", resp.Header.Get("WWW-Authenticate"), body.Error)
+			}
+		})
+	}
+
+	// without a token every request is let through
+	open := httptest.NewServer(api.RequireToken("", api.NewServer(fake.New())))
+	defer open.Close()
+
+	resp, err := open.Client().Get(open.URL + "/v1/lists")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status without token = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	server, storage := newServer(t)
+	ctx := context.Background()
+	list, _ := storage.StoreList(ctx, &lists.List{Name: "Work"})
+	report, _ := storage.StoreTask(ctx, &tasks.Task{ListID: list.ID, Text: "write report"})
+	mail, _ := storage.StoreTask(ctx, &tasks.Task{ListID: list.ID, Text: "write mail"})
+
+	tests := []struct {
+		body   string
+		status int
+	}{
+		{`{"updated": [{"id": "` + report.ID + `", "text": "write report"}, {"id": "missing", "text": "lost"}], "deleted": ["` + mail.ID + `"]}`, http.StatusNotFound},
+		{`{"updated": [{"id": "` + report.ID + `", "text": " "}]}`, http.StatusBadRequest},
+		{`{"updated": `, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		body := &errorBody{}
+
+		if status := do(t, server, http.MethodPost, "/v1/tasks/batch", test.body, body); status != test.status || body.Error == "" {
+			t.Errorf("POST %s = %d %+v, want %d with an error", test.body, status, body, test.status)
+		}
+	}
+
+	// the failed batches changed nothing
+	if all, _ := storage.GetAllTasks(ctx); len(all) != 2 || all[0].Completed {
+		t.Fatalf("tasks = %v, want both unchanged", all)
+	}
+
+	body := `{"updated": [{"id": "` + report.ID + `", "text": "write report", "completed": true}], "deleted": ["` + mail.ID + `", "missing"]}`
+
+	if status := do(t, server, http.MethodPost, "/v1/tasks/batch", body, nil); status != http.StatusNoContent {
+		t.Fatalf("POST = %d, want 204", status)
+	}
+
+	all, _ := storage.GetAllTasks(ctx)
+
+	if len(all) != 1 || !all[0].Completed || all[0].ListID != list.ID || !all[0].CreatedAt.Equal(report.CreatedAt) {
+		t.Errorf("tasks = %v, want write report completed in its list", all)
+	}
+
+	// batch is no task ID, other methods are routed to the task
+	if status := do(t, server, http.MethodGet, "/v1/tasks/batch", "", &errorBody{}); status != http.StatusNotFound {
+		t.Errorf("GET /v1/tasks/batch = %d, want 404", status)
+	}
+}
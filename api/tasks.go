@@ -0,0 +1,312 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+var errMissingText = errors.New("text must not be empty")
+
+// batch is the body of POST /v1/tasks/batch
+type batch struct {
+	Updated []*tasks.Task `json:"updated"`
+	Deleted []string      `json:"deleted"`
+}
+
+func (s *Server) getTasks(w http.ResponseWriter, r *http.Request) {
+	if listID := r.URL.Query().Get("list_id"); listID != "" {
+		s.getListTasks(w, r, listID)
+		return
+	}
+
+	if r.URL.Query().Get("query") != "" {
+		s.queryTasks(w, r, "")
+		return
+	}
+
+	all, err := s.storage.GetAllTasks(r.Context())
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	s.writeTasks(w, r, all)
+}
+
+func (s *Server) getListTasks(w http.ResponseWriter, r *http.Request, listID string) {
+	_, err := s.storage.GetList(r.Context(), listID)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	if r.URL.Query().Get("query") != "" {
+		s.queryTasks(w, r, listID)
+		return
+	}
+
+	// without filters only the requested page has to be read
+	if r.URL.Query().Get("completed") == "" && r.URL.Query().Get("q") == "" {
+		s.writeTasksPage(w, r, listID)
+		return
+	}
+
+	all, err := s.storage.GetTasks(r.Context(), listID)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	s.writeTasks(w, r, all)
+}
+
+// writeTasksPage writes the requested page of the tasks of the list
+func (s *Server) writeTasksPage(w http.ResponseWriter, r *http.Request, listID string) {
+	limit, offset, err := page(r)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	items, total, err := s.storage.GetTasksPage(r.Context(), listID, offset, limit)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, &Page{
+		Items:  items,
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+// queryTasks writes the tasks matching the query parameter, restricted to the list if listID is set
+func (s *Server) queryTasks(w http.ResponseWriter, r *http.Request, listID string) {
+	q, err := query.Parse(r.URL.Query().Get("query"))
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if listID != "" {
+		restriction := &query.Term{Field: "list", Op: query.OpMatch, Value: listID}
+
+		if q.Where == nil {
+			q.Where = restriction
+		} else {
+			q.Where = &query.And{Nodes: []query.Node{restriction, q.Where}}
+		}
+	}
+
+	all, err := s.storage.QueryTasks(r.Context(), q)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	s.writeTasks(w, r, all)
+}
+
+// writeTasks applies the filters and pagination of the request and writes the page
+func (s *Server) writeTasks(w http.ResponseWriter, r *http.Request, all []*tasks.Task) {
+	limit, offset, err := page(r)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query := r.URL.Query()
+	filterCompleted := query.Get("completed") != ""
+	completed, err := strconv.ParseBool(query.Get("completed"))
+
+	if filterCompleted && err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("completed must be true or false"))
+		return
+	}
+
+	text := strings.ToLower(query.Get("q"))
+	matching := []*tasks.Task{}
+
+	for _, task := range all {
+		if filterCompleted && task.Completed != completed {
+			continue
+		}
+
+		if text != "" && !strings.Contains(strings.ToLower(task.Text), text) {
+			continue
+		}
+
+		matching = append(matching, task)
+	}
+
+	start, end := service.Bounds(len(matching), offset, limit)
+
+	writeJSON(w, http.StatusOK, &Page{
+		Items:  matching[start:end],
+		Total:  len(matching),
+		Limit:  limit,
+		Offset: offset,
+	})
+}
+
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request, listID string) {
+	_, err := s.storage.GetList(r.Context(), listID)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	task := &tasks.Task{}
+	err = readJSON(w, r, task)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if strings.TrimSpace(task.Text) == "" {
+		writeError(w, http.StatusBadRequest, errMissingText)
+		return
+	}
+
+	// IDs are always assigned by the server
+	task.ID = ""
+	task.ListID = listID
+	task, err = s.storage.StoreTask(r.Context(), task)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, task)
+}
+
+func (s *Server) getTask(w http.ResponseWriter, r *http.Request, id string) {
+	task, err := s.storage.GetTask(r.Context(), id)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) updateTask(w http.ResponseWriter, r *http.Request, id string) {
+	existing, err := s.storage.GetTask(r.Context(), id)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	task := &tasks.Task{}
+	err = readJSON(w, r, task)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if strings.TrimSpace(task.Text) == "" {
+		writeError(w, http.StatusBadRequest, errMissingText)
+		return
+	}
+
+	task.ID = existing.ID
+	task.CreatedAt = existing.CreatedAt
+
+	if task.ListID == "" {
+		task.ListID = existing.ListID
+	}
+
+	task, err = s.storage.UpdateTask(r.Context(), task)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, task)
+}
+
+func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request, id string) {
+	err := s.storage.DeleteTask(r.Context(), id)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyTasks updates and deletes several tasks at once, either all of the checks pass
+// or nothing is changed. Updated tasks are completed like in updateTask
+func (s *Server) applyTasks(w http.ResponseWriter, r *http.Request) {
+	body := &batch{}
+	err := readJSON(w, r, body)
+
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	for _, task := range body.Updated {
+		if strings.TrimSpace(task.Text) == "" {
+			writeError(w, http.StatusBadRequest, errMissingText)
+			return
+		}
+
+		existing, err := s.storage.GetTask(r.Context(), task.ID)
+
+		if err != nil {
+			writeStorageError(w, err)
+			return
+		}
+
+		task.CreatedAt = existing.CreatedAt
+
+		if task.ListID == "" {
+			task.ListID = existing.ListID
+		}
+	}
+
+	err = s.storage.ApplyTasks(r.Context(), body.Updated, body.Deleted)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) deleteListTasks(w http.ResponseWriter, r *http.Request, listID string) {
+	err := s.storage.DeleteTasks(r.Context(), listID)
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// keepAliveInterval is the time after which an idle change stream gets a comment,
+// so proxies and clients don't consider the connection dead
+const keepAliveInterval = 30 * time.Second
+
+// watch streams a server-sent "change" event whenever the lists or tasks may have changed.
+// Clients are expected to fetch what they display again on every event
+func (s *Server) watch(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming is not supported"))
+		return
+	}
+
+	changes, err := s.storage.Watch(r.Context())
+
+	if err != nil {
+		writeStorageError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case _, ok := <-changes:
+			if !ok {
+				return
+			}
+
+			_, err = fmt.Fprint(w, "event: change\ndata: {}\n\n")
+
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+
+		if err != nil {
+			return
+		}
+
+		flusher.Flush()
+	}
+}
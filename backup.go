@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/julez-dev/go2todo/backup"
+)
+
+// backupStore writes every list and task of the store to an archive
+func backupStore(args []string) {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := flags.String("o", "go2todo-"+time.Now().Format("20060102-150405")+".tar.gz", "file to write the archive to, - for stdout")
+	flags.Parse(args)
+
+	store := chooseStore()
+	archive, err := backup.Create(context.Background(), store)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if *output == "-" {
+		err = backup.Write(os.Stdout, archive)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
+	file, err := os.OpenFile(*output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = backup.Write(file, archive)
+
+	if err != nil {
+		file.Close()
+		os.Remove(*output)
+		log.Fatalln(err)
+	}
+
+	err = file.Close()
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Printf("wrote %d lists and %d tasks to %s\n", archive.Manifest.Lists, archive.Manifest.Tasks, *output)
+}
+
+// restoreStore writes the lists and tasks of an archive into the store
+func restoreStore(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	modeName := flags.String("mode", "merge", "merge adds to the existing data, replace deletes everything not in the archive")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: go2todo restore [-mode merge|replace] file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	mode, err := backup.ParseMode(*modeName)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	file, err := os.Open(flags.Arg(0))
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	defer file.Close()
+
+	archive, err := backup.Read(file)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	store := chooseStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registerHooks(ctx, store, loadConfig(), log.Default())
+
+	result, err := backup.Restore(ctx, store, archive, mode)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Printf("restored %d lists and %d tasks from %s", result.Lists, result.Tasks, archive.Manifest.CreatedAt.Format(time.RFC1123))
+
+	if mode == backup.Replace {
+		fmt.Printf(", deleted %d lists and %d tasks", result.DeletedLists, result.DeletedTasks)
+	}
+
+	fmt.Println()
+	store.Events.Wait()
+}
@@ -0,0 +1,206 @@
+// Package backup writes all lists and tasks of a store to a single archive
+// and restores them into any other store. The archive is a gzipped tar file
+// containing manifest.json and data.json, the manifest holds the format
+// version and the SHA-256 checksum of the data
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+// Version is the version of the archive format written by Write
+const Version = 1
+
+const (
+	manifestName = "manifest.json"
+	dataName     = "data.json"
+)
+
+// ErrChecksum is returned by Read when the data does not match the manifest
+var ErrChecksum = errors.New("backup: checksum mismatch, the archive is corrupted")
+
+type Manifest struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Lists     int       `json:"lists"`
+	Tasks     int       `json:"tasks"`
+	// Checksum is the hex encoded SHA-256 sum of data.json
+	Checksum string `json:"checksum"`
+}
+
+type Data struct {
+	Lists []*lists.List `json:"lists"`
+	Tasks []*tasks.Task `json:"tasks"`
+}
+
+type Archive struct {
+	Manifest Manifest
+	Data     Data
+}
+
+// Create reads every list and task of the store, including tasks of lists which no longer exist
+func Create(ctx context.Context, store service.Interface) (*Archive, error) {
+	lists, err := store.GetLists(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := store.GetAllTasks(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Archive{
+		Manifest: Manifest{
+			Version:   Version,
+			CreatedAt: time.Now(),
+			Lists:     len(lists),
+			Tasks:     len(tasks),
+		},
+		Data: Data{Lists: lists, Tasks: tasks},
+	}, nil
+}
+
+// Write writes the archive, filling in the checksum of the manifest
+func Write(w io.Writer, archive *Archive) error {
+	data, err := json.MarshalIndent(archive.Data, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(data)
+	archive.Manifest.Version = Version
+	archive.Manifest.Lists = len(archive.Data.Lists)
+	archive.Manifest.Tasks = len(archive.Data.Tasks)
+	archive.Manifest.Checksum = hex.EncodeToString(sum[:])
+
+	manifest, err := json.MarshalIndent(archive.Manifest, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	for _, file := range []struct {
+		name    string
+		content []byte
+	}{
+		{manifestName, manifest},
+		{dataName, data},
+	} {
+		err := tw.WriteHeader(&tar.Header{
+			Name:    file.name,
+			Mode:    0o644,
+			Size:    int64(len(file.content)),
+			ModTime: archive.Manifest.CreatedAt,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tw.Write(file.content)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Close()
+
+	if err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// Read reads an archive and verifies its version and checksum
+func Read(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+
+	defer gz.Close()
+
+	files := map[string][]byte{}
+	tr := tar.NewReader(gz)
+
+	for {
+		header, err := tr.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("backup: %w", err)
+		}
+
+		if header.Name != manifestName && header.Name != dataName {
+			continue
+		}
+
+		files[header.Name], err = ioutil.ReadAll(tr)
+
+		if err != nil {
+			return nil, fmt.Errorf("backup: %w", err)
+		}
+	}
+
+	for _, name := range []string{manifestName, dataName} {
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("backup: %s is missing", name)
+		}
+	}
+
+	archive := &Archive{}
+	err = json.Unmarshal(files[manifestName], &archive.Manifest)
+
+	if err != nil {
+		return nil, fmt.Errorf("backup: manifest: %w", err)
+	}
+
+	if archive.Manifest.Version < 1 || archive.Manifest.Version > Version {
+		return nil, fmt.Errorf("backup: unsupported version %d, this go2todo reads up to version %d", archive.Manifest.Version, Version)
+	}
+
+	sum := sha256.Sum256(files[dataName])
+
+	if hex.EncodeToString(sum[:]) != archive.Manifest.Checksum {
+		return nil, ErrChecksum
+	}
+
+	err = json.Unmarshal(files[dataName], &archive.Data)
+
+	if err != nil {
+		return nil, fmt.Errorf("backup: data: %w", err)
+	}
+
+	if len(archive.Data.Lists) != archive.Manifest.Lists || len(archive.Data.Tasks) != archive.Manifest.Tasks {
+		return nil, fmt.Errorf("backup: the data does not match the counts of the manifest")
+	}
+
+	return archive, nil
+}
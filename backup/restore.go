@@ -0,0 +1,186 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+type Mode int
+
+const (
+	// Merge adds the lists and tasks of the archive, overwriting those with the same ID. A remote
+	// server assigns new IDs to the items it doesn't know, merging the same archive into it twice
+	// creates them twice
+	Merge Mode = iota
+	// Replace makes the store an exact copy of the archive, deleting everything else. The tasks
+	// missing from the archive are deleted in one transaction if the repo implements tasks.Batcher,
+	// otherwise one after another, so a failure may leave some of them deleted
+	Replace
+)
+
+func ParseMode(name string) (Mode, error) {
+	switch name {
+	case "merge":
+		return Merge, nil
+	case "replace":
+		return Replace, nil
+	}
+
+	return 0, fmt.Errorf("unknown restore mode %q, expected merge or replace", name)
+}
+
+type Result struct {
+	Lists        int
+	Tasks        int
+	DeletedLists int
+	DeletedTasks int
+}
+
+// Restore writes the archive into the store, keeping IDs and timestamps where the backend allows it
+func Restore(ctx context.Context, store *service.Storage, archive *Archive, mode Mode) (*Result, error) {
+	result := &Result{}
+
+	if mode == Replace {
+		err := deleteMissing(ctx, store, archive, result)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// backends may assign new IDs, the references of the tasks have to follow them
+	listIDs := map[string]string{}
+	taskIDs := map[string]string{}
+
+	for _, list := range archive.Data.Lists {
+		restored := *list
+		stored, err := store.RestoreList(ctx, &restored)
+
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", list.Name, err)
+		}
+
+		listIDs[list.ID] = stored.ID
+		result.Lists++
+	}
+
+	for _, task := range parentsFirst(archive.Data.Tasks) {
+		restored := *task
+
+		if id, ok := listIDs[task.ListID]; ok {
+			restored.ListID = id
+		}
+
+		if id, ok := taskIDs[task.ParentID]; ok {
+			restored.ParentID = id
+		}
+
+		stored, err := store.RestoreTask(ctx, &restored)
+
+		if err != nil {
+			return nil, fmt.Errorf("task %s: %w", task.ID, err)
+		}
+
+		taskIDs[task.ID] = stored.ID
+		result.Tasks++
+	}
+
+	return result, nil
+}
+
+// deleteMissing deletes the lists and tasks of the store which are not part of the archive
+func deleteMissing(ctx context.Context, store *service.Storage, archive *Archive, result *Result) error {
+	keepLists := map[string]bool{}
+	keepTasks := map[string]bool{}
+
+	for _, list := range archive.Data.Lists {
+		keepLists[list.ID] = true
+	}
+
+	for _, task := range archive.Data.Tasks {
+		keepTasks[task.ID] = true
+	}
+
+	existingTasks, err := store.GetAllTasks(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	missing := []string{}
+
+	for _, task := range existingTasks {
+		if !keepTasks[task.ID] {
+			missing = append(missing, task.ID)
+		}
+	}
+
+	// a single veto of the before hooks keeps all tasks
+	err = store.DeleteTasksByID(ctx, missing)
+
+	if err != nil {
+		return err
+	}
+
+	result.DeletedTasks = len(missing)
+
+	existingLists, err := store.GetLists(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	for _, list := range existingLists {
+		if keepLists[list.ID] {
+			continue
+		}
+
+		err := store.DeleteList(ctx, list.ID)
+
+		if err != nil {
+			return fmt.Errorf("list %s: %w", list.Name, err)
+		}
+
+		result.DeletedLists++
+	}
+
+	return nil
+}
+
+// parentsFirst orders the tasks so every parent comes before its subtasks
+func parentsFirst(all []*tasks.Task) []*tasks.Task {
+	byID := map[string]*tasks.Task{}
+
+	for _, task := range all {
+		byID[task.ID] = task
+	}
+
+	sorted := make([]*tasks.Task, 0, len(all))
+	added := map[string]bool{}
+
+	var add func(*tasks.Task, int)
+	add = func(task *tasks.Task, depth int) {
+		if added[task.ID] {
+			return
+		}
+
+		// depth stops cycles of parents, their tasks are added as they come
+		if parent, ok := byID[task.ParentID]; ok && depth < len(all) {
+			add(parent, depth+1)
+		}
+
+		if !added[task.ID] {
+			added[task.ID] = true
+			sorted = append(sorted, task)
+		}
+	}
+
+	for _, task := range all {
+		add(task, 0)
+	}
+
+	return sorted
+}
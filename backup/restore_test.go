@@ -0,0 +1,101 @@
+package backup_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/julez-dev/go2todo/backup"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+// batches counts how the tasks are deleted
+type batches struct {
+	*tasks.InMemory
+
+	applied int
+	deletes int
+}
+
+func (b *batches) ApplyTasks(ctx context.Context, updated []*tasks.Task, deleted []string) error {
+	b.applied++
+
+	return b.InMemory.ApplyTasks(ctx, updated, deleted)
+}
+
+func (b *batches) DeleteTask(ctx context.Context, taskID string) error {
+	b.deletes++
+
+	return b.InMemory.DeleteTask(ctx, taskID)
+}
+
+func TestReplace(t *testing.T) {
+	ctx := context.Background()
+	repo := &batches{InMemory: tasks.NewInMemory()}
+	store := service.NewStorage(repo, lists.NewInMemory())
+
+	work, _ := store.StoreList(ctx, &lists.List{Name: "Work"})
+	kept, _ := store.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "write report"})
+
+	for _, text := range []string{"call Bob", "write mail", "buy milk"} {
+		store.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: text})
+	}
+
+	archive := &backup.Archive{Data: backup.Data{
+		Lists: []*lists.List{work},
+		Tasks: []*tasks.Task{kept},
+	}}
+
+	result, err := backup.Restore(ctx, store, archive, backup.Replace)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.DeletedTasks != 3 || result.Tasks != 1 || result.DeletedLists != 0 {
+		t.Errorf("result = %+v, want 3 deleted tasks and 1 restored", result)
+	}
+
+	// the missing tasks are deleted in one transaction
+	if repo.applied != 1 || repo.deletes != 0 {
+		t.Errorf("%d batches and %d single deletions, want 1 batch", repo.applied, repo.deletes)
+	}
+
+	remaining, _ := store.GetAllTasks(ctx)
+
+	if len(remaining) != 1 || remaining[0].ID != kept.ID {
+		t.Errorf("tasks = %v, want only write report", remaining)
+	}
+}
+
+func TestReplaceVeto(t *testing.T) {
+	ctx := context.Background()
+	store := service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+
+	work, _ := store.StoreList(ctx, &lists.List{Name: "Work"})
+	store.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "write report"})
+	protected, _ := store.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "call Bob"})
+
+	store.Events.Before(func(_ context.Context, event *service.Event) error {
+		if event.Task.ID == protected.ID {
+			return errors.New("protected")
+		}
+
+		return nil
+	}, service.TaskDeleted)
+
+	archive := &backup.Archive{Data: backup.Data{Lists: []*lists.List{work}}}
+
+	_, err := backup.Restore(ctx, store, archive, backup.Replace)
+
+	if err == nil {
+		t.Fatal("the veto was ignored")
+	}
+
+	// one veto keeps every task
+	if remaining, _ := store.GetAllTasks(ctx); len(remaining) != 2 {
+		t.Errorf("tasks = %v, want both kept", remaining)
+	}
+}
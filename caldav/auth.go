@@ -0,0 +1,33 @@
+package caldav
+
+import (
+	"crypto/subtle"
+	"net/http"
+)
+
+// RequireToken rejects all requests not carrying the token. Task apps usually
+// only support basic authentication, so the token is accepted as password
+// with any user name as well as bearer token. An empty token disables the check
+func RequireToken(token string, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provided := ""
+
+		if _, password, ok := r.BasicAuth(); ok {
+			provided = password
+		} else if header := r.Header.Get("Authorization"); len(header) > len("Bearer ") && header[:len("Bearer ")] == "Bearer " {
+			provided = header[len("Bearer "):]
+		}
+
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="go2todo"`)
+			http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
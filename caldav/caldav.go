@@ -0,0 +1,384 @@
+// Package caldav serves the lists as CalDAV calendar collections of VTODOs
+// (RFC 4791), so task apps can sync with a go2todo server.
+//
+// The calendar home is the prefix the handler is mounted at, it also acts as
+// principal. Every list is the collection <prefix>/<list ID>/ and every task
+// the resource <prefix>/<list ID>/<task ID>.ics. Apps choose the names of new
+// resources, which become the IDs of the tasks
+package caldav
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/ics"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+const (
+	calendarContentType = "text/calendar; charset=utf-8"
+	maxBodySize         = 1 << 20
+)
+
+// validName matches the resource names accepted for new tasks. The name becomes the
+// ID of the task, which the file backends write unquoted, so spaces and colons are not allowed
+var validName = regexp.MustCompile(`^[A-Za-z0-9._~@+-]{1,200}$`)
+
+var (
+	errInvalidName = errors.New("resource names may only contain letters, digits and . _ ~ @ + -")
+	errOtherList   = errors.New("the task belongs to another calendar, it can't be moved by writing it")
+)
+
+type Handler struct {
+	storage service.Interface
+	prefix  string
+}
+
+// NewHandler creates a handler for all requests below prefix, for example /caldav
+func NewHandler(storage service.Interface, prefix string) *Handler {
+	return &Handler{
+		storage: storage,
+		prefix:  strings.TrimRight(prefix, "/"),
+	}
+}
+
+// resource is the target of a request, listID and taskID are empty for the calendar home
+type resource struct {
+	listID string
+	taskID string
+}
+
+// resolve maps a path below the prefix to a resource
+func (h *Handler) resolve(path string) (resource, bool) {
+	if path != h.prefix && !strings.HasPrefix(path, h.prefix+"/") {
+		return resource{}, false
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(path, h.prefix), "/"), "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "":
+		return resource{}, true
+	case len(parts) == 1:
+		return resource{listID: parts[0]}, true
+	case len(parts) == 2 && strings.HasSuffix(parts[1], ".ics") && len(parts[1]) > len(".ics"):
+		return resource{listID: parts[0], taskID: strings.TrimSuffix(parts[1], ".ics")}, true
+	}
+
+	return resource{}, false
+}
+
+func (h *Handler) homeHref() string {
+	return h.prefix + "/"
+}
+
+func (h *Handler) listHref(listID string) string {
+	return h.prefix + "/" + url.PathEscape(listID) + "/"
+}
+
+func (h *Handler) taskHref(task *tasks.Task) string {
+	return h.listHref(task.ListID) + url.PathEscape(task.ID) + ".ics"
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	res, ok := h.resolve(r.URL.Path)
+
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.Header().Set("DAV", "1, 3, calendar-access")
+		w.Header().Set("Allow", "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, REPORT")
+		w.WriteHeader(http.StatusOK)
+	case "PROPFIND":
+		h.propfind(w, r, res)
+	case "REPORT":
+		h.report(w, r, res)
+	case http.MethodGet, http.MethodHead:
+		h.get(w, r, res)
+	case http.MethodPut:
+		h.put(w, r, res)
+	case http.MethodDelete:
+		h.delete(w, r, res)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) get(w http.ResponseWriter, r *http.Request, res resource) {
+	if res.taskID == "" {
+		http.Error(w, "collections can only be read with PROPFIND", http.StatusMethodNotAllowed)
+		return
+	}
+
+	task, err := h.task(r, res)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	body := &bytes.Buffer{}
+
+	err = ics.Encode(body, &ics.Calendar{Tasks: []*tasks.Task{task}})
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", calendarContentType)
+	w.Header().Set("ETag", etag(task))
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	w.Write(body.Bytes())
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, res resource) {
+	if res.taskID == "" {
+		http.Error(w, "only tasks can be written", http.StatusMethodNotAllowed)
+		return
+	}
+
+	_, err := h.list(r, res.listID)
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	task, err := ics.DecodeTask(io.LimitReader(r.Body, maxBodySize))
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the resource name identifies the task, even if the client used another UID
+	task.ID = res.taskID
+	task.ListID = res.listID
+
+	existing, err := h.storage.GetTask(r.Context(), res.taskID)
+
+	if err != nil && !errors.Is(err, tasks.ErrNotFound) {
+		writeError(w, err)
+		return
+	}
+
+	if existing == nil && !validName.MatchString(res.taskID) {
+		http.Error(w, errInvalidName.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the IDs are unique across all lists, the name is taken by a task of another calendar
+	if existing != nil && existing.ListID != res.listID {
+		http.Error(w, errOtherList.Error(), http.StatusConflict)
+		return
+	}
+
+	if !preconditionsMet(r, existing) {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+
+	status := http.StatusCreated
+
+	if existing != nil {
+		status = http.StatusNoContent
+		task.CreatedAt = existing.CreatedAt
+		task, err = h.storage.UpdateTask(r.Context(), task)
+	} else {
+		task, err = h.storage.StoreTask(r.Context(), task)
+	}
+
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(task))
+	w.WriteHeader(status)
+}
+
+func (h *Handler) delete(w http.ResponseWriter, r *http.Request, res resource) {
+	switch {
+	case res.listID == "":
+		http.Error(w, "the calendar home can't be deleted", http.StatusForbidden)
+
+	case res.taskID == "":
+		_, err := h.list(r, res.listID)
+
+		if err == nil {
+			err = h.storage.DeleteList(r.Context(), res.listID)
+		}
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		task, err := h.task(r, res)
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if !preconditionsMet(r, task) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		err = h.storage.DeleteTask(r.Context(), task.ID)
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// list returns the list of the path. Smart lists aren't served, their tasks
+// belong to other calendars
+func (h *Handler) list(r *http.Request, listID string) (*lists.List, error) {
+	list, err := h.storage.GetList(r.Context(), listID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if list.Smart() {
+		return nil, lists.ErrNotFound
+	}
+
+	return list, nil
+}
+
+// task returns the task of the resource, it has to belong to the list of the path
+func (h *Handler) task(r *http.Request, res resource) (*tasks.Task, error) {
+	task, err := h.storage.GetTask(r.Context(), res.taskID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if task.ListID != res.listID {
+		return nil, tasks.ErrNotFound
+	}
+
+	return task, nil
+}
+
+// preconditionsMet checks If-Match and If-None-Match against the current task, which may be nil
+func preconditionsMet(r *http.Request, current *tasks.Task) bool {
+	if match := r.Header.Get("If-Match"); match != "" {
+		if current == nil {
+			return false
+		}
+
+		if match != "*" && !containsETag(match, etag(current)) {
+			return false
+		}
+	}
+
+	if noneMatch := r.Header.Get("If-None-Match"); noneMatch != "" && current != nil {
+		if noneMatch == "*" || containsETag(noneMatch, etag(current)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsETag(header, tag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// etag identifies the revision of a task, it changes whenever any field of it does.
+// Times are rounded to seconds, as not all backends store them more precisely
+func etag(task *tasks.Task) string {
+	normalized := *task
+	normalized.CreatedAt = roundTime(task.CreatedAt)
+
+	if task.CompletedAt != nil {
+		completedAt := roundTime(*task.CompletedAt)
+		normalized.CompletedAt = &completedAt
+	}
+
+	if task.Due != nil {
+		due := roundTime(*task.Due)
+		normalized.Due = &due
+	}
+
+	encoded, _ := json.Marshal(&normalized)
+	sum := sha1.Sum(encoded)
+
+	return `"` + hex.EncodeToString(sum[:10]) + `"`
+}
+
+func roundTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Second)
+}
+
+// ctag changes whenever the list or any of its tasks change
+func ctag(list *lists.List, all []*tasks.Task) string {
+	tags := make([]string, 0, len(all))
+
+	for _, task := range all {
+		tags = append(tags, etag(task))
+	}
+
+	sort.Strings(tags)
+
+	hash := sha1.New()
+	io.WriteString(hash, list.Name)
+
+	for _, tag := range tags {
+		io.WriteString(hash, tag)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:10])
+}
+
+// writeError maps the errors of the storage layer to status codes
+func writeError(w http.ResponseWriter, err error) {
+	veto := &service.VetoError{}
+
+	switch {
+	case errors.Is(err, tasks.ErrNotFound) || errors.Is(err, lists.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.As(err, &veto):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,232 @@
+package caldav_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emersion/go-ical"
+	"github.com/emersion/go-webdav"
+	client "github.com/emersion/go-webdav/caldav"
+	"github.com/julez-dev/go2todo/caldav"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+const token = "secret"
+
+// newClient serves an in-memory storage and returns a CalDAV client logged in with the token
+func newClient(t *testing.T) (*client.Client, *service.Storage) {
+	t.Helper()
+
+	store := service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+	server := httptest.NewServer(caldav.RequireToken(token, caldav.NewHandler(store, "/caldav")))
+	t.Cleanup(server.Close)
+
+	c, err := client.NewClient(webdav.HTTPClientWithBasicAuth(server.Client(), "user", token), server.URL+"/caldav/")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return c, store
+}
+
+func todo(uid, summary string) *ical.Calendar {
+	cal := ical.NewCalendar()
+	cal.Props.SetText(ical.PropVersion, "2.0")
+	cal.Props.SetText(ical.PropProductID, "-//go2todo//test//EN")
+
+	vtodo := ical.NewComponent(ical.CompToDo)
+	vtodo.Props.SetText(ical.PropUID, uid)
+	vtodo.Props.SetDateTime(ical.PropDateTimeStamp, time.Now().UTC())
+	vtodo.Props.SetText(ical.PropSummary, summary)
+	cal.Children = append(cal.Children, vtodo)
+
+	return cal
+}
+
+// query returns the tasks of the calendar like a syncing app
+func query(t *testing.T, c *client.Client, path string) []client.CalendarObject {
+	t.Helper()
+
+	objects, err := c.QueryCalendar(path, &client.CalendarQuery{
+		CompRequest: client.CalendarCompRequest{Name: "VCALENDAR", AllProps: true, AllComps: true},
+		CompFilter:  client.CompFilter{Name: "VCALENDAR", Comps: []client.CompFilter{{Name: "VTODO"}}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return objects
+}
+
+func summary(object *client.CalendarObject) string {
+	for _, child := range object.Data.Children {
+		if child.Name == ical.CompToDo {
+			text, _ := child.Props.Text(ical.PropSummary)
+			return text
+		}
+	}
+
+	return ""
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	c, store := newClient(t)
+	work, _ := store.StoreList(ctx, &lists.List{Name: "Work"})
+
+	principal, err := c.FindCurrentUserPrincipal()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home, err := c.FindCalendarHomeSet(principal)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calendars, err := c.FindCalendars(home)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calendars) != 1 || calendars[0].Name != "Work" {
+		t.Fatalf("calendars = %+v, want Work", calendars)
+	}
+
+	path := calendars[0].Path
+
+	// the app chooses the name of the new resource, it becomes the ID of the task
+	created, err := c.PutCalendarObject(path+"write-report.ics", todo("write-report", "write report"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := store.GetTask(ctx, "write-report")
+
+	if err != nil || task.Text != "write report" || task.ListID != work.ID {
+		t.Fatalf("stored task = %v, %v, want write report in Work", task, err)
+	}
+
+	objects := query(t, c, path)
+
+	if len(objects) != 1 || summary(&objects[0]) != "write report" || objects[0].ETag != created.ETag {
+		t.Fatalf("query = %+v, want write report with the ETag of the PUT", objects)
+	}
+
+	// changes made by others show up with a new ETag
+	update := *task
+	update.Completed = true
+	_, err = store.UpdateTask(ctx, &update)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	object, err := c.GetCalendarObject(path + "write-report.ics")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := object.Data.Children[0].Props.Text(ical.PropStatus); status != "COMPLETED" || object.ETag == created.ETag {
+		t.Errorf("GET = %s with ETag %s, want COMPLETED with a new ETag", status, object.ETag)
+	}
+
+	err = c.RemoveAll(path + "write-report.ics")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.GetTask(ctx, "write-report")
+
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("GetTask after DELETE: err = %v, want tasks.ErrNotFound", err)
+	}
+
+	if objects := query(t, c, path); len(objects) != 0 {
+		t.Errorf("query after DELETE = %+v, want none", objects)
+	}
+}
+
+func TestPutConflicts(t *testing.T) {
+	ctx := context.Background()
+	c, store := newClient(t)
+	work, _ := store.StoreList(ctx, &lists.List{Name: "Work"})
+	home, _ := store.StoreList(ctx, &lists.List{Name: "Home"})
+
+	_, err := c.PutCalendarObject("/caldav/"+work.ID+"/shared.ics", todo("shared", "write report"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the same name in another calendar would silently move the task
+	_, err = c.PutCalendarObject("/caldav/"+home.ID+"/shared.ics", todo("shared", "buy milk"))
+
+	if err == nil || !strings.Contains(err.Error(), "409") {
+		t.Errorf("PUT into another calendar: err = %v, want 409", err)
+	}
+
+	if task, _ := store.GetTask(ctx, "shared"); task.ListID != work.ID || task.Text != "write report" {
+		t.Errorf("task = %v, want it unchanged in Work", task)
+	}
+
+	// the names become IDs, which the file backends can't store with spaces or colons
+	for _, name := range []string{"my%20task", "a:b", "urn:uuid:1"} {
+		_, err = c.PutCalendarObject("/caldav/"+work.ID+"/"+name+".ics", todo(name, "lost"))
+
+		if err == nil || !strings.Contains(err.Error(), "400") {
+			t.Errorf("PUT %s: err = %v, want 400", name, err)
+		}
+	}
+
+	all, _ := store.GetAllTasks(ctx)
+
+	if len(all) != 1 {
+		t.Errorf("tasks = %v, want only shared", all)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	store := service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+	server := httptest.NewServer(caldav.RequireToken(token, caldav.NewHandler(store, "/caldav")))
+	defer server.Close()
+
+	c, err := client.NewClient(webdav.HTTPClientWithBasicAuth(server.Client(), "user", "wrong"), server.URL+"/caldav/")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.FindCurrentUserPrincipal()
+
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("err = %v, want 401", err)
+	}
+
+	resp, err := server.Client().Get(server.URL + "/caldav/")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(resp.Header.Get("WWW-Authenticate"), "Basic") {
+		t.Errorf("status %d, WWW-Authenticate %q, want 401 asking for basic auth", resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+	}
+}
@@ -0,0 +1,485 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/julez-dev/go2todo/ics"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+const (
+	nsDAV            = "DAV:"
+	nsCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	nsCalendarServer = "http://calendarserver.org/ns/"
+)
+
+var prefixes = map[string]string{
+	nsDAV:            "d",
+	nsCalDAV:         "c",
+	nsCalendarServer: "cs",
+}
+
+var (
+	propResourceType = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName  = xml.Name{Space: nsDAV, Local: "displayname"}
+	propETag         = xml.Name{Space: nsDAV, Local: "getetag"}
+	propContentType  = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propPrincipal    = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propPrivileges   = xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}
+	propHomeSet      = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propComponents   = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propCalendarData = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propCTag         = xml.Name{Space: nsCalendarServer, Local: "getctag"}
+
+	reportQuery    = xml.Name{Space: nsCalDAV, Local: "calendar-query"}
+	reportMultiget = xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}
+)
+
+// the properties returned for PROPFIND requests without prop element
+var (
+	defaultHomeProps = []xml.Name{propResourceType, propDisplayName, propPrincipal, propPrincipalURL, propHomeSet, propPrivileges}
+	defaultListProps = []xml.Name{propResourceType, propDisplayName, propETag, propComponents, propCTag, propPrivileges}
+	defaultTaskProps = []xml.Name{propResourceType, propETag, propContentType}
+)
+
+const (
+	privileges = "<d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege>" +
+		"<d:privilege><d:write-content/></d:privilege><d:privilege><d:bind/></d:privilege><d:privilege><d:unbind/></d:privilege>"
+
+	errUnknownReport  = "only calendar-query and calendar-multiget reports are supported"
+	errInvalidXMLBody = "invalid XML body"
+	errInvalidDepth   = "invalid Depth header"
+)
+
+// propRequest is the prop element of PROPFIND and REPORT bodies
+type propRequest struct {
+	Names []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+func (p *propRequest) names() []xml.Name {
+	if p == nil {
+		return nil
+	}
+
+	names := make([]xml.Name, 0, len(p.Names))
+
+	for _, name := range p.Names {
+		names = append(names, name.XMLName)
+	}
+
+	return names
+}
+
+type propfindRequest struct {
+	XMLName xml.Name     `xml:"DAV: propfind"`
+	Prop    *propRequest `xml:"DAV: prop"`
+}
+
+type compFilter struct {
+	Name    string       `xml:"name,attr"`
+	Filters []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+type reportRequest struct {
+	XMLName xml.Name
+	Prop    *propRequest `xml:"DAV: prop"`
+	Hrefs   []string     `xml:"DAV: href"`
+	Filter  *struct {
+		CompFilter compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// wantsTasks reports whether the filter of a calendar-query matches VTODOs
+func (r *reportRequest) wantsTasks() bool {
+	if r.Filter == nil {
+		return true
+	}
+
+	for _, filter := range r.Filter.CompFilter.Filters {
+		if !strings.EqualFold(filter.Name, "VTODO") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// decodeBody decodes the XML body into v, an empty body leaves v untouched
+func decodeBody(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize))
+
+	if err != nil || len(bytes.TrimSpace(body)) == 0 {
+		return err
+	}
+
+	return xml.Unmarshal(body, v)
+}
+
+func (h *Handler) propfind(w http.ResponseWriter, r *http.Request, res resource) {
+	req := &propfindRequest{}
+
+	if decodeBody(r, req) != nil {
+		http.Error(w, errInvalidXMLBody, http.StatusBadRequest)
+		return
+	}
+
+	depth := r.Header.Get("Depth")
+
+	if depth != "" && depth != "0" && depth != "1" && depth != "infinity" {
+		http.Error(w, errInvalidDepth, http.StatusBadRequest)
+		return
+	}
+
+	// the default depth of PROPFIND is infinity, which is limited to the direct children here
+	children := depth != "0"
+	requested := req.Prop.names()
+	ms := &multistatus{}
+
+	switch {
+	case res.listID == "":
+		ms.add(h.homeResponse(requested))
+
+		if !children {
+			break
+		}
+
+		all, err := h.storage.GetLists(r.Context())
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		for _, list := range all {
+			if list.Smart() {
+				continue
+			}
+
+			tasks, err := h.storage.GetTasks(r.Context(), list.ID)
+
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+
+			ms.add(h.listResponse(list, tasks, requested))
+		}
+
+	case res.taskID == "":
+		list, err := h.list(r, res.listID)
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		tasks, err := h.storage.GetTasks(r.Context(), list.ID)
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		ms.add(h.listResponse(list, tasks, requested))
+
+		if !children {
+			break
+		}
+
+		for _, task := range tasks {
+			ms.add(h.taskResponse(task, requested))
+		}
+
+	default:
+		task, err := h.task(r, res)
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		ms.add(h.taskResponse(task, requested))
+	}
+
+	ms.write(w)
+}
+
+func (h *Handler) report(w http.ResponseWriter, r *http.Request, res resource) {
+	if res.listID == "" || res.taskID != "" {
+		http.Error(w, errUnknownReport, http.StatusForbidden)
+		return
+	}
+
+	req := &reportRequest{}
+
+	if decodeBody(r, req) != nil {
+		http.Error(w, errInvalidXMLBody, http.StatusBadRequest)
+		return
+	}
+
+	requested := req.Prop.names()
+	ms := &multistatus{}
+
+	switch req.XMLName {
+	case reportQuery:
+		list, err := h.list(r, res.listID)
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		if !req.wantsTasks() {
+			break
+		}
+
+		tasks, err := h.storage.GetTasks(r.Context(), list.ID)
+
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		for _, task := range tasks {
+			ms.add(h.taskResponse(task, requested))
+		}
+
+	case reportMultiget:
+		for _, href := range req.Hrefs {
+			ms.add(h.multigetResponse(r, strings.TrimSpace(href), requested))
+		}
+
+	default:
+		http.Error(w, errUnknownReport, http.StatusForbidden)
+		return
+	}
+
+	ms.write(w)
+}
+
+func (h *Handler) multigetResponse(r *http.Request, href string, requested []xml.Name) *response {
+	missing := &response{href: href, status: http.StatusNotFound}
+	parsed, err := url.Parse(href)
+
+	if err != nil {
+		return missing
+	}
+
+	res, ok := h.resolve(parsed.Path)
+
+	if !ok || res.taskID == "" {
+		return missing
+	}
+
+	task, err := h.task(r, res)
+
+	if err != nil {
+		return missing
+	}
+
+	return h.taskResponse(task, requested)
+}
+
+func (h *Handler) homeResponse(requested []xml.Name) *response {
+	resp := &response{href: h.homeHref()}
+
+	resp.props(requested, defaultHomeProps, func(name xml.Name) (string, bool) {
+		switch name {
+		case propResourceType:
+			return "<d:collection/><d:principal/>", true
+		case propDisplayName:
+			return "go2todo", true
+		case propPrincipal, propPrincipalURL, propHomeSet:
+			return href(h.homeHref()), true
+		case propPrivileges:
+			return privileges, true
+		}
+
+		return "", false
+	})
+
+	return resp
+}
+
+func (h *Handler) listResponse(list *lists.List, all []*tasks.Task, requested []xml.Name) *response {
+	resp := &response{href: h.listHref(list.ID)}
+	tag := ctag(list, all)
+
+	resp.props(requested, defaultListProps, func(name xml.Name) (string, bool) {
+		switch name {
+		case propResourceType:
+			return "<d:collection/><c:calendar/>", true
+		case propDisplayName:
+			return escape(list.Name), true
+		case propETag:
+			return escape(`"` + tag + `"`), true
+		case propCTag:
+			return tag, true
+		case propComponents:
+			return `<c:comp name="VTODO"/>`, true
+		case propPrincipal:
+			return href(h.homeHref()), true
+		case propPrivileges:
+			return privileges, true
+		}
+
+		return "", false
+	})
+
+	return resp
+}
+
+func (h *Handler) taskResponse(task *tasks.Task, requested []xml.Name) *response {
+	resp := &response{href: h.taskHref(task)}
+
+	resp.props(requested, defaultTaskProps, func(name xml.Name) (string, bool) {
+		switch name {
+		case propResourceType:
+			return "", true
+		case propETag:
+			return escape(etag(task)), true
+		case propContentType:
+			return "text/calendar; charset=utf-8; component=vtodo", true
+		case propCalendarData:
+			data := &bytes.Buffer{}
+
+			if ics.Encode(data, &ics.Calendar{Tasks: []*tasks.Task{task}}) != nil {
+				return "", false
+			}
+
+			return escape(data.String()), true
+		case propPrincipal:
+			return href(h.homeHref()), true
+		}
+
+		return "", false
+	})
+
+	return resp
+}
+
+// response is a single response element of a multistatus body
+type response struct {
+	href string
+	// status is set for responses without properties, like missing resources
+	status  int
+	found   []prop
+	missing []xml.Name
+}
+
+type prop struct {
+	name  xml.Name
+	inner string
+}
+
+// props adds the requested properties, or the default ones if none were requested.
+// value returns the XML content of a property and whether the resource has it
+func (resp *response) props(requested, defaults []xml.Name, value func(xml.Name) (string, bool)) {
+	names := requested
+
+	if len(names) == 0 {
+		names = defaults
+	}
+
+	for _, name := range names {
+		if inner, ok := value(name); ok {
+			resp.found = append(resp.found, prop{name: name, inner: inner})
+			continue
+		}
+
+		resp.missing = append(resp.missing, name)
+	}
+}
+
+type multistatus struct {
+	responses []*response
+}
+
+func (ms *multistatus) add(resp *response) {
+	ms.responses = append(ms.responses, resp)
+}
+
+func (ms *multistatus) write(w http.ResponseWriter) {
+	b := &strings.Builder{}
+	b.WriteString(`<?xml version="1.0" encoding="utf-8"?>` + "\n")
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+
+	for _, resp := range ms.responses {
+		b.WriteString("<d:response>" + href(resp.href))
+
+		if resp.status != 0 {
+			b.WriteString(status(resp.status))
+		}
+
+		if len(resp.found) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+
+			for _, p := range resp.found {
+				b.WriteString(element(p.name, p.inner))
+			}
+
+			b.WriteString("</d:prop>" + status(http.StatusOK) + "</d:propstat>")
+		}
+
+		if len(resp.missing) > 0 {
+			b.WriteString("<d:propstat><d:prop>")
+
+			for _, name := range resp.missing {
+				b.WriteString(element(name, ""))
+			}
+
+			b.WriteString("</d:prop>" + status(http.StatusNotFound) + "</d:propstat>")
+		}
+
+		b.WriteString("</d:response>")
+	}
+
+	b.WriteString("</d:multistatus>\n")
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, b.String())
+}
+
+// element writes an element with the known namespace prefixes, other namespaces are declared inline
+func element(name xml.Name, inner string) string {
+	tag := name.Local
+	declaration := ""
+
+	if prefix, ok := prefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	} else if name.Space != "" {
+		declaration = ` xmlns="` + escape(name.Space) + `"`
+	}
+
+	if inner == "" {
+		return "<" + tag + declaration + "/>"
+	}
+
+	return "<" + tag + declaration + ">" + inner + "</" + tag + ">"
+}
+
+func href(path string) string {
+	return "<d:href>" + escape(path) + "</d:href>"
+}
+
+func status(code int) string {
+	return "<d:status>HTTP/1.1 " + strconv.Itoa(code) + " " + http.StatusText(code) + "</d:status>"
+}
+
+func escape(s string) string {
+	b := &strings.Builder{}
+	xml.EscapeText(b, []byte(s))
+
+	return b.String()
+}
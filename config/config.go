@@ -0,0 +1,167 @@
+// Package config loads the optional JSON configuration file of go2todo
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Config is the content of the configuration file, all fields are optional
+type Config struct {
+	Hooks    []Hook    `json:"hooks"`
+	Webhooks []Webhook `json:"webhooks"`
+	// WebhookDir holds the delivery queue and log of the webhooks, it defaults to
+	// the webhooks directory next to the configuration file
+	WebhookDir string `json:"webhook_dir"`
+	UI         UI     `json:"ui"`
+}
+
+// UI configures the terminal interface
+type UI struct {
+	// Confirm turns the confirmation of destructive actions on or off, for example
+	// {"delete_task": false}. Actions which are not listed are confirmed
+	Confirm map[string]bool `json:"confirm"`
+	// Keymap is the preset of the key bindings, "default", "vim" or "emacs"
+	Keymap string `json:"keymap"`
+	// Keys replaces the keys of single actions of the preset, for example
+	// {"delete": ["x"], "toggle": ["space", "enter"]}. An empty list turns the action off
+	Keys map[string][]string `json:"keys"`
+	// Theme names one of Themes or the built in themes "dark", "light", "high-contrast"
+	// and "no-color". It defaults to dark or light depending on the terminal, NO_COLOR
+	// in the environment always selects no-color
+	Theme string `json:"theme"`
+	// Themes defines own themes, which may also replace the built in ones
+	Themes map[string]Theme `json:"themes"`
+}
+
+// Theme contains the colours of the TUI, either ANSI numbers from 0 to 255 like "9" or hex values like
+// "#ff5f5f". Colours which are left out are taken from the built in theme named in Base
+type Theme struct {
+	// Base is the built in theme the colours are changed on, it defaults like UI.Theme
+	Base     string `json:"base"`
+	Cursor   string `json:"cursor"`
+	Selected string `json:"selected"`
+	Done     string `json:"done"`
+	Undone   string `json:"undone"`
+	Overdue  string `json:"overdue"`
+	Match    string `json:"match"`
+	// Muted colours secondary text like the queries of smart lists, it defaults to faint text
+	Muted  string `json:"muted"`
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Border string `json:"border"`
+	// Priorities are the colours of priority 1, 2 and so on, the last one is used for all lower priorities
+	Priorities []string `json:"priorities"`
+}
+
+// Hook runs a shell command for storage events, see package hooks
+type Hook struct {
+	// Events are the event types the hook runs for, for example "task.completed". Empty means all
+	Events []string `json:"events"`
+	// Command is run by the shell, with the event as JSON on stdin
+	Command string `json:"command"`
+	// Before runs the command before the change is made, a failing command vetoes the change
+	Before bool `json:"before"`
+	// Timeout limits the runtime of the command, it defaults to 10 seconds
+	Timeout Duration `json:"timeout"`
+}
+
+// Webhook receives a POST request with the event as JSON for storage events, see package webhooks
+type Webhook struct {
+	URL string `json:"url"`
+	// Secret is the key of the HMAC-SHA256 signature sent in the X-Go2todo-Signature header
+	Secret string `json:"secret"`
+	// Events are the event types sent to the URL. Empty means all
+	Events []string `json:"events"`
+}
+
+// Duration is a time.Duration written as string like "1m30s" in the file
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+
+	err := json.Unmarshal(data, &value)
+
+	if err != nil {
+		return err
+	}
+
+	parsed, err := time.ParseDuration(value)
+
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Path returns the location of the configuration file, GO2TODO_CONFIG overrides
+// the default go2todo/config.json inside the user configuration directory
+func Path() (string, error) {
+	if path := os.Getenv("GO2TODO_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "go2todo", "config.json"), nil
+}
+
+// Dir returns the directory of the configuration file, other state of go2todo lives next to it
+func Dir() (string, error) {
+	path, err := Path()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Dir(path), nil
+}
+
+// Load reads the configuration file, a missing file results in the empty configuration
+func Load() (*Config, error) {
+	path, err := Path()
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return &Config{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	cfg := &Config{}
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+
+	err = decoder.Decode(cfg)
+
+	if err != nil {
+		return nil, fmt.Errorf("config: %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/julez-dev/go2todo/editor"
+)
+
+// editTask opens a task in the editor of the user and stores the changes
+func editTask(args []string) {
+	flags := flag.NewFlagSet("edit", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: go2todo edit TASK_ID")
+		fmt.Fprintln(flags.Output(), "The editor is taken from $VISUAL or $EDITOR")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	store := chooseStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registerHooks(ctx, store, loadConfig(), log.Default())
+
+	task, err := store.GetTask(ctx, flags.Arg(0))
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	path, err := editor.WriteTemp(task)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	defer os.Remove(path)
+
+	for {
+		cmd := editor.Command(path)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+		err = cmd.Run()
+
+		if err != nil {
+			log.Fatalln("editor failed:", err)
+		}
+
+		edited, err := editor.ReadFile(path, task)
+
+		if err == nil {
+			_, err = store.UpdateTask(ctx, edited)
+		}
+
+		if err == nil {
+			break
+		}
+
+		// the edits are kept in the file, so they are not lost to a typo
+		if !confirm(fmt.Sprintf("%v\nedit again? [Y/n] ", err)) {
+			os.Remove(path)
+			log.Fatalln("task not changed")
+		}
+	}
+
+	store.Events.Wait()
+}
+
+// confirm asks a yes or no question on the terminal, yes is the default
+func confirm(question string) bool {
+	fmt.Fprint(os.Stderr, question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	if err != nil {
+		return false
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+
+	return answer == "" || answer == "y" || answer == "yes"
+}
@@ -0,0 +1,211 @@
+// Package editor edits tasks as text files in the editor of the user. The file
+// holds the notes of the task as Markdown below a YAML front matter with its other fields
+package editor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	separator  = "---"
+	dateLayout = "2006-01-02"
+)
+
+// ErrNoFrontMatter is returned for files which don't start with the front matter
+var ErrNoFrontMatter = errors.New("the file does not start with the front matter between --- lines")
+
+// frontMatter contains the editable fields of the task besides the notes
+type frontMatter struct {
+	Text string   `yaml:"text"`
+	Due  string   `yaml:"due"`
+	Tags []string `yaml:"tags,flow"`
+}
+
+// Marshal formats the task as file for the editor
+func Marshal(task *tasks.Task) ([]byte, error) {
+	fields := frontMatter{
+		Text: task.Text,
+		Tags: task.Tags,
+	}
+
+	if task.Due != nil {
+		fields.Due = formatDue(*task.Due)
+	}
+
+	if fields.Tags == nil {
+		fields.Tags = []string{}
+	}
+
+	encoded, err := yaml.Marshal(fields)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &bytes.Buffer{}
+	s.WriteString(separator + "\n")
+	s.Write(encoded)
+	s.WriteString(separator + "\n\n")
+
+	if task.Notes != "" {
+		s.WriteString(task.Notes + "\n")
+	}
+
+	return s.Bytes(), nil
+}
+
+// Unmarshal applies the edited file to a copy of the task, the task itself stays untouched
+func Unmarshal(data []byte, task *tasks.Task) (*tasks.Task, error) {
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	if !strings.HasPrefix(content, separator+"\n") {
+		return nil, ErrNoFrontMatter
+	}
+
+	// the closing separator may be the last line without a line break
+	content = content[len(separator)+1:] + "\n"
+	end := strings.Index("\n"+content, "\n"+separator+"\n")
+
+	if end < 0 {
+		return nil, ErrNoFrontMatter
+	}
+
+	fields := frontMatter{}
+
+	err := yaml.Unmarshal([]byte(content[:end]), &fields)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid front matter: %w", err)
+	}
+
+	edited := *task
+	edited.Text = strings.TrimSpace(fields.Text)
+	edited.Notes = strings.TrimSpace(content[end+len(separator)+1:])
+	edited.Tags = nil
+
+	if edited.Text == "" {
+		return nil, errors.New("the text of the task is empty")
+	}
+
+	for _, tag := range fields.Tags {
+		if tag = strings.TrimSpace(strings.TrimPrefix(tag, "#")); tag != "" {
+			edited.Tags = append(edited.Tags, tag)
+		}
+	}
+
+	edited.Due, err = parseDue(strings.TrimSpace(fields.Due), task.Due)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &edited, nil
+}
+
+// formatDue writes due dates at midnight as plain date
+func formatDue(due time.Time) string {
+	local := due.Local()
+
+	if local.Hour() == 0 && local.Minute() == 0 && local.Second() == 0 {
+		return local.Format(dateLayout)
+	}
+
+	return due.Format(time.RFC3339)
+}
+
+// parseDue reads the due date back, an unchanged value keeps the previous due date as is
+func parseDue(value string, previous *time.Time) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	if previous != nil && value == formatDue(*previous) {
+		return previous, nil
+	}
+
+	if due, err := time.ParseInLocation(dateLayout, value, time.Local); err == nil {
+		return &due, nil
+	}
+
+	if due, err := time.Parse(time.RFC3339, value); err == nil {
+		return &due, nil
+	}
+
+	return nil, fmt.Errorf("invalid due date %q, expected YYYY-MM-DD", value)
+}
+
+// WriteTemp writes the task to a temporary file, which the caller removes once it was edited
+func WriteTemp(task *tasks.Task) (string, error) {
+	data, err := Marshal(task)
+
+	if err != nil {
+		return "", err
+	}
+
+	// the extension lets editors highlight the notes as Markdown
+	file, err := ioutil.TempFile("", "go2todo-*.md")
+
+	if err != nil {
+		return "", err
+	}
+
+	_, err = file.Write(data)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(file.Name())
+		return "", err
+	}
+
+	return file.Name(), nil
+}
+
+// ReadFile reads the edited file of the task back
+func ReadFile(path string, task *tasks.Task) (*tasks.Task, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Unmarshal(data, task)
+}
+
+// Command prepares the editor of the user for the file. $VISUAL is preferred over $EDITOR,
+// both may contain arguments like "code --wait". Without either vi is used
+func Command(path string) *exec.Cmd {
+	editor := os.Getenv("VISUAL")
+
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+
+	if editor == "" {
+		editor = "vi"
+
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", editor+` "`+path+`"`)
+	}
+
+	// the path is passed as argument, so it needs no quoting
+	return exec.Command("sh", "-c", editor+` "$1"`, "editor", path)
+}
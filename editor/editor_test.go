@@ -0,0 +1,178 @@
+package editor_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/editor"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+func TestMarshal(t *testing.T) {
+	due := time.Date(2021, time.March, 5, 0, 0, 0, 0, time.Local)
+	task := &tasks.Task{ID: "1", ListID: "work", Text: "write report", Notes: "Quarterly numbers\n\n- ask Alice", Tags: []string{"office", "q1"}, Due: &due, Priority: 2}
+
+	data, err := editor.Marshal(task)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "---\ntext: write report\ndue: \"2021-03-05\"\ntags: [office, q1]\n---\n\nQuarterly numbers\n\n- ask Alice\n"
+
+	if string(data) != want {
+		t.Errorf("Marshal =\n%s\nwant\n%s", data, want)
+	}
+
+	// the unchanged file gives back the task, the due date is kept as is
+	edited, err := editor.Unmarshal(data, task)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(edited, task) || edited.Due != task.Due {
+		t.Errorf("Unmarshal = %+v, want %+v", edited, task)
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	due := time.Date(2021, time.March, 5, 14, 30, 0, 0, time.UTC)
+	task := &tasks.Task{ID: "1", Text: "write report", Notes: "old notes", Tags: []string{"office"}, Due: &due, Priority: 2}
+
+	date := func(s string) *time.Time {
+		parsed, _ := time.ParseInLocation("2006-01-02", s, time.Local)
+		return &parsed
+	}
+
+	tests := []struct {
+		name, content string
+		want          *tasks.Task
+	}{
+		{
+			"edited",
+			"---\ntext: ' write the report '\ndue: 2021-03-06\ntags: ['#office', ' q1 ', '']\n---\n\n new notes \n",
+			&tasks.Task{ID: "1", Text: "write the report", Notes: "new notes", Tags: []string{"office", "q1"}, Due: date("2021-03-06"), Priority: 2},
+		},
+		{
+			"cleared",
+			"---\ntext: write report\ndue: \"\"\ntags: []\n---\n",
+			&tasks.Task{ID: "1", Text: "write report", Priority: 2},
+		},
+		{
+			"time",
+			"---\ntext: write report\ndue: 2021-03-05T16:30:00+02:00\n---",
+			&tasks.Task{ID: "1", Text: "write report", Due: &due, Priority: 2},
+		},
+		{
+			"windows line breaks",
+			"---\r\ntext: write report\r\n---\r\n\r\nold notes\r\n",
+			&tasks.Task{ID: "1", Text: "write report", Notes: "old notes", Priority: 2},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			edited, err := editor.Unmarshal([]byte(test.content), task)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			sameDue := (edited.Due == nil && test.want.Due == nil) || (edited.Due != nil && test.want.Due != nil && edited.Due.Equal(*test.want.Due))
+			edited.Due, test.want.Due = nil, nil
+
+			if !sameDue || !reflect.DeepEqual(edited, test.want) {
+				t.Errorf("Unmarshal = %+v, want %+v", edited, test.want)
+			}
+
+			if task.Text != "write report" || task.Notes != "old notes" {
+				t.Errorf("the task was changed: %+v", task)
+			}
+		})
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	task := &tasks.Task{Text: "write report"}
+
+	tests := []struct {
+		name, content, want string
+	}{
+		{"no front matter", "text: write report\n", editor.ErrNoFrontMatter.Error()},
+		{"unclosed", "---\ntext: write report\n", editor.ErrNoFrontMatter.Error()},
+		{"yaml", "---\ntext: [write report\n---\n", "invalid front matter"},
+		{"empty text", "---\ntext: ' '\n---\n", "the text of the task is empty"},
+		{"due", "---\ntext: write report\ndue: next week\n---\n", `invalid due date "next week", expected YYYY-MM-DD`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := editor.Unmarshal([]byte(test.content), task)
+
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("err = %v, want it to contain %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestWriteTemp(t *testing.T) {
+	task := &tasks.Task{Text: "write report", Notes: "Quarterly numbers"}
+	path, err := editor.WriteTemp(task)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.Remove(path)
+
+	if filepath.Ext(path) != ".md" {
+		t.Errorf("path = %s, want a Markdown file", path)
+	}
+
+	read, err := editor.ReadFile(path, task)
+
+	if err != nil || !reflect.DeepEqual(read, task) {
+		t.Errorf("ReadFile = %+v, %v, want the task", read, err)
+	}
+
+	_, err = editor.ReadFile(filepath.Join(t.TempDir(), "missing.md"), task)
+
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadFile of a missing file: err = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the editor is started by cmd on Windows")
+	}
+
+	tests := []struct {
+		visual, editor, want string
+	}{
+		{"code --wait", "nano", `code --wait "$1"`},
+		{"", "nano", `nano "$1"`},
+		{"", "", `vi "$1"`},
+	}
+
+	for _, test := range tests {
+		t.Setenv("VISUAL", test.visual)
+		t.Setenv("EDITOR", test.editor)
+
+		// the path is an argument of the shell and needs no quoting
+		cmd := editor.Command("/tmp/it's a task.md")
+		want := []string{"sh", "-c", test.want, "editor", "/tmp/it's a task.md"}
+
+		if !reflect.DeepEqual(cmd.Args, want) {
+			t.Errorf("Command = %q, want %q", cmd.Args, want)
+		}
+	}
+}
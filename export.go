@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/julez-dev/go2todo/ics"
+	"github.com/julez-dev/go2todo/service"
+)
+
+// exportTasks writes all lists with their tasks to stdout or a file
+func exportTasks(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "ics", "export format, only ics is supported")
+	output := flags.String("o", "", "file to write to instead of stdout")
+	flags.Parse(args)
+
+	if *format != "ics" {
+		log.Fatalf("unknown export format %q", *format)
+	}
+
+	store := chooseStore()
+	ctx := context.Background()
+
+	calendars, err := calendars(ctx, store)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var w io.Writer = os.Stdout
+
+	if *output != "" {
+		file, err := os.Create(*output)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		defer file.Close()
+		w = file
+	}
+
+	err = ics.EncodeAll(w, calendars)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func calendars(ctx context.Context, store service.Interface) ([]*ics.Calendar, error) {
+	lists, err := store.GetLists(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	calendars := []*ics.Calendar{}
+
+	for _, list := range lists {
+		// the tasks of smart lists are exported with their real lists
+		if list.Smart() {
+			continue
+		}
+
+		tasks, err := store.GetTasks(ctx, list.ID)
+
+		if err != nil {
+			return nil, fmt.Errorf("tasks of %s: %w", list.Name, err)
+		}
+
+		calendars = append(calendars, &ics.Calendar{List: list, Tasks: tasks})
+	}
+
+	return calendars, nil
+}
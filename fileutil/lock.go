@@ -0,0 +1,51 @@
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// lockTimeout is how long Lock waits for another process to release the lock
+	lockTimeout = 5 * time.Second
+	// staleLock is the age after which a lock file is considered left over by a crashed process
+	staleLock = 30 * time.Second
+)
+
+// ErrLocked is returned by Lock when the lock is not released in time
+var ErrLocked = errors.New("the file is locked by another process")
+
+// Lock creates the lock file path.lock and returns the function removing it again. It waits
+// while another process holds the lock, which only works between processes using Lock.
+// Creating the file exclusively works the same on all systems, unlike flock
+func Lock(path string) (func(), error) {
+	lock := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		file, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+
+		if err == nil {
+			file.Close()
+
+			return func() { os.Remove(lock) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(lock)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: %s", ErrLocked, lock)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
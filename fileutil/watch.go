@@ -0,0 +1,68 @@
+package fileutil
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watch notifies about all changes of the file at path until ctx is done.
+// The directory is watched instead of the file itself, so files replaced by
+// a rename (like WriteFileAtomic and most editors do) keep being watched
+func Watch(ctx context.Context, path string) (<-chan struct{}, error) {
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return nil, err
+	}
+
+	absPath, err := filepath.Abs(path)
+
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	err = watcher.Add(filepath.Dir(absPath))
+
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+		defer watcher.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if filepath.Clean(event.Name) != absPath || event.Op == fsnotify.Chmod {
+					continue
+				}
+
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return changes, nil
+}
@@ -0,0 +1,96 @@
+package grpcapi
+
+import (
+	"time"
+
+	todov1 "github.com/julez-dev/go2todo/proto/todo/v1"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toProtoList(list *lists.List) *todov1.List {
+	return &todov1.List{
+		Id:        list.ID,
+		Name:      list.Name,
+		Query:     list.Query,
+		CreatedAt: toProtoTime(list.CreatedAt),
+	}
+}
+
+func fromProtoList(list *todov1.List) *lists.List {
+	return &lists.List{
+		ID:        list.GetId(),
+		Name:      list.GetName(),
+		Query:     list.GetQuery(),
+		CreatedAt: fromProtoTime(list.GetCreatedAt()),
+	}
+}
+
+func toProtoTask(task *tasks.Task) *todov1.Task {
+	converted := &todov1.Task{
+		Id:        task.ID,
+		ListId:    task.ListID,
+		Text:      task.Text,
+		Completed: task.Completed,
+		Priority:  int32(task.Priority),
+		Tags:      task.Tags,
+		Notes:     task.Notes,
+		ParentId:  task.ParentID,
+		CreatedAt: toProtoTime(task.CreatedAt),
+	}
+
+	if task.CompletedAt != nil {
+		converted.CompletedAt = timestamppb.New(*task.CompletedAt)
+	}
+
+	if task.Due != nil {
+		converted.Due = timestamppb.New(*task.Due)
+	}
+
+	return converted
+}
+
+func fromProtoTask(task *todov1.Task) *tasks.Task {
+	converted := &tasks.Task{
+		ID:        task.GetId(),
+		ListID:    task.GetListId(),
+		Text:      task.GetText(),
+		Completed: task.GetCompleted(),
+		Priority:  int(task.GetPriority()),
+		Tags:      task.GetTags(),
+		Notes:     task.GetNotes(),
+		ParentID:  task.GetParentId(),
+		CreatedAt: fromProtoTime(task.GetCreatedAt()),
+	}
+
+	if task.GetCompletedAt() != nil {
+		completedAt := task.GetCompletedAt().AsTime()
+		converted.CompletedAt = &completedAt
+	}
+
+	if task.GetDue() != nil {
+		due := task.GetDue().AsTime()
+		converted.Due = &due
+	}
+
+	return converted
+}
+
+// toProtoTime converts t, leaving the zero time unset
+func toProtoTime(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+
+	return timestamppb.New(t)
+}
+
+// fromProtoTime converts t, mapping an unset timestamp to the zero time
+func fromProtoTime(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+
+	return t.AsTime()
+}
@@ -0,0 +1,283 @@
+// Package grpcapi contains the gRPC implementation of todo.v1.TodoService
+package grpcapi
+
+//go:generate sh -c "cd .. && buf generate proto"
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	todov1 "github.com/julez-dev/go2todo/proto/todo/v1"
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type Server struct {
+	todov1.UnimplementedTodoServiceServer
+
+	storage  service.Interface
+	watchers *watchers
+}
+
+// NewServer serves the storage, Watch streams the changes reported by events. All
+// changes made through the storage are streamed, no matter which frontend made them
+func NewServer(storage service.Interface, events *service.EventBus) *Server {
+	w := newWatchers()
+	events.Observe(w.observe)
+
+	return &Server{
+		storage:  storage,
+		watchers: w,
+	}
+}
+
+// toStatus maps the errors of the storage layer to gRPC status errors
+func toStatus(err error) error {
+	if errors.Is(err, tasks.ErrNotFound) || errors.Is(err, lists.ErrNotFound) {
+		return status.Error(codes.NotFound, err.Error())
+	}
+
+	veto := &service.VetoError{}
+
+	if errors.As(err, &veto) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	syntax := &query.SyntaxError{}
+
+	if errors.Is(err, service.ErrSmartList) || errors.As(err, &syntax) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+func (s *Server) CreateList(ctx context.Context, req *todov1.CreateListRequest) (*todov1.List, error) {
+	if strings.TrimSpace(req.GetList().GetName()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "name must not be empty")
+	}
+
+	list, err := s.storage.StoreList(ctx, fromProtoList(req.GetList()))
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoList(list), nil
+}
+
+func (s *Server) GetList(ctx context.Context, req *todov1.GetListRequest) (*todov1.List, error) {
+	list, err := s.storage.GetList(ctx, req.GetId())
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoList(list), nil
+}
+
+func (s *Server) GetLists(ctx context.Context, _ *todov1.GetListsRequest) (*todov1.GetListsResponse, error) {
+	all, err := s.storage.GetLists(ctx)
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	resp := &todov1.GetListsResponse{}
+
+	for _, list := range all {
+		resp.Lists = append(resp.Lists, toProtoList(list))
+	}
+
+	return resp, nil
+}
+
+func (s *Server) UpdateList(ctx context.Context, req *todov1.UpdateListRequest) (*todov1.List, error) {
+	if strings.TrimSpace(req.GetList().GetName()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "name must not be empty")
+	}
+
+	existing, err := s.storage.GetList(ctx, req.GetList().GetId())
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	list := fromProtoList(req.GetList())
+	list.CreatedAt = existing.CreatedAt
+
+	list, err = s.storage.UpdateList(ctx, list)
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoList(list), nil
+}
+
+func (s *Server) DeleteList(ctx context.Context, req *todov1.DeleteListRequest) (*todov1.DeleteListResponse, error) {
+	err := s.storage.DeleteList(ctx, req.GetId())
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.DeleteListResponse{}, nil
+}
+
+func (s *Server) DeleteLists(ctx context.Context, _ *todov1.DeleteListsRequest) (*todov1.DeleteListsResponse, error) {
+	err := s.storage.DeleteLists(ctx)
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.DeleteListsResponse{}, nil
+}
+
+func (s *Server) CreateTask(ctx context.Context, req *todov1.CreateTaskRequest) (*todov1.Task, error) {
+	if strings.TrimSpace(req.GetTask().GetText()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "text must not be empty")
+	}
+
+	_, err := s.storage.GetList(ctx, req.GetTask().GetListId())
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	task := fromProtoTask(req.GetTask())
+	task.ID = ""
+
+	task, err = s.storage.StoreTask(ctx, task)
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoTask(task), nil
+}
+
+func (s *Server) GetTask(ctx context.Context, req *todov1.GetTaskRequest) (*todov1.Task, error) {
+	task, err := s.storage.GetTask(ctx, req.GetId())
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoTask(task), nil
+}
+
+func (s *Server) GetTasks(ctx context.Context, req *todov1.GetTasksRequest) (*todov1.GetTasksResponse, error) {
+	all, err := s.storage.GetTasks(ctx, req.GetListId())
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toTasksResponse(all), nil
+}
+
+func (s *Server) GetAllTasks(ctx context.Context, _ *todov1.GetAllTasksRequest) (*todov1.GetTasksResponse, error) {
+	all, err := s.storage.GetAllTasks(ctx)
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toTasksResponse(all), nil
+}
+
+func (s *Server) UpdateTask(ctx context.Context, req *todov1.UpdateTaskRequest) (*todov1.Task, error) {
+	if strings.TrimSpace(req.GetTask().GetText()) == "" {
+		return nil, status.Error(codes.InvalidArgument, "text must not be empty")
+	}
+
+	existing, err := s.storage.GetTask(ctx, req.GetTask().GetId())
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	task := fromProtoTask(req.GetTask())
+	task.CreatedAt = existing.CreatedAt
+
+	if task.ListID == "" {
+		task.ListID = existing.ListID
+	}
+
+	task, err = s.storage.UpdateTask(ctx, task)
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return toProtoTask(task), nil
+}
+
+func (s *Server) DeleteTask(ctx context.Context, req *todov1.DeleteTaskRequest) (*todov1.DeleteTaskResponse, error) {
+	err := s.storage.DeleteTask(ctx, req.GetId())
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.DeleteTaskResponse{}, nil
+}
+
+func (s *Server) DeleteTasks(ctx context.Context, req *todov1.DeleteTasksRequest) (*todov1.DeleteTasksResponse, error) {
+	err := s.storage.DeleteTasks(ctx, req.GetListId())
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &todov1.DeleteTasksResponse{}, nil
+}
+
+// Watch streams the changes. The header is sent once the subscription is active,
+// clients waiting for it don't miss the changes they make afterwards
+func (s *Server) Watch(_ *todov1.WatchRequest, stream todov1.TodoService_WatchServer) error {
+	events, cancel := s.watchers.subscribe()
+	defer cancel()
+
+	err := stream.SendHeader(metadata.MD{})
+
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+
+		case event, ok := <-events:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "client is too slow to receive events")
+			}
+
+			err = stream.Send(event)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toTasksResponse(all []*tasks.Task) *todov1.GetTasksResponse {
+	resp := &todov1.GetTasksResponse{}
+
+	for _, task := range all {
+		resp.Tasks = append(resp.Tasks, toProtoTask(task))
+	}
+
+	return resp
+}
@@ -0,0 +1,258 @@
+package grpcapi_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/grpcapi"
+	todov1 "github.com/julez-dev/go2todo/proto/todo/v1"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const token = "secret"
+
+// newClient serves an in-memory storage over bufconn and returns a client authenticated with the token
+func newClient(t *testing.T) (todov1.TodoServiceClient, *service.Storage) {
+	t.Helper()
+
+	store := service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+	listener := bufconn.Listen(1 << 20)
+
+	server := grpc.NewServer(grpc.UnaryInterceptor(grpcapi.RequireToken(token)), grpc.StreamInterceptor(grpcapi.RequireStreamToken(token)))
+	todov1.RegisterTodoServiceServer(server, grpcapi.NewServer(store, store.Events))
+
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithInsecure(),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return todov1.NewTodoServiceClient(conn), store
+}
+
+func authenticated() context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// watch opens the change stream and waits until the server subscribed it
+func watch(t *testing.T, client todov1.TodoServiceClient) (todov1.TodoService_WatchClient, context.CancelFunc) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(authenticated())
+	stream, err := client.Watch(ctx, &todov1.WatchRequest{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = stream.Header()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return stream, cancel
+}
+
+// receive returns the next events of the stream
+func receive(t *testing.T, stream todov1.TodoService_WatchClient, n int) []*todov1.Event {
+	t.Helper()
+
+	events := []*todov1.Event{}
+
+	for len(events) < n {
+		event, err := stream.Recv()
+
+		if err != nil {
+			t.Fatalf("after %d events: %v", len(events), err)
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func TestCRUD(t *testing.T) {
+	client, _ := newClient(t)
+	ctx := authenticated()
+
+	list, err := client.CreateList(ctx, &todov1.CreateListRequest{List: &todov1.List{Name: "Work"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := client.CreateTask(ctx, &todov1.CreateTaskRequest{Task: &todov1.Task{Id: "mine", ListId: list.Id, Text: "write report"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if task.Id == "mine" || task.ListId != list.Id {
+		t.Errorf("created = %v, want a server ID in %s", task, list.Id)
+	}
+
+	task.Completed = true
+	updated, err := client.UpdateTask(ctx, &todov1.UpdateTaskRequest{Task: task})
+
+	if err != nil || !updated.Completed || updated.CompletedAt == nil {
+		t.Errorf("UpdateTask = %v, %v, want the completed task", updated, err)
+	}
+
+	resp, err := client.GetTasks(ctx, &todov1.GetTasksRequest{ListId: list.Id})
+
+	if err != nil || len(resp.Tasks) != 1 {
+		t.Errorf("GetTasks = %v, %v, want write report", resp, err)
+	}
+
+	_, err = client.DeleteList(ctx, &todov1.DeleteListRequest{Id: list.Id})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetTask(ctx, &todov1.GetTaskRequest{Id: task.Id})
+
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("GetTask of a deleted list: err = %v, want NotFound", err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	client, _ := newClient(t)
+
+	_, err := client.GetLists(context.Background(), &todov1.GetListsRequest{})
+
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("without token: err = %v, want Unauthenticated", err)
+	}
+
+	_, err = client.CreateList(authenticated(), &todov1.CreateListRequest{List: &todov1.List{Name: " "}})
+
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("empty name: err = %v, want InvalidArgument", err)
+	}
+
+	_, err = client.CreateTask(authenticated(), &todov1.CreateTaskRequest{Task: &todov1.Task{ListId: "missing", Text: "lost"}})
+
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("missing list: err = %v, want NotFound", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	client, store := newClient(t)
+	ctx := authenticated()
+
+	stream, cancel := watch(t, client)
+	defer cancel()
+
+	list, err := client.CreateList(ctx, &todov1.CreateListRequest{List: &todov1.List{Name: "Work"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// changes made without gRPC, for example through the REST API, are streamed as well
+	task, err := store.StoreTask(context.Background(), &tasks.Task{ListID: list.Id, Text: "write report"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task.Completed = true
+	_, err = store.UpdateTask(context.Background(), task)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.DeleteTask(ctx, &todov1.DeleteTaskRequest{Id: task.ID})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := receive(t, stream, 4)
+
+	want := []struct {
+		eventType todov1.Event_Type
+		id        string
+	}{
+		{todov1.Event_TYPE_LIST_CREATED, list.Id},
+		{todov1.Event_TYPE_TASK_CREATED, task.ID},
+		{todov1.Event_TYPE_TASK_UPDATED, task.ID},
+		{todov1.Event_TYPE_TASK_DELETED, task.ID},
+	}
+
+	for i, w := range want {
+		if events[i].Type != w.eventType || events[i].Id != w.id {
+			t.Errorf("event %d = %v %s, want %v %s", i, events[i].Type, events[i].Id, w.eventType, w.id)
+		}
+	}
+
+	if !events[2].Task.Completed {
+		t.Errorf("the update event carries %v, want the completed task", events[2].Task)
+	}
+
+	// every change is streamed once, the next event is the one of the next change
+	_, err = client.UpdateList(ctx, &todov1.UpdateListRequest{List: &todov1.List{Id: list.Id, Name: "Office"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if next := receive(t, stream, 1)[0]; next.Type != todov1.Event_TYPE_LIST_UPDATED || next.List.Name != "Office" {
+		t.Errorf("next event = %v %v, want LIST_UPDATED of Office", next.Type, next.List)
+	}
+}
+
+func TestWatchEndsWithClient(t *testing.T) {
+	client, store := newClient(t)
+
+	stream, cancel := watch(t, client)
+	cancel()
+
+	_, err := stream.Recv()
+
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("err = %v, want Canceled", err)
+	}
+
+	// publishing to the ended subscription must not block the storage
+	done := make(chan struct{})
+
+	go func() {
+		for i := 0; i < 100; i++ {
+			store.StoreList(context.Background(), &lists.List{Name: "Work"})
+		}
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the storage blocked")
+	}
+}
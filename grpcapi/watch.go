@@ -0,0 +1,98 @@
+package grpcapi
+
+import (
+	"sync"
+
+	todov1 "github.com/julez-dev/go2todo/proto/todo/v1"
+	"github.com/julez-dev/go2todo/service"
+)
+
+// watcherBuffer is the number of events a subscriber may fall behind
+// before it gets disconnected
+const watcherBuffer = 64
+
+// eventTypes maps the events of the storage to the ones of the API. Completing and
+// reopening a task are left out, they follow the update of the task
+var eventTypes = map[service.EventType]todov1.Event_Type{
+	service.ListCreated:  todov1.Event_TYPE_LIST_CREATED,
+	service.ListUpdated:  todov1.Event_TYPE_LIST_UPDATED,
+	service.ListDeleted:  todov1.Event_TYPE_LIST_DELETED,
+	service.ListsDeleted: todov1.Event_TYPE_LISTS_DELETED,
+	service.TaskCreated:  todov1.Event_TYPE_TASK_CREATED,
+	service.TaskUpdated:  todov1.Event_TYPE_TASK_UPDATED,
+	service.TaskDeleted:  todov1.Event_TYPE_TASK_DELETED,
+	service.TasksDeleted: todov1.Event_TYPE_TASKS_DELETED,
+}
+
+// watchers fans out the change events to all running Watch calls
+type watchers struct {
+	subscribers map[chan *todov1.Event]struct{}
+	l           *sync.Mutex
+}
+
+func newWatchers() *watchers {
+	return &watchers{
+		subscribers: make(map[chan *todov1.Event]struct{}),
+		l:           &sync.Mutex{},
+	}
+}
+
+// subscribe returns a channel receiving all future events and a function to
+// end the subscription. The channel is closed when the subscriber falls too far behind
+func (w *watchers) subscribe() (<-chan *todov1.Event, func()) {
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	events := make(chan *todov1.Event, watcherBuffer)
+	w.subscribers[events] = struct{}{}
+
+	return events, func() {
+		w.l.Lock()
+		defer w.l.Unlock()
+
+		if _, ok := w.subscribers[events]; ok {
+			delete(w.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+func (w *watchers) publish(event *todov1.Event) {
+	w.l.Lock()
+	defer w.l.Unlock()
+
+	for events := range w.subscribers {
+		select {
+		case events <- event:
+		default:
+			delete(w.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// observe converts the event of the storage and publishes it
+func (w *watchers) observe(event *service.Event) {
+	eventType, ok := eventTypes[event.Type]
+
+	if !ok {
+		return
+	}
+
+	converted := &todov1.Event{Type: eventType}
+
+	switch {
+	case event.Task != nil:
+		converted.Id = event.Task.ID
+		converted.Task = toProtoTask(event.Task)
+
+	case event.List != nil:
+		converted.Id = event.List.ID
+		converted.List = toProtoList(event.List)
+
+	default:
+		converted.Id = event.ListID
+	}
+
+	w.publish(converted)
+}
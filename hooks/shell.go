@@ -0,0 +1,118 @@
+// Package hooks runs the shell commands configured for storage events
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/service"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// waitDelay is how long a timed out command may keep its output open
+const waitDelay = time.Second
+
+// Shell runs a command with the event encoded as JSON on stdin. The type of the
+// event is also available in the GO2TODO_EVENT environment variable
+type Shell struct {
+	Command string
+	Timeout time.Duration
+}
+
+// Run runs the command and returns its trimmed stderr as error if it fails
+func (sh *Shell) Run(ctx context.Context, event *service.Event) error {
+	input, err := json.Marshal(event)
+
+	if err != nil {
+		return err
+	}
+
+	timeout := sh.Timeout
+
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := shellCommand(ctx, sh.Command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), "GO2TODO_EVENT="+string(event.Type))
+
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	// children of the shell keep stderr open after it was killed, Run stops waiting for them
+	cmd.WaitDelay = waitDelay
+
+	err = cmd.Run()
+
+	if err == nil {
+		return nil
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("%q timed out after %s", sh.Command, timeout)
+	}
+
+	if message := strings.TrimSpace(stderr.String()); message != "" {
+		return errors.New(message)
+	}
+
+	return fmt.Errorf("%q failed: %w", sh.Command, err)
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// Register adds the configured hooks to the bus. Failures of after hooks
+// can't be reported to anyone making changes, so they are written to errorLog
+func Register(bus *service.EventBus, hooks []config.Hook, errorLog *log.Logger) error {
+	for _, hook := range hooks {
+		if strings.TrimSpace(hook.Command) == "" {
+			return errors.New("hooks: command must not be empty")
+		}
+
+		types, err := service.ParseEventTypes(hook.Events)
+
+		if err != nil {
+			return fmt.Errorf("hooks: %w", err)
+		}
+
+		sh := &Shell{
+			Command: hook.Command,
+			Timeout: time.Duration(hook.Timeout),
+		}
+
+		if hook.Before {
+			bus.Before(sh.Run, types...)
+			continue
+		}
+
+		bus.After(func(event *service.Event) {
+			err := sh.Run(context.Background(), event)
+
+			if err != nil {
+				errorLog.Printf("hook for %s: %v", event.Type, err)
+			}
+		}, types...)
+	}
+
+	return nil
+}
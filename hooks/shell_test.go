@@ -0,0 +1,134 @@
+package hooks_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/hooks"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+func newStorage(t *testing.T, hook config.Hook) (*service.Storage, *bytes.Buffer) {
+	t.Helper()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are written for sh")
+	}
+
+	store := service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+	errorLog := &bytes.Buffer{}
+	err := hooks.Register(store.Events, []config.Hook{hook}, log.New(errorLog, "", 0))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return store, errorLog
+}
+
+func TestVeto(t *testing.T) {
+	store, _ := newStorage(t, config.Hook{
+		Events:  []string{"task.created"},
+		Command: `grep -q '"text":"forbidden"' && echo "no forbidden tasks" >&2 && exit 1; exit 0`,
+		Before:  true,
+	})
+
+	_, err := store.StoreTask(context.Background(), &tasks.Task{Text: "forbidden"})
+
+	var veto *service.VetoError
+
+	if !errors.As(err, &veto) || veto.Err.Error() != "no forbidden tasks" {
+		t.Fatalf("err = %v, want a veto with the stderr of the command", err)
+	}
+
+	if all, _ := store.GetAllTasks(context.Background()); len(all) != 0 {
+		t.Errorf("tasks = %v, want the vetoed task missing", all)
+	}
+
+	_, err = store.StoreTask(context.Background(), &tasks.Task{Text: "allowed"})
+
+	if err != nil {
+		t.Errorf("err = %v, want the task stored", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	store, _ := newStorage(t, config.Hook{
+		Command: "sleep 5",
+		Before:  true,
+		Timeout: config.Duration(100 * time.Millisecond),
+	})
+
+	start := time.Now()
+	_, err := store.StoreList(context.Background(), &lists.List{Name: "Work"})
+
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("err = %v, want a timeout", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("the hook ran for %s", elapsed)
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "event")
+	store, errorLog := newStorage(t, config.Hook{
+		Events:  []string{"task.completed"},
+		Command: `echo "$GO2TODO_EVENT" > ` + out + ` && cat >> ` + out,
+	})
+
+	ctx := context.Background()
+	task, _ := store.StoreTask(ctx, &tasks.Task{Text: "write report"})
+	update := *task
+	update.Completed = true
+	updated, err := store.UpdateTask(ctx, &update)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the hook gets the task as it was stored, not as it is changed afterwards
+	updated.Text = "changed"
+	store.Events.Wait()
+
+	content, err := ioutil.ReadFile(out)
+
+	if err != nil {
+		t.Fatalf("the hook did not run: %v, log %q", err, errorLog)
+	}
+
+	lines := strings.SplitN(string(content), "\n", 2)
+	event := &service.Event{}
+	err = json.Unmarshal([]byte(lines[1]), event)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if lines[0] != "task.completed" || event.Type != service.TaskCompleted || event.Task.Text != "write report" {
+		t.Errorf("hook got %q and %+v, want task.completed of write report", lines[0], event.Task)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	bus := service.NewEventBus()
+
+	for _, hook := range []config.Hook{{Command: " "}, {Command: "true", Events: []string{"task.exploded"}}} {
+		if err := hooks.Register(bus, []config.Hook{hook}, log.Default()); err == nil {
+			t.Errorf("hook %+v was registered", hook)
+		}
+	}
+}
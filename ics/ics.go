@@ -0,0 +1,305 @@
+// Package ics converts lists and tasks to and from iCalendar (RFC 5545).
+// A list is a VCALENDAR, its tasks are the VTODO components inside of it
+package ics
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+const (
+	prodID = "-//go2todo//go2todo//EN"
+
+	dateLayout     = "20060102"
+	dateTimeLayout = "20060102T150405"
+	utcLayout      = "20060102T150405Z"
+
+	// maxPriority is the lowest priority iCalendar can express
+	maxPriority = 9
+)
+
+var errNoUID = errors.New("ics: VTODO without UID")
+
+// Calendar is a list with its tasks
+type Calendar struct {
+	// List is nil for calendars without name, their tasks have no ListID
+	List  *lists.List
+	Tasks []*tasks.Task
+}
+
+// Encode writes the calendar as VCALENDAR with a VTODO for each task
+func Encode(w io.Writer, calendar *Calendar) error {
+	return writeComponent(w, encodeCalendar(calendar))
+}
+
+// EncodeAll writes all calendars into one iCalendar stream
+func EncodeAll(w io.Writer, calendars []*Calendar) error {
+	for _, calendar := range calendars {
+		err := Encode(w, calendar)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeCalendar converts the calendar to its VCALENDAR component
+func encodeCalendar(calendar *Calendar) *component {
+	vcalendar := &component{Name: "VCALENDAR"}
+	vcalendar.add("VERSION", "2.0")
+	vcalendar.add("PRODID", prodID)
+
+	if calendar.List != nil {
+		vcalendar.add("X-WR-CALNAME", escapeText(calendar.List.Name))
+		vcalendar.add("X-WR-RELCALID", escapeText(calendar.List.ID))
+	}
+
+	for _, task := range calendar.Tasks {
+		vcalendar.Components = append(vcalendar.Components, encodeTask(task))
+	}
+
+	return vcalendar
+}
+
+// encodeTask converts the task to a VTODO component
+func encodeTask(task *tasks.Task) *component {
+	vtodo := &component{Name: "VTODO"}
+	vtodo.add("UID", escapeText(task.ID))
+	vtodo.add("DTSTAMP", time.Now().UTC().Format(utcLayout))
+
+	if !task.CreatedAt.IsZero() {
+		vtodo.add("CREATED", task.CreatedAt.UTC().Format(utcLayout))
+	}
+
+	vtodo.add("SUMMARY", escapeText(task.Text))
+
+	if task.Notes != "" {
+		vtodo.add("DESCRIPTION", escapeText(task.Notes))
+	}
+
+	if task.Completed {
+		vtodo.add("STATUS", "COMPLETED")
+
+		if task.CompletedAt != nil {
+			vtodo.add("COMPLETED", task.CompletedAt.UTC().Format(utcLayout))
+		}
+	} else {
+		vtodo.add("STATUS", "NEEDS-ACTION")
+	}
+
+	if task.Due != nil && isDate(*task.Due) {
+		vtodo.add("DUE", task.Due.In(time.Local).Format(dateLayout), "VALUE", "DATE")
+	} else if task.Due != nil {
+		vtodo.add("DUE", task.Due.UTC().Format(utcLayout))
+	}
+
+	if task.Priority > 0 {
+		priority := task.Priority
+
+		if priority > maxPriority {
+			priority = maxPriority
+		}
+
+		vtodo.add("PRIORITY", strconv.Itoa(priority))
+	}
+
+	if len(task.Tags) > 0 {
+		escaped := make([]string, len(task.Tags))
+
+		for i, tag := range task.Tags {
+			escaped[i] = escapeText(tag)
+		}
+
+		vtodo.add("CATEGORIES", strings.Join(escaped, ","))
+	}
+
+	if task.ParentID != "" {
+		vtodo.add("RELATED-TO", escapeText(task.ParentID), "RELTYPE", "PARENT")
+	}
+
+	return vtodo
+}
+
+// Decode reads all VCALENDAR objects of an iCalendar stream. Calendars without
+// X-WR-CALNAME result in a Calendar without List
+func Decode(r io.Reader) ([]*Calendar, error) {
+	components, err := readComponents(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	calendars := []*Calendar{}
+
+	for _, vcalendar := range components {
+		if vcalendar.Name != "VCALENDAR" {
+			continue
+		}
+
+		calendar := &Calendar{Tasks: []*tasks.Task{}}
+
+		if name := vcalendar.value("X-WR-CALNAME"); name != "" {
+			calendar.List = &lists.List{
+				ID:   unescapeText(vcalendar.value("X-WR-RELCALID")),
+				Name: unescapeText(name),
+			}
+		}
+
+		for _, child := range vcalendar.Components {
+			if child.Name != "VTODO" {
+				continue
+			}
+
+			task, err := decodeTask(child)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if calendar.List != nil {
+				task.ListID = calendar.List.ID
+			}
+
+			calendar.Tasks = append(calendar.Tasks, task)
+		}
+
+		calendars = append(calendars, calendar)
+	}
+
+	return calendars, nil
+}
+
+// DecodeTask parses a single VTODO, optionally wrapped in a VCALENDAR like the
+// resources of CalDAV servers
+func DecodeTask(r io.Reader) (*tasks.Task, error) {
+	components, err := readComponents(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range components {
+		candidates := append([]*component{c}, c.Components...)
+
+		for _, candidate := range candidates {
+			if candidate.Name == "VTODO" {
+				return decodeTask(candidate)
+			}
+		}
+	}
+
+	return nil, errors.New("ics: no VTODO found")
+}
+
+func decodeTask(vtodo *component) (*tasks.Task, error) {
+	task := &tasks.Task{
+		ID:    unescapeText(vtodo.value("UID")),
+		Text:  unescapeText(vtodo.value("SUMMARY")),
+		Notes: unescapeText(vtodo.value("DESCRIPTION")),
+	}
+
+	if task.ID == "" {
+		return nil, errNoUID
+	}
+
+	if created := vtodo.get("CREATED"); created != nil {
+		task.CreatedAt, _ = parseTime(created)
+	}
+
+	if completed := vtodo.get("COMPLETED"); completed != nil {
+		if completedAt, err := parseTime(completed); err == nil {
+			task.Completed = true
+			task.CompletedAt = &completedAt
+		}
+	}
+
+	if strings.EqualFold(vtodo.value("STATUS"), "COMPLETED") {
+		task.Completed = true
+	}
+
+	if due := vtodo.get("DUE"); due != nil {
+		if dueAt, err := parseTime(due); err == nil {
+			task.Due = &dueAt
+		}
+	}
+
+	task.Priority, _ = strconv.Atoi(vtodo.value("PRIORITY"))
+
+	if task.Priority < 0 || task.Priority > maxPriority {
+		task.Priority = 0
+	}
+
+	for _, prop := range vtodo.Properties {
+		if prop.Name != "CATEGORIES" {
+			continue
+		}
+
+		for _, tag := range splitList(prop.Value) {
+			if tag = strings.TrimSpace(unescapeText(tag)); tag != "" {
+				task.Tags = append(task.Tags, tag)
+			}
+		}
+	}
+
+	for _, prop := range vtodo.Properties {
+		reltype := prop.Params["RELTYPE"]
+
+		if prop.Name == "RELATED-TO" && (reltype == "" || strings.EqualFold(reltype, "PARENT")) {
+			task.ParentID = unescapeText(prop.Value)
+			break
+		}
+	}
+
+	return task, nil
+}
+
+// isDate reports whether the time is midnight in the local time zone. Dates without
+// time, like DATE values or the due dates of todo.txt, are stored as local midnight
+func isDate(t time.Time) bool {
+	t = t.In(time.Local)
+
+	return t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 && t.Nanosecond() == 0
+}
+
+// parseTime parses DATE values as local midnight and DATE-TIME values in UTC,
+// in the time zone of the TZID parameter or as floating local time
+func parseTime(prop *property) (time.Time, error) {
+	if prop.Params["VALUE"] == "DATE" || len(prop.Value) == len(dateLayout) {
+		return time.ParseInLocation(dateLayout, prop.Value, time.Local)
+	}
+
+	if strings.HasSuffix(prop.Value, "Z") {
+		return time.Parse(utcLayout, prop.Value)
+	}
+
+	location := time.Local
+
+	if tzid := prop.Params["TZID"]; tzid != "" {
+		if loaded, err := time.LoadLocation(tzid); err == nil {
+			location = loaded
+		}
+	}
+
+	return time.ParseInLocation(dateTimeLayout, prop.Value, location)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,159 @@
+package ics_test
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/ics"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+func roundTrip(t *testing.T, calendar *ics.Calendar) (string, *ics.Calendar) {
+	t.Helper()
+
+	b := &bytes.Buffer{}
+	err := ics.Encode(b, calendar)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := ics.Decode(bytes.NewReader(b.Bytes()))
+
+	if err != nil {
+		t.Fatalf("decoding\n%s: %v", b, err)
+	}
+
+	if len(decoded) != 1 {
+		t.Fatalf("decoded %d calendars, want 1", len(decoded))
+	}
+
+	return b.String(), decoded[0]
+}
+
+func TestRoundTrip(t *testing.T) {
+	created := time.Date(2021, time.January, 2, 15, 4, 5, 0, time.UTC)
+	due := time.Date(2021, time.January, 9, 8, 30, 0, 0, time.UTC)
+	task := &tasks.Task{
+		ID:        "id,with;specials\\",
+		ListID:    "work",
+		Text:      "write report, then call Bob; really\\",
+		Notes:     "first line\nsecond line",
+		Priority:  2,
+		Due:       &due,
+		Tags:      []string{"office", "with,comma"},
+		ParentID:  "parent,1",
+		CreatedAt: created,
+	}
+
+	content, calendar := roundTrip(t, &ics.Calendar{List: &lists.List{ID: "work", Name: "Work, Home"}, Tasks: []*tasks.Task{task}})
+
+	for _, want := range []string{"UID:id\\,with\\;specials\\\\\r\n", "RELATED-TO;RELTYPE=PARENT:parent\\,1\r\n", "DESCRIPTION:first line\\nsecond line\r\n", "DUE:20210109T083000Z\r\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content does not contain %q:\n%s", want, content)
+		}
+	}
+
+	if calendar.List.Name != "Work, Home" || calendar.List.ID != "work" {
+		t.Errorf("list = %+v, want Work, Home", calendar.List)
+	}
+
+	got := calendar.Tasks[0]
+	got.Due = timeIn(got.Due, time.UTC)
+	got.CreatedAt = got.CreatedAt.UTC()
+
+	if !reflect.DeepEqual(got, task) {
+		t.Errorf("task = %+v, want %+v", got, task)
+	}
+}
+
+func timeIn(t *time.Time, location *time.Location) *time.Time {
+	if t == nil {
+		return nil
+	}
+
+	in := t.In(location)
+	return &in
+}
+
+func TestDates(t *testing.T) {
+	input := "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:1\r\nDUE;VALUE=DATE:20210109\r\nEND:VTODO\r\n" +
+		"BEGIN:VTODO\r\nUID:2\r\nDUE;TZID=Europe/Berlin:20210109T083000\r\nEND:VTODO\r\n" +
+		"BEGIN:VTODO\r\nUID:3\r\nDUE:20210109T083000Z\r\nEND:VTODO\r\nEND:VCALENDAR\r\n"
+
+	decoded, err := ics.Decode(strings.NewReader(input))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+
+	if err != nil {
+		t.Skip("no time zone database:", err)
+	}
+
+	want := []time.Time{
+		time.Date(2021, time.January, 9, 0, 0, 0, 0, time.Local),
+		time.Date(2021, time.January, 9, 8, 30, 0, 0, berlin),
+		time.Date(2021, time.January, 9, 8, 30, 0, 0, time.UTC),
+	}
+
+	all := decoded[0].Tasks
+
+	for i, task := range all {
+		if task.Due == nil || !task.Due.Equal(want[i]) {
+			t.Errorf("due of %s = %v, want %v", task.ID, task.Due, want[i])
+		}
+	}
+
+	// dates stay dates, times stay times
+	content, _ := roundTrip(t, &ics.Calendar{Tasks: []*tasks.Task{all[0], all[2]}})
+
+	for _, want := range []string{"DUE;VALUE=DATE:20210109\r\n", "DUE:20210109T083000Z\r\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content does not contain %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestFolding(t *testing.T) {
+	text := strings.Repeat("ä", 30) + strings.Repeat("write the report ", 10)
+	content, calendar := roundTrip(t, &ics.Calendar{Tasks: []*tasks.Task{{ID: "1", Text: text}}})
+
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+
+	if got := calendar.Tasks[0].Text; got != text {
+		t.Errorf("text = %q, want %q", got, text)
+	}
+
+	// continuation lines may start with a tab as well
+	task, err := ics.DecodeTask(strings.NewReader("BEGIN:VTODO\r\nUID:1\r\nSUMMARY:write\r\n  the\r\n\t report\r\nEND:VTODO\r\n"))
+
+	if err != nil || task.Text != "write the report" {
+		t.Errorf("task = %+v, %v, want write the report", task, err)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	tests := map[string]string{
+		"unclosed":       "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nUID:1\r\nEND:VTODO\r\n",
+		"unexpected end": "BEGIN:VCALENDAR\r\nEND:VTODO\r\n",
+		"no colon":       "BEGIN:VCALENDAR\r\nSUMMARY\r\nEND:VCALENDAR\r\n",
+		"no uid":         "BEGIN:VCALENDAR\r\nBEGIN:VTODO\r\nSUMMARY:x\r\nEND:VTODO\r\nEND:VCALENDAR\r\n",
+	}
+
+	for name, input := range tests {
+		if _, err := ics.Decode(strings.NewReader(input)); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
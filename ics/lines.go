@@ -0,0 +1,306 @@
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineLength is the length in octets after which content lines are folded
+const maxLineLength = 75
+
+// property is a single content line like DUE;VALUE=DATE:20210102
+type property struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// component is a BEGIN/END block with its properties and nested components
+type component struct {
+	Name       string
+	Properties []*property
+	Components []*component
+}
+
+func (c *component) get(name string) *property {
+	for _, prop := range c.Properties {
+		if prop.Name == name {
+			return prop
+		}
+	}
+
+	return nil
+}
+
+func (c *component) value(name string) string {
+	if prop := c.get(name); prop != nil {
+		return prop.Value
+	}
+
+	return ""
+}
+
+func (c *component) add(name, value string, params ...string) {
+	prop := &property{Name: name, Value: value, Params: map[string]string{}}
+
+	for i := 0; i+1 < len(params); i += 2 {
+		prop.Params[params[i]] = params[i+1]
+	}
+
+	c.Properties = append(c.Properties, prop)
+}
+
+// readComponents parses all top level components of an iCalendar stream
+func readComponents(r io.Reader) ([]*component, error) {
+	lines, err := unfold(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	root := &component{}
+	stack := []*component{root}
+
+	for number, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		prop, err := parseProperty(line)
+
+		if err != nil {
+			return nil, fmt.Errorf("ics: line %d: %w", number+1, err)
+		}
+
+		current := stack[len(stack)-1]
+
+		switch prop.Name {
+		case "BEGIN":
+			child := &component{Name: strings.ToUpper(prop.Value)}
+			current.Components = append(current.Components, child)
+			stack = append(stack, child)
+
+		case "END":
+			if len(stack) == 1 || current.Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("ics: line %d: unexpected END:%s", number+1, prop.Value)
+			}
+
+			stack = stack[:len(stack)-1]
+
+		default:
+			current.Properties = append(current.Properties, prop)
+		}
+	}
+
+	if len(stack) != 1 {
+		return nil, fmt.Errorf("ics: %s is not closed", stack[len(stack)-1].Name)
+	}
+
+	return root.Components, nil
+}
+
+// unfold joins the continuation lines, which start with a space or tab, to their content line
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	lines := []string{}
+
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (*property, error) {
+	prop := &property{Params: map[string]string{}}
+
+	// the value starts at the first colon outside of a quoted parameter value
+	quoted := false
+	split := -1
+
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		}
+
+		if r == ':' && !quoted {
+			split = i
+			break
+		}
+	}
+
+	if split <= 0 {
+		return nil, fmt.Errorf("invalid content line %q", line)
+	}
+
+	prop.Value = line[split+1:]
+	parts := splitParams(line[:split])
+	prop.Name = strings.ToUpper(parts[0])
+
+	for _, param := range parts[1:] {
+		i := strings.Index(param, "=")
+
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid parameter %q", param)
+		}
+
+		prop.Params[strings.ToUpper(param[:i])] = strings.Trim(param[i+1:], `"`)
+	}
+
+	return prop, nil
+}
+
+// splitParams splits the name and parameters at semicolons outside of quotes
+func splitParams(s string) []string {
+	parts := []string{}
+	quoted := false
+	start := 0
+
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ';' && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// writeComponent writes the component with CRLF line endings and folded lines
+func writeComponent(w io.Writer, c *component) error {
+	lines := []string{"BEGIN:" + c.Name}
+
+	for _, prop := range c.Properties {
+		lines = append(lines, prop.String())
+	}
+
+	_, err := io.WriteString(w, foldAll(lines))
+
+	if err != nil {
+		return err
+	}
+
+	for _, child := range c.Components {
+		err = writeComponent(w, child)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "END:"+c.Name+"\r\n")
+
+	return err
+}
+
+func (prop *property) String() string {
+	b := &strings.Builder{}
+	b.WriteString(prop.Name)
+
+	for _, name := range sortedKeys(prop.Params) {
+		value := prop.Params[name]
+
+		if strings.ContainsAny(value, ";:,") {
+			value = `"` + value + `"`
+		}
+
+		b.WriteString(";" + name + "=" + value)
+	}
+
+	b.WriteString(":" + prop.Value)
+
+	return b.String()
+}
+
+func foldAll(lines []string) string {
+	b := &strings.Builder{}
+
+	for _, line := range lines {
+		b.WriteString(fold(line))
+	}
+
+	return b.String()
+}
+
+// fold splits the line into parts of at most maxLineLength octets without splitting characters
+func fold(line string) string {
+	b := &strings.Builder{}
+	length := 0
+
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+
+		if length+size > maxLineLength {
+			b.WriteString("\r\n ")
+			length = 1
+		}
+
+		b.WriteRune(r)
+		length += size
+	}
+
+	b.WriteString("\r\n")
+
+	return b.String()
+}
+
+// escapeText escapes a TEXT value
+func escapeText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// unescapeText reverses escapeText
+func unescapeText(s string) string {
+	b := &strings.Builder{}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		i++
+
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String()
+}
+
+// splitList splits a value at the commas which are not escaped
+func splitList(s string) []string {
+	parts := []string{}
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
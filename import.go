@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/julez-dev/go2todo/importer"
+)
+
+var importFormats = []string{"auto", "ics", "todotxt", "taskwarrior", "todoist", "csv"}
+
+// importTasks reads the tasks of another tool into the store
+func importTasks(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	format := flags.String("format", "auto", "format of the file: "+strings.Join(importFormats, ", "))
+	listName := flags.String("list", "Imported", "list for tasks without list, defaults to the file name for todoist")
+	dryRun := flags.Bool("dry-run", false, "only report what would be imported")
+	mapping := flags.String("map", "", "csv columns of the task fields, like text=Title,due=Due Date")
+	comma := flags.String("comma", ",", "csv field delimiter")
+	dateLayout := flags.String("date-layout", "2006-01-02", "csv layout of due dates in Go time format")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: go2todo import [flags] file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	path := flags.Arg(0)
+
+	if *format == "auto" {
+		detected, err := detectFormat(path)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		*format = detected
+	}
+
+	listSet := false
+	flags.Visit(func(f *flag.Flag) {
+		listSet = listSet || f.Name == "list"
+	})
+
+	var parser importer.Parser
+
+	switch *format {
+	case "ics":
+		parser = importer.ICS{}
+	case "todotxt":
+		parser = importer.TodoTxt{}
+	case "taskwarrior":
+		parser = importer.Taskwarrior{}
+	case "todoist":
+		// the export of a project does not contain its name
+		name := *listName
+
+		if !listSet {
+			name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+
+		parser = importer.Todoist{List: name}
+	case "csv":
+		columns, err := importer.ParseMapping(*mapping)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		delimiter, size := utf8.DecodeRuneInString(*comma)
+
+		if size == 0 || size != len(*comma) {
+			log.Fatalf("invalid delimiter %q, expected a single character", *comma)
+		}
+
+		parser = importer.CSV{Mapping: columns, Comma: delimiter, DateLayout: *dateLayout}
+	default:
+		log.Fatalf("unknown format %q, expected one of %s", *format, strings.Join(importFormats, ", "))
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	defer file.Close()
+
+	records, err := parser.Parse(file)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	store := chooseStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registerHooks(ctx, store, loadConfig(), log.Default())
+
+	report, err := importer.Import(ctx, store, records, importer.Options{
+		DryRun:      *dryRun,
+		DefaultList: *listName,
+	})
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	report.Write(os.Stdout)
+	store.Events.Wait()
+
+	if len(report.Failures) > 0 {
+		os.Exit(1)
+	}
+}
+
+// detectFormat guesses the format by the file extension, CSV files exported by
+// Todoist are recognized by their TYPE and CONTENT columns
+func detectFormat(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ics", ".ical", ".ifb":
+		return "ics", nil
+	case ".txt":
+		return "todotxt", nil
+	case ".json":
+		return "taskwarrior", nil
+	case ".csv":
+		file, err := os.Open(path)
+
+		if err != nil {
+			return "", err
+		}
+
+		defer file.Close()
+
+		reader := csv.NewReader(bufio.NewReader(file))
+		reader.FieldsPerRecord = -1
+		header, err := reader.Read()
+
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+
+		columns := map[string]bool{}
+
+		for _, name := range header {
+			columns[strings.ToUpper(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = true
+		}
+
+		if columns["TYPE"] && columns["CONTENT"] {
+			return "todoist", nil
+		}
+
+		return "csv", nil
+	}
+
+	return "", fmt.Errorf("can't detect the format of %s, set it with -format", path)
+}
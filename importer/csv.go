@@ -0,0 +1,194 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// Fields of a task which can be mapped to CSV columns
+const (
+	FieldText      = "text"
+	FieldList      = "list"
+	FieldCompleted = "completed"
+	FieldPriority  = "priority"
+	FieldDue       = "due"
+	FieldTags      = "tags"
+	FieldNotes     = "notes"
+)
+
+var csvFields = []string{FieldText, FieldList, FieldCompleted, FieldPriority, FieldDue, FieldTags, FieldNotes}
+
+// CSV parses CSV files with a header row. Mapping maps the fields of a task
+// to column names, fields without mapping use the column named like the field
+type CSV struct {
+	Mapping map[string]string
+	// Comma is the field delimiter, it defaults to ','
+	Comma rune
+	// DateLayout is the time.Parse layout of the due column, it defaults to 2006-01-02
+	DateLayout string
+	// TagSeparator splits the tags column, it defaults to ','
+	TagSeparator string
+}
+
+// ParseMapping parses a mapping like "text=Title,due=Due Date"
+func ParseMapping(value string) (map[string]string, error) {
+	mapping := map[string]string{}
+
+	if strings.TrimSpace(value) == "" {
+		return mapping, nil
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+
+		if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+			return nil, fmt.Errorf("csv: invalid mapping %q, expected field=column", pair)
+		}
+
+		field := strings.ToLower(strings.TrimSpace(parts[0]))
+
+		if !isCSVField(field) {
+			return nil, fmt.Errorf("csv: unknown field %q, expected one of %s", field, strings.Join(csvFields, ", "))
+		}
+
+		mapping[field] = strings.TrimSpace(parts[1])
+	}
+
+	return mapping, nil
+}
+
+func isCSVField(field string) bool {
+	for _, known := range csvFields {
+		if known == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c CSV) Parse(r io.Reader) ([]*Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	if c.Comma != 0 {
+		reader.Comma = c.Comma
+	}
+
+	dateLayout := c.DateLayout
+
+	if dateLayout == "" {
+		dateLayout = "2006-01-02"
+	}
+
+	tagSeparator := c.TagSeparator
+
+	if tagSeparator == "" {
+		tagSeparator = ","
+	}
+
+	rows, err := reader.ReadAll()
+
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return []*Record{}, nil
+	}
+
+	header := columnIndex(rows[0])
+	columns := map[string]int{}
+
+	for _, field := range csvFields {
+		name := field
+
+		if mapped, ok := c.Mapping[field]; ok {
+			name = mapped
+		}
+
+		index, ok := header[strings.ToLower(name)]
+
+		if !ok {
+			if _, mapped := c.Mapping[field]; mapped {
+				return nil, fmt.Errorf("csv: column %q of field %s not found", name, field)
+			}
+
+			continue
+		}
+
+		columns[field] = index
+	}
+
+	if _, ok := columns[FieldText]; !ok {
+		return nil, fmt.Errorf("csv: no column for the text of the tasks, map it with text=<column>")
+	}
+
+	records := []*Record{}
+
+	for i, row := range rows[1:] {
+		value := func(field string) string {
+			index, ok := columns[field]
+
+			if !ok || index >= len(row) {
+				return ""
+			}
+
+			return strings.TrimSpace(row[index])
+		}
+
+		source := fmt.Sprintf("row %d", i+2)
+		task := &tasks.Task{
+			Text:      value(FieldText),
+			Notes:     value(FieldNotes),
+			Completed: parseBool(value(FieldCompleted)),
+		}
+
+		if priority := value(FieldPriority); priority != "" {
+			task.Priority, err = strconv.Atoi(priority)
+
+			if err != nil || task.Priority < 0 {
+				return nil, fmt.Errorf("csv: %s: invalid priority %q", source, priority)
+			}
+		}
+
+		if due := value(FieldDue); due != "" {
+			parsed, err := time.ParseInLocation(dateLayout, due, time.Local)
+
+			if err != nil {
+				return nil, fmt.Errorf("csv: %s: invalid due date %q", source, due)
+			}
+
+			task.Due = &parsed
+		}
+
+		for _, tag := range strings.Split(value(FieldTags), tagSeparator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				task.Tags = append(task.Tags, tag)
+			}
+		}
+
+		records = append(records, &Record{
+			Source: source,
+			List:   value(FieldList),
+			Task:   task,
+		})
+	}
+
+	return records, nil
+}
+
+func parseBool(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "x", "true", "yes", "done", "completed":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,75 @@
+package importer
+
+import (
+	"io"
+
+	"github.com/julez-dev/go2todo/ics"
+)
+
+// ICS parses iCalendar files, the name of a calendar names the list of its VTODOs
+type ICS struct{}
+
+func (ICS) Parse(r io.Reader) ([]*Record, error) {
+	calendars, err := ics.Decode(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	records := []*Record{}
+	byUID := map[string]*Record{}
+
+	for _, calendar := range calendars {
+		listName := ""
+
+		if calendar.List != nil {
+			listName = calendar.List.Name
+		}
+
+		for _, task := range calendar.Tasks {
+			record := &Record{
+				Source: "VTODO " + task.ID,
+				List:   listName,
+				Task:   task,
+			}
+
+			byUID[task.ID] = record
+			records = append(records, record)
+		}
+	}
+
+	for _, record := range records {
+		record.Parent = byUID[record.Task.ParentID]
+	}
+
+	return sortParentsFirst(records), nil
+}
+
+// sortParentsFirst orders the records so every parent comes before its subtasks
+func sortParentsFirst(records []*Record) []*Record {
+	sorted := make([]*Record, 0, len(records))
+	added := map[*Record]bool{}
+
+	var add func(*Record, int)
+	add = func(record *Record, depth int) {
+		if added[record] {
+			return
+		}
+
+		// a cycle of parents would recurse forever, its remaining records are added as they are
+		if record.Parent != nil && depth < len(records) {
+			add(record.Parent, depth+1)
+		}
+
+		if !added[record] {
+			added[record] = true
+			sorted = append(sorted, record)
+		}
+	}
+
+	for _, record := range records {
+		add(record, 0)
+	}
+
+	return sorted
+}
@@ -0,0 +1,223 @@
+// Package importer brings tasks of other tools into go2todo. Parsers turn
+// the files of a tool into records, Import writes them through the storage
+// layer, skipping the tasks which already exist
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+// Record is a task read from another tool
+type Record struct {
+	// Source locates the record in the input for the report, for example "line 3"
+	Source string
+	// List is the name of the list of the task, empty means the default list
+	List string
+	// Task holds the fields of the task, its ID and ListID are assigned on import
+	Task *tasks.Task
+	// Parent is the record of the task this one is a subtask of
+	Parent *Record
+}
+
+// Parser reads the records of a tool, parents have to come before their subtasks
+type Parser interface {
+	Parse(io.Reader) ([]*Record, error)
+}
+
+type Options struct {
+	// DryRun only reports what would be imported
+	DryRun bool
+	// DefaultList is the list of records without list
+	DefaultList string
+}
+
+type Failure struct {
+	Record *Record
+	Err    error
+}
+
+type Report struct {
+	DryRun bool
+	// Lists are the names of the created lists
+	Lists      []string
+	Created    []*Record
+	Duplicates []*Record
+	Failures   []*Failure
+}
+
+// Write prints a summary of the import, listing the skipped and failed records
+func (r *Report) Write(w io.Writer) {
+	verb := "imported"
+
+	if r.DryRun {
+		verb = "would import"
+	}
+
+	fmt.Fprintf(w, "%s %d tasks, %d new lists, %d duplicates skipped, %d failed\n",
+		verb, len(r.Created), len(r.Lists), len(r.Duplicates), len(r.Failures))
+
+	for _, name := range r.Lists {
+		fmt.Fprintf(w, "  new list %q\n", name)
+	}
+
+	for _, record := range r.Duplicates {
+		fmt.Fprintf(w, "  duplicate %s: %q\n", record.Source, record.Task.Text)
+	}
+
+	for _, failure := range r.Failures {
+		fmt.Fprintf(w, "  failed %s: %v\n", failure.Record.Source, failure.Err)
+	}
+}
+
+// Import stores the records, creating missing lists by name. Records whose text
+// already exists in the same list, ignoring case and spacing, are skipped
+func Import(ctx context.Context, store service.Interface, records []*Record, opts Options) (*Report, error) {
+	existingLists, err := store.GetLists(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	existingTasks, err := store.GetAllTasks(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	im := &importer{
+		store:   store,
+		opts:    opts,
+		report:  &Report{DryRun: opts.DryRun},
+		lists:   map[string]*lists.List{},
+		known:   map[string]*tasks.Task{},
+		created: map[*Record]*tasks.Task{},
+	}
+
+	names := map[string]string{}
+
+	for _, list := range existingLists {
+		names[list.ID] = list.Name
+
+		// tasks can't be imported into smart lists, a real list of the same name is created instead
+		if !list.Smart() {
+			im.lists[normalize(list.Name)] = list
+		}
+	}
+
+	for _, task := range existingTasks {
+		im.known[key(names[task.ListID], task.Text)] = task
+	}
+
+	for _, record := range records {
+		err := im.add(ctx, record)
+
+		if err != nil {
+			im.report.Failures = append(im.report.Failures, &Failure{Record: record, Err: err})
+		}
+	}
+
+	return im.report, nil
+}
+
+type importer struct {
+	store  service.Interface
+	opts   Options
+	report *Report
+
+	// lists and known are indexed by normalized names and keys
+	lists map[string]*lists.List
+	known map[string]*tasks.Task
+	// created contains the tasks of all imported and duplicate records
+	created map[*Record]*tasks.Task
+}
+
+func (im *importer) add(ctx context.Context, record *Record) error {
+	if strings.TrimSpace(record.Task.Text) == "" {
+		return fmt.Errorf("task has no text")
+	}
+
+	listName := record.List
+
+	if strings.TrimSpace(listName) == "" {
+		listName = im.opts.DefaultList
+	}
+
+	k := key(listName, record.Task.Text)
+
+	if duplicate, ok := im.known[k]; ok {
+		im.created[record] = duplicate
+		im.report.Duplicates = append(im.report.Duplicates, record)
+		return nil
+	}
+
+	list, err := im.list(ctx, listName)
+
+	if err != nil {
+		return err
+	}
+
+	task := *record.Task
+	task.ID = ""
+	task.ListID = list.ID
+	task.ParentID = ""
+
+	if parent, ok := im.created[record.Parent]; ok && record.Parent != nil {
+		task.ParentID = parent.ID
+	}
+
+	stored := &task
+
+	if !im.opts.DryRun {
+		stored, err = im.store.StoreTask(ctx, stored)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	im.known[k] = stored
+	im.created[record] = stored
+	im.report.Created = append(im.report.Created, record)
+
+	return nil
+}
+
+// list returns the list with the name, creating it if necessary
+func (im *importer) list(ctx context.Context, name string) (*lists.List, error) {
+	if list, ok := im.lists[normalize(name)]; ok {
+		return list, nil
+	}
+
+	list := &lists.List{Name: strings.TrimSpace(name)}
+
+	if !im.opts.DryRun {
+		stored, err := im.store.StoreList(ctx, list)
+
+		if err != nil {
+			return nil, err
+		}
+
+		list = stored
+	}
+
+	im.lists[normalize(name)] = list
+	im.report.Lists = append(im.report.Lists, list.Name)
+
+	return list, nil
+}
+
+// key identifies a task for the de-duplication
+func key(list, text string) string {
+	return normalize(list) + "\x00" + normalize(text)
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
@@ -0,0 +1,226 @@
+package importer_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/julez-dev/go2todo/importer"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service/fake"
+)
+
+// summarize writes the mapped fields of the records as list|text|done|priority|due|tags|parent|notes
+func summarize(records []*importer.Record) []string {
+	summaries := []string{}
+
+	for _, record := range records {
+		task := record.Task
+		due, parent := "", ""
+
+		if task.Due != nil {
+			due = task.Due.Format("2006-01-02")
+		}
+
+		if record.Parent != nil {
+			parent = record.Parent.Task.Text
+		}
+
+		summaries = append(summaries, fmt.Sprintf("%s|%s|%v|%d|%s|%s|%s|%s",
+			record.List, task.Text, task.Completed, task.Priority, due, strings.Join(task.Tags, ","), parent, task.Notes))
+	}
+
+	return summaries
+}
+
+func parse(t *testing.T, parser importer.Parser, fixture string) ([]*importer.Record, error) {
+	t.Helper()
+
+	f, err := os.Open(filepath.Join("testdata", fixture))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	return parser.Parse(f)
+}
+
+func TestParsers(t *testing.T) {
+	taskwarrior := []string{
+		"Work|write report|false|1|2021-03-05|office,q1||Quarterly numbers\nask Alice",
+		"Work|call Bob|true|0||||",
+		"|buy milk|false|3||||",
+	}
+
+	tests := []struct {
+		name    string
+		parser  importer.Parser
+		fixture string
+		want    []string
+	}{
+		{
+			name: "csv",
+			parser: importer.CSV{
+				Mapping: map[string]string{
+					importer.FieldText: "Title", importer.FieldList: "Project", importer.FieldCompleted: "Done",
+					importer.FieldPriority: "Prio", importer.FieldDue: "Due Date", importer.FieldTags: "Labels",
+					importer.FieldNotes: "Notes",
+				},
+				DateLayout:   "02.01.2006",
+				TagSeparator: ";",
+			},
+			fixture: "tasks.csv",
+			want: []string{
+				"Work|write report|false|1|2021-03-05|office,q1||Quarterly numbers",
+				"Work|call Bob|true|0||||",
+				"|buy milk|true|3||||",
+			},
+		},
+		{
+			name:    "ics",
+			parser:  importer.ICS{},
+			fixture: "tasks.ics",
+			want: []string{
+				"Work|write report|false|1|2021-03-05|office,q1||",
+				"Work|collect numbers|false|0|||write report|",
+				"Work|call Bob|true|0||||",
+				"|buy milk|false|0||||",
+			},
+		},
+		{
+			name:    "taskwarrior array",
+			parser:  importer.Taskwarrior{},
+			fixture: "taskwarrior.json",
+			want:    taskwarrior,
+		},
+		{
+			name:    "taskwarrior lines",
+			parser:  importer.Taskwarrior{},
+			fixture: "taskwarrior-lines.json",
+			want:    taskwarrior,
+		},
+		{
+			name:    "todoist",
+			parser:  importer.Todoist{List: "Plans"},
+			fixture: "todoist.csv",
+			want: []string{
+				"Plans|write report|false|1|2021-03-05|Q1,office||Quarterly numbers",
+				"Plans|collect numbers|false|0||Q1|write report|",
+				"Plans|call Bob|false|3|2021-03-05|Q1||",
+				"Plans|buy milk|false|0||Later||",
+			},
+		},
+		{
+			name:    "todo.txt",
+			parser:  importer.TodoTxt{},
+			fixture: "todo.txt",
+			want: []string{
+				"Work|write report|false|1|2021-03-05|office,q1||",
+				"Work|collect numbers|false|0|||write report|",
+				"Work|call Bob|true|0||||",
+				"|buy milk|false|3||||",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			records, err := parse(t, test.parser, test.fixture)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := summarize(records); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("records =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		parser  importer.Parser
+		fixture string
+		want    string
+	}{
+		{"csv priority", importer.CSV{Mapping: map[string]string{importer.FieldText: "Title", importer.FieldPriority: "Prio"}}, "bad-priority.csv", `row 3: invalid priority "high"`},
+		{"csv due", importer.CSV{Mapping: map[string]string{importer.FieldText: "Title", importer.FieldDue: "Due Date"}}, "bad-due.csv", `row 2: invalid due date "March 5th"`},
+		{"csv mapping", importer.CSV{Mapping: map[string]string{importer.FieldText: "Name"}}, "tasks.csv", `column "Name" of field text not found`},
+		{"csv text", importer.CSV{}, "tasks.csv", "no column for the text"},
+		{"ics", importer.ICS{}, "bad.ics", "unexpected END:VCALENDAR"},
+		{"taskwarrior", importer.Taskwarrior{}, "bad-taskwarrior.json", "line 2"},
+		{"todoist", importer.Todoist{}, "bad-todoist.csv", "missing column TYPE"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parse(t, test.parser, test.fixture)
+
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("err = %v, want it to contain %q", err, test.want)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	store := fake.New()
+	work, _ := store.StoreList(ctx, &lists.List{Name: "work"})
+	store.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "Call  bob"})
+
+	records, err := parse(t, importer.TodoTxt{}, "todo.txt")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a dry run reports the same as the import but stores nothing
+	report, err := importer.Import(ctx, store, records, importer.Options{DryRun: true, DefaultList: "Inbox"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if all, _ := store.GetAllTasks(ctx); len(all) != 1 || len(report.Created) != 3 {
+		t.Fatalf("dry run stored %d tasks and reported %d, want 1 stored and 3 reported", len(all), len(report.Created))
+	}
+
+	report, err = importer.Import(ctx, store, records, importer.Options{DefaultList: "Inbox"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the existing list and task are matched ignoring case and spacing
+	if !reflect.DeepEqual(report.Lists, []string{"Inbox"}) || len(report.Duplicates) != 1 || len(report.Failures) != 0 {
+		t.Errorf("report = %+v, want the new list Inbox and one duplicate", report)
+	}
+
+	// the subtask gets the ID its parent was stored with
+	imported, _ := store.GetTasks(ctx, work.ID)
+	byText := map[string]*tasks.Task{}
+
+	for _, task := range imported {
+		byText[task.Text] = task
+	}
+
+	if len(imported) != 3 || byText["collect numbers"].ParentID != byText["write report"].ID {
+		t.Errorf("tasks of work = %v, want collect numbers as subtask of write report", imported)
+	}
+
+	report, _ = importer.Import(ctx, store, records, importer.Options{DefaultList: "Inbox"})
+
+	if len(report.Created) != 0 || len(report.Duplicates) != 4 {
+		t.Errorf("second import created %d tasks, want all 4 skipped", len(report.Created))
+	}
+}
@@ -0,0 +1,135 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+const taskwarriorTimeLayout = "20060102T150405Z"
+
+// Taskwarrior parses the output of "task export", either as JSON array
+// or as one JSON object per line like older versions write it. Deleted
+// tasks and recurrence templates are skipped
+type Taskwarrior struct{}
+
+type taskwarriorTask struct {
+	UUID        string   `json:"uuid"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Project     string   `json:"project"`
+	Priority    string   `json:"priority"`
+	Tags        []string `json:"tags"`
+	Due         string   `json:"due"`
+	End         string   `json:"end"`
+	// Annotations become the notes of the task, one per line
+	Annotations []struct {
+		Description string `json:"description"`
+	} `json:"annotations"`
+}
+
+func (Taskwarrior) Parse(r io.Reader) ([]*Record, error) {
+	data, err := ioutil.ReadAll(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	exported := []*taskwarriorTask{}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &exported)
+
+		if err != nil {
+			return nil, fmt.Errorf("taskwarrior: %w", err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+		for number := 1; scanner.Scan(); number++ {
+			line := bytes.TrimSpace(bytes.TrimSuffix(bytes.TrimSpace(scanner.Bytes()), []byte(",")))
+
+			if len(line) == 0 {
+				continue
+			}
+
+			task := &taskwarriorTask{}
+			err = json.Unmarshal(line, task)
+
+			if err != nil {
+				return nil, fmt.Errorf("taskwarrior: line %d: %w", number, err)
+			}
+
+			exported = append(exported, task)
+		}
+
+		if scanner.Err() != nil {
+			return nil, scanner.Err()
+		}
+	}
+
+	records := []*Record{}
+
+	for i, exportedTask := range exported {
+		if exportedTask.Status == "deleted" || exportedTask.Status == "recurring" {
+			continue
+		}
+
+		task := &tasks.Task{
+			Text:      exportedTask.Description,
+			Completed: exportedTask.Status == "completed",
+			Priority:  taskwarriorPriority(exportedTask.Priority),
+			Tags:      exportedTask.Tags,
+		}
+
+		for _, annotation := range exportedTask.Annotations {
+			if task.Notes != "" {
+				task.Notes += "\n"
+			}
+
+			task.Notes += annotation.Description
+		}
+
+		if due, err := time.Parse(taskwarriorTimeLayout, exportedTask.Due); err == nil {
+			task.Due = &due
+		}
+
+		if end, err := time.Parse(taskwarriorTimeLayout, exportedTask.End); err == nil && task.Completed {
+			task.CompletedAt = &end
+		}
+
+		source := fmt.Sprintf("task %d", i+1)
+
+		if exportedTask.UUID != "" {
+			source = "task " + exportedTask.UUID
+		}
+
+		records = append(records, &Record{
+			Source: source,
+			List:   exportedTask.Project,
+			Task:   task,
+		})
+	}
+
+	return records, nil
+}
+
+func taskwarriorPriority(priority string) int {
+	switch priority {
+	case "H":
+		return 1
+	case "M":
+		return 2
+	case "L":
+		return 3
+	}
+
+	return 0
+}
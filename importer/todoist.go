@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// todoistDateLayouts are tried in order for the DATE column, which may also
+// contain recurring dates like "every monday" that can't be imported
+var todoistDateLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"Jan 2 2006",
+	"Jan 2 2006 15:04",
+	"2 Jan 2006",
+	"2 Jan 2006 15:04",
+}
+
+// Todoist parses the CSV export of a Todoist project. The export contains
+// no project name, so all tasks go to List. Sections become tags, labels
+// written as @label in the content become tags as well
+type Todoist struct {
+	List string
+}
+
+func (t Todoist) Parse(r io.Reader) ([]*Record, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+
+	if err != nil {
+		return nil, fmt.Errorf("todoist: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return []*Record{}, nil
+	}
+
+	columns := columnIndex(rows[0])
+
+	for _, required := range []string{"type", "content"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("todoist: missing column %s", strings.ToUpper(required))
+		}
+	}
+
+	records := []*Record{}
+	section := ""
+	// parents holds the last record of every indentation level
+	parents := []*Record{}
+
+	for i, row := range rows[1:] {
+		value := func(column string) string {
+			index, ok := columns[column]
+
+			if !ok || index >= len(row) {
+				return ""
+			}
+
+			return strings.TrimSpace(row[index])
+		}
+
+		switch value("type") {
+		case "section":
+			section = value("content")
+			parents = nil
+			continue
+		case "task":
+		default:
+			continue
+		}
+
+		text, labels := splitLabels(value("content"))
+		task := &tasks.Task{
+			Text:     text,
+			Notes:    value("description"),
+			Priority: todoistPriority(value("priority")),
+			Tags:     labels,
+		}
+
+		if section != "" {
+			task.Tags = append([]string{section}, task.Tags...)
+		}
+
+		if due, ok := parseTodoistDate(value("date")); ok {
+			task.Due = &due
+		}
+
+		record := &Record{
+			Source: fmt.Sprintf("row %d", i+2),
+			List:   t.List,
+			Task:   task,
+		}
+
+		indent, err := strconv.Atoi(value("indent"))
+
+		if err != nil || indent < 1 {
+			indent = 1
+		}
+
+		if indent > len(parents)+1 {
+			indent = len(parents) + 1
+		}
+
+		parents = append(parents[:indent-1], record)
+
+		if indent > 1 {
+			record.Parent = parents[indent-2]
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// splitLabels removes the @labels from the content of a task
+func splitLabels(content string) (string, []string) {
+	words := []string{}
+	labels := []string{}
+
+	for _, word := range strings.Fields(content) {
+		if len(word) > 1 && strings.HasPrefix(word, "@") {
+			labels = append(labels, word[1:])
+			continue
+		}
+
+		words = append(words, word)
+	}
+
+	return strings.Join(words, " "), labels
+}
+
+// todoistPriority maps the priority column, where 4 is p1 and 1 no priority
+func todoistPriority(value string) int {
+	priority, err := strconv.Atoi(value)
+
+	if err != nil || priority < 2 || priority > 4 {
+		return 0
+	}
+
+	return 5 - priority
+}
+
+func parseTodoistDate(value string) (time.Time, bool) {
+	for _, layout := range todoistDateLayouts {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// columnIndex maps the lower case names of a header row to their index
+func columnIndex(header []string) map[string]int {
+	columns := map[string]int{}
+
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+
+	return columns
+}
@@ -0,0 +1,46 @@
+package importer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/julez-dev/go2todo/repo/todotxt"
+)
+
+// TodoTxt parses todo.txt files, the first +project of a line names its list.
+// The parent:... extension refers to the id:... extension of another line
+type TodoTxt struct{}
+
+func (TodoTxt) Parse(r io.Reader) ([]*Record, error) {
+	scanner := bufio.NewScanner(r)
+	records := []*Record{}
+	byID := map[string]*Record{}
+
+	for number := 1; scanner.Scan(); number++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		line := todotxt.Parse(scanner.Text())
+		record := &Record{
+			Source: fmt.Sprintf("line %d", number),
+			List:   todotxt.ListName(line.Project),
+			Task:   line.Task,
+		}
+
+		byID[line.Task.ID] = record
+		records = append(records, record)
+	}
+
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+
+	for _, record := range records {
+		record.Parent = byID[record.Task.ParentID]
+	}
+
+	return sortParentsFirst(records), nil
+}
@@ -1,22 +1,35 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/hooks"
 	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/markdown"
+	"github.com/julez-dev/go2todo/repo/remote"
 	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/repo/todotxt"
 	"github.com/julez-dev/go2todo/service"
 	"github.com/julez-dev/go2todo/ui"
+	"github.com/julez-dev/go2todo/webhooks"
+	_ "github.com/lib/pq"
+	bolt "go.etcd.io/bbolt"
 	_ "modernc.org/sqlite"
 )
 
 func chooseStore() *service.Storage {
 	storageType := os.Getenv("GO2TODO_STORAGETYPE")
 
-	if storageType == "sql" {
+	switch storageType {
+	case "sql":
 		sql, err := sql.Open("sqlite", os.Getenv("GO2TODO_SQLPATH"))
 
 		if err != nil {
@@ -38,6 +51,96 @@ func chooseStore() *service.Storage {
 		}
 
 		return service.NewStorage(tasksDB, listsDB)
+
+	case "postgres":
+		db, err := sql.Open("postgres", os.Getenv("GO2TODO_POSTGRESURL"))
+
+		if err != nil {
+			log.Fatalf("could not open postgres: %v", err)
+		}
+
+		// Open only checks the URL, the connection is made on first use
+		err = db.Ping()
+
+		if err != nil {
+			log.Fatalf("could not connect to postgres: %v", err)
+		}
+
+		listsDB, err := lists.NewInPostgres(db)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		tasksDB, err := tasks.NewInPostgres(db)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		return service.NewStorage(tasksDB, listsDB)
+
+	case "bolt":
+		path := os.Getenv("GO2TODO_BOLTPATH")
+
+		if path == "" {
+			path = "go2todo.db"
+		}
+
+		db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		listsDB, err := lists.NewInBolt(db)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		tasksDB, err := tasks.NewInBolt(db)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		return service.NewStorage(tasksDB, listsDB)
+
+	case "remote":
+		client := remote.New(os.Getenv("GO2TODO_REMOTEURL"), os.Getenv("GO2TODO_TOKEN"))
+
+		return service.NewStorage(client, client)
+
+	case "todotxt":
+		path := os.Getenv("GO2TODO_TODOTXTPATH")
+
+		if path == "" {
+			path = "todo.txt"
+		}
+
+		file, err := todotxt.New(path)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		return service.NewStorage(file, file)
+
+	case "markdown":
+		path := os.Getenv("GO2TODO_MARKDOWNPATH")
+
+		if path == "" {
+			path = "TODO.md"
+		}
+
+		file, err := markdown.New(path)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		return service.NewStorage(file, file)
 	}
 
 	taskPath := os.Getenv("GO2TODO_TASKPATH")
@@ -67,7 +170,128 @@ func chooseStore() *service.Storage {
 	return service.NewStorage(taskDB, listDB)
 }
 
+// loadConfig reads the configuration file, a broken file ends the process
+func loadConfig() *config.Config {
+	cfg, err := config.Load()
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	return cfg
+}
+
+// registerHooks adds the hooks and webhooks of the configuration to the store,
+// webhooks are delivered until ctx is done
+func registerHooks(ctx context.Context, store *service.Storage, cfg *config.Config, errorLog *log.Logger) {
+	// the server runs the hooks of the changes made through a remote store
+	if _, ok := store.TasksRepo.(service.Remote); ok {
+		return
+	}
+
+	err := hooks.Register(store.Events, cfg.Hooks, errorLog)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if len(cfg.Webhooks) == 0 {
+		return
+	}
+
+	dir := cfg.WebhookDir
+
+	if dir == "" {
+		configDir, err := config.Dir()
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		dir = filepath.Join(configDir, "webhooks")
+	}
+
+	dispatcher, err := webhooks.New(dir, cfg.Webhooks)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	dispatcher.ErrorLog = errorLog
+	store.Events.After(dispatcher.Enqueue)
+
+	go dispatcher.Run(ctx)
+}
+
+// hookLog opens the log for failing hooks of the TUI, which can't write to the terminal
+func hookLog() *log.Logger {
+	dir, err := os.UserCacheDir()
+
+	if err != nil {
+		return log.New(ioutil.Discard, "", 0)
+	}
+
+	err = os.MkdirAll(filepath.Join(dir, "go2todo"), 0o755)
+
+	if err != nil {
+		return log.New(ioutil.Discard, "", 0)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, "go2todo", "hooks.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+
+	if err != nil {
+		return log.New(ioutil.Discard, "", 0)
+	}
+
+	return log.New(file, "", log.LstdFlags)
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			serve(os.Args[2:])
+			return
+		case "export":
+			exportTasks(os.Args[2:])
+			return
+		case "import":
+			importTasks(os.Args[2:])
+			return
+		case "query":
+			queryTasks(os.Args[2:])
+			return
+		case "edit":
+			editTask(os.Args[2:])
+			return
+		case "smartlist":
+			smartLists(os.Args[2:])
+			return
+		case "backup":
+			backupStore(os.Args[2:])
+			return
+		case "restore":
+			restoreStore(os.Args[2:])
+			return
+		}
+	}
+
 	store := chooseStore()
-	tea.NewProgram(ui.New(store)).Start()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := loadConfig()
+	registerHooks(ctx, store, cfg, hookLog())
+
+	model, err := ui.New(store, cfg.UI)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	tea.NewProgram(model).Start()
+
+	// give running hooks the chance to finish, undelivered webhooks stay queued for the next start
+	store.Events.Wait()
 }
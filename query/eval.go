@@ -0,0 +1,394 @@
+package query
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/migrate"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+var _ tasks.Filter = (*Filter)(nil)
+
+// Filter is the condition of a query bound to the lists, which resolve list
+// names, and to the time relative dates are resolved against
+type Filter struct {
+	where Node
+	lists []*lists.List
+	now   time.Time
+}
+
+// Bind returns the filter of the query, all is the list of all lists
+func (q *Query) Bind(all []*lists.List, now time.Time) *Filter {
+	return &Filter{where: q.Where, lists: all, now: now}
+}
+
+// Match reports whether the task matches the query
+func (f *Filter) Match(task *tasks.Task) bool {
+	if f.where == nil {
+		return true
+	}
+
+	return f.match(f.where, task)
+}
+
+// Apply returns the matching tasks
+func (f *Filter) Apply(all []*tasks.Task) []*tasks.Task {
+	matching := []*tasks.Task{}
+
+	for _, task := range all {
+		if f.Match(task) {
+			matching = append(matching, task)
+		}
+	}
+
+	return matching
+}
+
+func (f *Filter) match(node Node, task *tasks.Task) bool {
+	switch node := node.(type) {
+	case *And:
+		for _, child := range node.Nodes {
+			if !f.match(child, task) {
+				return false
+			}
+		}
+
+		return true
+
+	case *Or:
+		for _, child := range node.Nodes {
+			if f.match(child, task) {
+				return true
+			}
+		}
+
+		return false
+
+	case *Not:
+		return !f.match(node.Node, task)
+
+	case *Term:
+		return f.matchTerm(node, task)
+	}
+
+	return false
+}
+
+func (f *Filter) matchTerm(term *Term, task *tasks.Task) bool {
+	switch term.Field {
+	case "list":
+		for _, id := range f.listIDs(term.Value) {
+			if task.ListID == id {
+				return true
+			}
+		}
+
+		return false
+
+	case "tag":
+		for _, tag := range task.Tags {
+			if strings.EqualFold(tag, term.Value) {
+				return true
+			}
+		}
+
+		return false
+
+	case "text":
+		return strings.Contains(strings.ToLower(task.Text), strings.ToLower(term.Value))
+
+	case "is":
+		switch term.Value {
+		case "done":
+			return task.Completed
+		case "open":
+			return !task.Completed
+		case "subtask":
+			return task.ParentID != ""
+		}
+
+	case "has":
+		switch term.Value {
+		case "due":
+			return task.Due != nil
+		case "tags":
+			return len(task.Tags) > 0
+		case "notes":
+			return task.Notes != ""
+		case "parent":
+			return task.ParentID != ""
+		}
+
+	case "priority":
+		if term.Value == "none" {
+			return task.Priority == 0
+		}
+
+		// tasks without priority are neither higher nor lower than any priority
+		priority, _ := strconv.Atoi(term.Value)
+
+		if task.Priority == 0 {
+			return term.Op == OpMatch && priority == 0
+		}
+
+		return compare(term.Op, task.Priority-priority)
+
+	case "due", "created", "completed":
+		var value *time.Time
+
+		switch term.Field {
+		case "due":
+			value = task.Due
+		case "created":
+			value = &task.CreatedAt
+		case "completed":
+			value = task.CompletedAt
+		}
+
+		switch term.Value {
+		case "none":
+			return value == nil
+		case "any":
+			return value != nil
+		}
+
+		if value == nil {
+			return false
+		}
+
+		start, end, _ := dateRange(term.Value, f.now)
+
+		switch term.Op {
+		case OpMatch:
+			return !value.Before(start) && value.Before(end)
+		case OpLess:
+			return value.Before(start)
+		case OpLessEqual:
+			return value.Before(end)
+		case OpGreater:
+			return !value.Before(end)
+		case OpGreaterEqual:
+			return !value.Before(start)
+		}
+	}
+
+	return false
+}
+
+func compare(op string, diff int) bool {
+	switch op {
+	case OpMatch:
+		return diff == 0
+	case OpLess:
+		return diff < 0
+	case OpLessEqual:
+		return diff <= 0
+	case OpGreater:
+		return diff > 0
+	case OpGreaterEqual:
+		return diff >= 0
+	}
+
+	return false
+}
+
+// listIDs returns the IDs of the lists with the name or ID
+func (f *Filter) listIDs(value string) []string {
+	ids := []string{}
+
+	for _, list := range f.lists {
+		if list.ID == value || strings.EqualFold(list.Name, value) {
+			ids = append(ids, list.ID)
+		}
+	}
+
+	return ids
+}
+
+// SQL translates the filter to a condition on the tasks table. Dates can't be
+// compared in SQLite, which stores them as text with varying time zones
+func (f *Filter) SQL(dialect migrate.Dialect) (string, []interface{}, bool) {
+	if f.where == nil {
+		return "", nil, true
+	}
+
+	return f.sql(f.where, dialect)
+}
+
+// sql returns the condition of the node, an empty condition selects every row
+func (f *Filter) sql(node Node, dialect migrate.Dialect) (string, []interface{}, bool) {
+	switch node := node.(type) {
+	case *And:
+		conditions := []string{}
+		args := []interface{}{}
+		exact := true
+
+		for _, child := range node.Nodes {
+			condition, childArgs, childExact := f.sql(child, dialect)
+			exact = exact && childExact
+
+			if condition != "" {
+				conditions = append(conditions, "("+condition+")")
+				args = append(args, childArgs...)
+			}
+		}
+
+		return strings.Join(conditions, " AND "), args, exact
+
+	case *Or:
+		conditions := []string{}
+		args := []interface{}{}
+		exact := true
+
+		for _, child := range node.Nodes {
+			condition, childArgs, childExact := f.sql(child, dialect)
+
+			// a child selecting everything makes the whole OR select everything
+			if condition == "" {
+				return "", nil, false
+			}
+
+			exact = exact && childExact
+			conditions = append(conditions, "("+condition+")")
+			args = append(args, childArgs...)
+		}
+
+		return strings.Join(conditions, " OR "), args, exact
+
+	case *Not:
+		condition, args, exact := f.sql(node.Node, dialect)
+
+		// the negation of a superset is no superset of the negation
+		if condition == "" || !exact {
+			return "", nil, false
+		}
+
+		return "NOT (" + condition + ")", args, true
+
+	case *Term:
+		return f.termSQL(node, dialect)
+	}
+
+	return "", nil, false
+}
+
+func (f *Filter) termSQL(term *Term, dialect migrate.Dialect) (string, []interface{}, bool) {
+	switch term.Field {
+	case "list":
+		ids := f.listIDs(term.Value)
+
+		if len(ids) == 0 {
+			return "1 = 0", nil, true
+		}
+
+		args := make([]interface{}, len(ids))
+
+		for i, id := range ids {
+			args[i] = id
+		}
+
+		return "list_id IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")", args, true
+
+	case "tag":
+		// tags are stored as JSON array, the quotes make sure whole tags are matched
+		encoded, _ := json.Marshal(strings.ToLower(term.Value))
+		return `lower(tags) LIKE ? ESCAPE '\'`, []interface{}{"%" + escapeLike(string(encoded)) + "%"}, isASCII(term.Value)
+
+	case "text":
+		// lower only folds ASCII letters in SQLite
+		return `lower(text) LIKE ? ESCAPE '\'`, []interface{}{"%" + escapeLike(strings.ToLower(term.Value)) + "%"}, isASCII(term.Value)
+
+	case "is":
+		switch term.Value {
+		case "done":
+			return "completed = ?", []interface{}{true}, true
+		case "open":
+			return "completed = ?", []interface{}{false}, true
+		case "subtask":
+			return "parent_id <> ''", nil, true
+		}
+
+	case "has":
+		switch term.Value {
+		case "due":
+			return "due IS NOT NULL", nil, true
+		case "tags":
+			return "tags <> '[]'", nil, true
+		case "notes":
+			return "notes <> ''", nil, true
+		case "parent":
+			return "parent_id <> ''", nil, true
+		}
+
+	case "priority":
+		if term.Value == "none" {
+			return "priority = 0", nil, true
+		}
+
+		priority, _ := strconv.Atoi(term.Value)
+
+		if term.Op == OpMatch {
+			return "priority = ?", []interface{}{priority}, true
+		}
+
+		return "priority <> 0 AND priority " + term.Op + " ?", []interface{}{priority}, true
+
+	case "due", "created", "completed":
+		column := term.Field
+
+		if column != "due" {
+			column += "_at"
+		}
+
+		switch term.Value {
+		case "none":
+			return column + " IS NULL", nil, true
+		case "any":
+			return column + " IS NOT NULL", nil, true
+		}
+
+		if dialect.Name != migrate.Postgres.Name {
+			return column + " IS NOT NULL", nil, false
+		}
+
+		start, end, _ := dateRange(term.Value, f.now)
+
+		// tasks without the date have to fail the comparison instead of making it NULL,
+		// NOT would drop them otherwise while Match selects them
+		present := column + " IS NOT NULL AND "
+
+		switch term.Op {
+		case OpMatch:
+			return present + column + " >= ? AND " + column + " < ?", []interface{}{start, end}, true
+		case OpLess:
+			return present + column + " < ?", []interface{}{start}, true
+		case OpLessEqual:
+			return present + column + " < ?", []interface{}{end}, true
+		case OpGreater:
+			return present + column + " >= ?", []interface{}{end}, true
+		case OpGreaterEqual:
+			return present + column + " >= ?", []interface{}{start}, true
+		}
+	}
+
+	return "", nil, false
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+
+	return true
+}
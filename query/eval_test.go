@@ -0,0 +1,131 @@
+package query_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/migrate"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+func TestSQLNegatedDates(t *testing.T) {
+	// neither date is set, every negated date term matches the task in memory
+	undated := &tasks.Task{Text: "write report"}
+
+	tests := []struct {
+		input, want string
+	}{
+		{"!due:today", "NOT (due IS NOT NULL AND due >= ? AND due < ?)"},
+		{"NOT due<7d", "NOT (due IS NOT NULL AND due < ?)"},
+		{"!completed:week", "NOT (completed_at IS NOT NULL AND completed_at >= ? AND completed_at < ?)"},
+		{"!(completed>=yesterday OR due<=tomorrow)", "NOT ((completed_at IS NOT NULL AND completed_at >= ?) OR (due IS NOT NULL AND due < ?))"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			q, err := query.Parse(test.input)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			filter := q.Bind(nil, now)
+
+			if !filter.Match(undated) {
+				t.Fatal("the task without dates does not match")
+			}
+
+			condition, _, exact := filter.SQL(migrate.Postgres)
+
+			if condition != test.want || !exact {
+				t.Errorf("SQL = %q, %v, want the exact %q", condition, exact, test.want)
+			}
+		})
+	}
+}
+
+// now is a Wednesday, the week of the queries starts on Monday the 8th
+var now = time.Date(2021, time.March, 10, 12, 0, 0, 0, time.UTC)
+
+func day(d, hour int) *time.Time {
+	date := time.Date(2021, time.March, d, hour, 0, 0, 0, time.UTC)
+	return &date
+}
+
+// fixture returns the lists and tasks the queries are evaluated against
+func fixture() ([]*lists.List, []*tasks.Task) {
+	all := []*lists.List{{ID: "w", Name: "Work"}, {ID: "h", Name: "Home"}}
+
+	return all, []*tasks.Task{
+		{ID: "1", ListID: "w", Text: "write report", Tags: []string{"backend"}, Priority: 1, Due: day(10, 18), CreatedAt: *day(1, 9)},
+		{ID: "2", ListID: "w", Text: "call Bob", Priority: 3, Completed: true, CompletedAt: day(9, 15), CreatedAt: *day(2, 9)},
+		{ID: "3", ListID: "h", Text: "buy milk", Tags: []string{"Shop"}, Notes: "2 litres", Due: day(20, 0), CreatedAt: *day(5, 9)},
+		{ID: "4", ListID: "w", Text: "fix bug", Tags: []string{"backend"}, ParentID: "1", CreatedAt: *day(10, 9)},
+	}
+}
+
+func texts(all []*tasks.Task) []string {
+	result := []string{}
+
+	for _, task := range all {
+		result = append(result, task.Text)
+	}
+
+	return result
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", []string{"write report", "call Bob", "buy milk", "fix bug"}},
+		{"list:work", []string{"write report", "call Bob", "fix bug"}},
+		{"list:h", []string{"buy milk"}},
+		{"list:missing", []string{}},
+		{"tag:BACKEND", []string{"write report", "fix bug"}},
+		{"MILK", []string{"buy milk"}},
+		{"done", []string{"call Bob"}},
+		{"is:open", []string{"write report", "buy milk", "fix bug"}},
+		{"is:subtask", []string{"fix bug"}},
+		{"has:notes", []string{"buy milk"}},
+		{"has:due", []string{"write report", "buy milk"}},
+		{"has:tags", []string{"write report", "buy milk", "fix bug"}},
+		// tasks without priority are neither higher nor lower than any priority
+		{"priority<=2", []string{"write report"}},
+		{"priority>1", []string{"call Bob"}},
+		{"pri:none", []string{"buy milk", "fix bug"}},
+		{"due:today", []string{"write report"}},
+		{"due<7d", []string{"write report"}},
+		{"due>=7d", []string{"buy milk"}},
+		{"due:2021-03-20", []string{"buy milk"}},
+		{"due:none", []string{"call Bob", "fix bug"}},
+		{"completed:yesterday", []string{"call Bob"}},
+		{"completed:week", []string{"call Bob"}},
+		{"created<today", []string{"write report", "call Bob", "buy milk"}},
+		{"created:today", []string{"fix bug"}},
+		{"tag:backend OR list:home", []string{"write report", "buy milk", "fix bug"}},
+		{"list:work !done tag:backend", []string{"write report", "fix bug"}},
+		{"!(done OR is:subtask)", []string{"write report", "buy milk"}},
+		{"list:work (done OR priority:1)", []string{"write report", "call Bob"}},
+	}
+
+	all, matching := fixture()
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			q, err := query.Parse(test.input)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := texts(q.Bind(all, now).Apply(matching)); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Apply = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	lparenToken
+	rparenToken
+	notToken
+	andToken
+	orToken
+)
+
+type token struct {
+	kind   tokenKind
+	offset int
+
+	// field and op are empty for plain words
+	field  string
+	op     string
+	value  string
+	quoted bool
+}
+
+func (t *token) describe() string {
+	switch t.kind {
+	case lparenToken:
+		return "("
+	case rparenToken:
+		return ")"
+	case notToken:
+		return "NOT"
+	case andToken:
+		return "AND"
+	case orToken:
+		return "OR"
+	}
+
+	return "term " + quote(t.value)
+}
+
+// operators are checked in order, so the two character operators have to come first
+var operators = []string{OpLessEqual, OpGreaterEqual, OpMatch, OpEqual, OpLess, OpGreater}
+
+func lex(input string) ([]*token, error) {
+	tokens := []*token{}
+	runes := []rune(input)
+	// offsets maps rune indexes to byte offsets for the error messages
+	offsets := make([]int, len(runes)+1)
+
+	for i, offset := 0, 0; i < len(runes); i++ {
+		offsets[i] = offset
+		offset += len(string(runes[i]))
+		offsets[i+1] = offset
+	}
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, &token{kind: lparenToken, offset: offsets[i]})
+			i++
+			continue
+		case r == ')':
+			tokens = append(tokens, &token{kind: rparenToken, offset: offsets[i]})
+			i++
+			continue
+		case (r == '!' || r == '-') && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, &token{kind: notToken, offset: offsets[i]})
+			i++
+			continue
+		}
+
+		start := i
+		word := &strings.Builder{}
+		t := &token{kind: wordToken, offset: offsets[i]}
+
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+			if runes[i] == '"' {
+				end, value, ok := readQuoted(runes, i)
+
+				if !ok {
+					return nil, &SyntaxError{Offset: offsets[i], Msg: "unterminated quote"}
+				}
+
+				word.WriteString(value)
+				t.quoted = true
+				i = end
+				continue
+			}
+
+			// the first operator after a field name splits the word, later ones are part of the value
+			if t.op == "" && !t.quoted && isFieldName(word.String()) {
+				if op := operatorAt(runes, i); op != "" {
+					t.field = strings.ToLower(word.String())
+					t.op = op
+					word.Reset()
+					i += len(op)
+					continue
+				}
+			}
+
+			word.WriteRune(runes[i])
+			i++
+		}
+
+		t.value = word.String()
+
+		if t.op == "" && !t.quoted {
+			switch t.value {
+			case "AND":
+				t.kind = andToken
+			case "OR", "|":
+				t.kind = orToken
+			case "NOT":
+				t.kind = notToken
+			}
+		}
+
+		if i == start {
+			i++
+		}
+
+		tokens = append(tokens, t)
+	}
+
+	return tokens, nil
+}
+
+func isFieldName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func operatorAt(runes []rune, i int) string {
+	for _, op := range operators {
+		if strings.HasPrefix(string(runes[i:min(i+len(op), len(runes))]), op) {
+			return op
+		}
+	}
+
+	return ""
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// readQuoted reads the quoted string starting at i, \" and \\ are unescaped
+func readQuoted(runes []rune, i int) (int, string, bool) {
+	value := &strings.Builder{}
+
+	for i++; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				value.WriteRune(runes[i])
+			}
+		case '"':
+			return i + 1, value.String(), true
+		default:
+			value.WriteRune(runes[i])
+		}
+	}
+
+	return 0, "", false
+}
@@ -0,0 +1,499 @@
+// Package query implements the query language for tasks, for example
+//
+//	list:Work tag:backend !done due<7d sort:priority
+//
+// A query is a sequence of terms which all have to match. Terms can be
+// combined with OR, negated with ! or NOT and grouped with parentheses.
+// A term is either a word, which matches the text of a task, or a field,
+// an operator and a value:
+//
+//	list:NAME                 the list with the name or ID, ignoring case
+//	tag:NAME                  tasks with the tag, ignoring case
+//	text:WORDS                tasks whose text contains WORDS, same as a plain word
+//	done                      completed tasks, is:done and is:open work as well
+//	is:subtask                tasks with a parent
+//	has:due, has:tags         tasks with a due date or tags, has:notes with notes
+//	priority<=2, pri:none     priorities are numbers, 1 is the highest
+//	due<7d, due:today         due dates, see below
+//	created>=2021-01-01       creation dates
+//	completed:week            completion dates
+//
+// Dates are YYYY-MM-DD, today, tomorrow, yesterday or a number of days (7d)
+// or weeks (2w) relative to today, negative numbers reach into the past.
+// A date stands for the whole day, so due:today matches all tasks due today
+// and due<7d all tasks due before the day a week from now. week stands for
+// the current week starting on Monday. due:none and completed:none match
+// tasks without the date.
+//
+// sort:FIELD orders the tasks by priority, due, created, completed, text or list, a
+// leading - reverses the order. Several sort terms are applied in turn.
+// group:FIELD groups the tasks by list, tag, priority, due or done
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Operators of a Term
+const (
+	OpMatch        = ":"
+	OpEqual        = "="
+	OpLess         = "<"
+	OpLessEqual    = "<="
+	OpGreater      = ">"
+	OpGreaterEqual = ">="
+)
+
+// fields are the field names of terms, mapped to the name they are stored as
+var fields = map[string]string{
+	"list":      "list",
+	"tag":       "tag",
+	"text":      "text",
+	"is":        "is",
+	"has":       "has",
+	"priority":  "priority",
+	"pri":       "priority",
+	"due":       "due",
+	"created":   "created",
+	"completed": "completed",
+	"sort":      "sort",
+	"group":     "group",
+}
+
+var sortFields = []string{"priority", "due", "created", "completed", "text", "list"}
+
+var groupFields = []string{"list", "tag", "priority", "due", "done"}
+
+// Node is an element of the syntax tree of a query
+type Node interface {
+	String() string
+}
+
+// And matches if all of its nodes match
+type And struct {
+	Nodes []Node
+}
+
+// Or matches if any of its nodes matches
+type Or struct {
+	Nodes []Node
+}
+
+// Not matches if its node does not match
+type Not struct {
+	Node Node
+}
+
+// Term compares a field of the task with a value
+type Term struct {
+	Field string
+	Op    string
+	Value string
+}
+
+func (a *And) String() string {
+	return joinNodes(a.Nodes, " ")
+}
+
+func (o *Or) String() string {
+	return "(" + joinNodes(o.Nodes, " OR ") + ")"
+}
+
+func (n *Not) String() string {
+	return "!" + n.Node.String()
+}
+
+func (t *Term) String() string {
+	return t.Field + t.Op + quote(t.Value)
+}
+
+func joinNodes(nodes []Node, sep string) string {
+	parts := make([]string, len(nodes))
+
+	for i, node := range nodes {
+		parts[i] = node.String()
+	}
+
+	return strings.Join(parts, sep)
+}
+
+func quote(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t()\"") {
+		return strconv.Quote(value)
+	}
+
+	return value
+}
+
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+// Query is a parsed query
+type Query struct {
+	// Where selects the tasks, nil selects all tasks
+	Where Node
+	Sort  []SortKey
+	// Group is the field the tasks are grouped by, empty if they are not grouped
+	Group string
+}
+
+// String formats the query, parsing the result yields the same query
+func (q *Query) String() string {
+	parts := []string{}
+
+	if q.Where != nil {
+		parts = append(parts, q.Where.String())
+	}
+
+	for _, key := range q.Sort {
+		if key.Descending {
+			parts = append(parts, "sort:-"+key.Field)
+		} else {
+			parts = append(parts, "sort:"+key.Field)
+		}
+	}
+
+	if q.Group != "" {
+		parts = append(parts, "group:"+q.Group)
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// SyntaxError describes an invalid query
+type SyntaxError struct {
+	// Offset is the byte offset of the error in the query
+	Offset int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("query: %s at offset %d", e.Msg, e.Offset)
+}
+
+// Parse parses a query, the empty query matches all tasks
+func Parse(input string) (*Query, error) {
+	tokens, err := lex(input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens, query: &Query{}, end: len(input)}
+	where, err := p.parseOr(0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if token := p.peek(); token != nil {
+		return nil, &SyntaxError{Offset: token.offset, Msg: "unexpected " + token.describe()}
+	}
+
+	p.query.Where = where
+
+	return p.query, nil
+}
+
+type parser struct {
+	tokens []*token
+	pos    int
+	query  *Query
+	end    int
+}
+
+func (p *parser) peek() *token {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() *token {
+	token := p.peek()
+	p.pos++
+
+	return token
+}
+
+// parseOr parses terms combined with OR, depth counts the open parentheses
+func (p *parser) parseOr(depth int) (Node, error) {
+	nodes := []Node{}
+
+	for {
+		node, err := p.parseAnd(depth)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if token := p.peek(); token == nil || token.kind != orToken {
+			if len(nodes) == 0 {
+				return node, nil
+			}
+
+			if node == nil {
+				return nil, &SyntaxError{Offset: p.end, Msg: "missing term after OR"}
+			}
+
+			return &Or{Nodes: append(nodes, node)}, nil
+		}
+
+		or := p.next()
+
+		if node == nil {
+			return nil, &SyntaxError{Offset: or.offset, Msg: "missing term before OR"}
+		}
+
+		nodes = append(nodes, node)
+	}
+}
+
+// parseAnd parses a sequence of terms, nil means the sequence only contained sort and group terms
+func (p *parser) parseAnd(depth int) (Node, error) {
+	nodes := []Node{}
+
+	for {
+		token := p.peek()
+
+		if token == nil || token.kind == orToken || (token.kind == rparenToken && depth > 0) {
+			break
+		}
+
+		if token.kind == andToken {
+			p.next()
+			continue
+		}
+
+		node, err := p.parseUnary(depth)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if node != nil {
+			nodes = append(nodes, node)
+		}
+	}
+
+	switch len(nodes) {
+	case 0:
+		return nil, nil
+	case 1:
+		return nodes[0], nil
+	}
+
+	return &And{Nodes: nodes}, nil
+}
+
+func (p *parser) parseUnary(depth int) (Node, error) {
+	token := p.next()
+
+	switch token.kind {
+	case notToken:
+		if next := p.peek(); next == nil {
+			return nil, &SyntaxError{Offset: token.offset, Msg: "missing term after NOT"}
+		}
+
+		node, err := p.parseUnary(depth)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if node == nil {
+			return nil, &SyntaxError{Offset: token.offset, Msg: "sort and group can't be negated"}
+		}
+
+		return &Not{Node: node}, nil
+
+	case lparenToken:
+		node, err := p.parseOr(depth + 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		closing := p.next()
+
+		if closing == nil || closing.kind != rparenToken {
+			return nil, &SyntaxError{Offset: token.offset, Msg: "unclosed parenthesis"}
+		}
+
+		if node == nil {
+			return nil, &SyntaxError{Offset: token.offset, Msg: "empty parentheses"}
+		}
+
+		return node, nil
+
+	case wordToken:
+		return p.parseTerm(token, depth)
+	}
+
+	return nil, &SyntaxError{Offset: token.offset, Msg: "unexpected " + token.describe()}
+}
+
+// parseTerm validates a term, sort and group terms are added to the query and return nil
+func (p *parser) parseTerm(token *token, depth int) (Node, error) {
+	fail := func(format string, args ...interface{}) error {
+		return &SyntaxError{Offset: token.offset, Msg: fmt.Sprintf(format, args...)}
+	}
+
+	if token.field == "" {
+		if token.value == "done" && !token.quoted {
+			return &Term{Field: "is", Op: OpMatch, Value: "done"}, nil
+		}
+
+		return &Term{Field: "text", Op: OpMatch, Value: token.value}, nil
+	}
+
+	field, ok := fields[token.field]
+
+	if !ok {
+		return nil, fail("unknown field %q", token.field)
+	}
+
+	term := &Term{Field: field, Op: token.op, Value: token.value}
+	value := strings.ToLower(term.Value)
+	equality := term.Op == OpMatch || term.Op == OpEqual
+
+	if term.Value == "" {
+		return nil, fail("missing value for %s", term.Field)
+	}
+
+	switch field {
+	case "sort", "group":
+		if depth > 0 || !equality {
+			return nil, fail("%s has to be a top level term like %s:FIELD", field, field)
+		}
+
+		if field == "group" {
+			if !contains(groupFields, value) {
+				return nil, fail("can't group by %q, expected one of %s", term.Value, strings.Join(groupFields, ", "))
+			}
+
+			p.query.Group = value
+			return nil, nil
+		}
+
+		key := SortKey{Field: strings.TrimPrefix(value, "-"), Descending: strings.HasPrefix(value, "-")}
+
+		if !contains(sortFields, key.Field) {
+			return nil, fail("can't sort by %q, expected one of %s", term.Value, strings.Join(sortFields, ", "))
+		}
+
+		p.query.Sort = append(p.query.Sort, key)
+		return nil, nil
+
+	case "list", "tag", "text":
+		if !equality {
+			return nil, fail("%s only supports %s", field, OpMatch)
+		}
+
+	case "is", "has":
+		allowed := []string{"done", "open", "subtask"}
+
+		if field == "has" {
+			allowed = []string{"due", "tags", "notes", "parent"}
+		}
+
+		if !equality || !contains(allowed, value) {
+			return nil, fail("expected %s:%s", field, strings.Join(allowed, "|"))
+		}
+
+		term.Value = value
+
+	case "priority":
+		if value == "none" {
+			if !equality {
+				return nil, fail("priority:none can't be compared")
+			}
+
+			term.Value = value
+			break
+		}
+
+		priority, err := strconv.Atoi(value)
+
+		if err != nil || priority < 0 {
+			return nil, fail("invalid priority %q", term.Value)
+		}
+
+	case "due", "created", "completed":
+		if value == "none" || value == "any" {
+			if !equality || field == "created" {
+				return nil, fail("%s:%s is not supported", field, value)
+			}
+
+			term.Value = value
+			break
+		}
+
+		_, _, err := dateRange(value, time.Now())
+
+		if err != nil {
+			return nil, fail("%v", err)
+		}
+
+		term.Value = value
+	}
+
+	if term.Op == OpEqual {
+		term.Op = OpMatch
+	}
+
+	return term, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dateRange returns the start and end of the day a date value stands for
+func dateRange(value string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	day := today
+
+	switch value {
+	case "week":
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return monday, monday.AddDate(0, 0, 7), nil
+	case "today":
+	case "tomorrow":
+		day = today.AddDate(0, 0, 1)
+	case "yesterday":
+		day = today.AddDate(0, 0, -1)
+	default:
+		if parsed, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+			day = parsed
+			break
+		}
+
+		unit := value[len(value)-1]
+		n, err := strconv.Atoi(strings.TrimPrefix(value[:len(value)-1], "+"))
+
+		if err != nil || (unit != 'd' && unit != 'w') {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD, today, tomorrow, yesterday, week or a number of days like 7d", value)
+		}
+
+		if unit == 'w' {
+			n *= 7
+		}
+
+		day = today.AddDate(0, 0, n)
+	}
+
+	return day, day.AddDate(0, 0, 1), nil
+}
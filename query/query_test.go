@@ -0,0 +1,99 @@
+package query_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/julez-dev/go2todo/query"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"", ""},
+		{"list:Work tag:backend !done due<7d sort:priority", "list:Work tag:backend !is:done due<7d sort:priority"},
+		// AND binds tighter than OR, NOT tighter than AND
+		{"a b OR c", "(text:a text:b OR text:c)"},
+		{"a OR b c", "(text:a OR text:b text:c)"},
+		{"NOT a b", "!text:a text:b"},
+		{"!(a OR b) c", "!(text:a OR text:b) text:c"},
+		{"a AND (b | c)", "text:a (text:b OR text:c)"},
+		{"-done", "!is:done"},
+		{"- done", "text:- is:done"},
+		// quoted values are plain text, even if they look like a keyword
+		{`"done" "OR" text:"buy milk"`, `text:done text:OR text:"buy milk"`},
+		{`"say \"hi\""`, `text:"say \"hi\""`},
+		// only the first operator after a field name splits the term
+		{"pri=2 due>=2021-03-05 text:a<b 5<3", "priority:2 due>=2021-03-05 text:a<b text:5<3"},
+		{"DUE:Today IS:Done", "due:today is:done"},
+		{"sort:-due sort:text group:TAG", "sort:-due sort:text group:tag"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			q, err := query.Parse(test.input)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := q.String(); got != test.want {
+				t.Errorf("Parse(%q) = %q, want %q", test.input, got, test.want)
+			}
+
+			// the formatted query parses to the same query
+			again, err := query.Parse(q.String())
+
+			if err != nil || again.String() != test.want {
+				t.Errorf("Parse(%q) = %v, %v, want %q", q.String(), again, err, test.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		offset int
+		msg    string
+	}{
+		{`a "b`, 2, "unterminated quote"},
+		{"(a", 0, "unclosed parenthesis"},
+		{"a )", 2, "unexpected )"},
+		{"()", 0, "empty parentheses"},
+		{"a OR", 4, "missing term after OR"},
+		{"OR a", 0, "missing term before OR"},
+		{"a NOT", 2, "missing term after NOT"},
+		{"!sort:due", 0, "sort and group can't be negated"},
+		{"(a sort:due)", 3, "sort has to be a top level term like sort:FIELD"},
+		{"group<list", 0, "group has to be a top level term like group:FIELD"},
+		{"a foo:bar", 2, `unknown field "foo"`},
+		{"tag:", 0, "missing value for tag"},
+		{"list<Work", 0, "list only supports :"},
+		{"is:blocked", 0, "expected is:done|open|subtask"},
+		{"priority:high", 0, `invalid priority "high"`},
+		{"priority<none", 0, "priority:none can't be compared"},
+		{"created:none", 0, "created:none is not supported"},
+		{"due:someday", 0, `invalid date "someday", expected YYYY-MM-DD, today, tomorrow, yesterday, week or a number of days like 7d`},
+		{"sort:size", 0, `can't sort by "size", expected one of priority, due, created, completed, text, list`},
+		{"group:size", 0, `can't group by "size", expected one of list, tag, priority, due, done`},
+		// offsets count bytes, not runes
+		{"überall (", 9, "unclosed parenthesis"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			_, err := query.Parse(test.input)
+			syntaxErr := &query.SyntaxError{}
+
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Parse(%q) = %v, want a syntax error", test.input, err)
+			}
+
+			if syntaxErr.Offset != test.offset || syntaxErr.Msg != test.msg {
+				t.Errorf("Parse(%q) = %q at %d, want %q at %d", test.input, syntaxErr.Msg, syntaxErr.Offset, test.msg, test.offset)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package query
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// Group is a named group of tasks
+type Group struct {
+	Name  string        `json:"name"`
+	Tasks []*tasks.Task `json:"tasks"`
+}
+
+// SortTasks orders the tasks by the sort keys of the query, tasks which are
+// equal for all keys keep their order. Tasks without the sorted value come last
+func (q *Query) SortTasks(all []*tasks.Task, lists []*lists.List) {
+	names := listNames(lists)
+
+	sort.SliceStable(all, func(i, j int) bool {
+		for _, key := range q.Sort {
+			c := compareBy(key.Field, all[i], all[j], names)
+
+			if c == 0 {
+				continue
+			}
+
+			// missing values stay last when the order is reversed
+			if key.Descending && !missing(key.Field, all[i]) && !missing(key.Field, all[j]) {
+				c = -c
+			}
+
+			return c < 0
+		}
+
+		return false
+	})
+}
+
+func missing(field string, task *tasks.Task) bool {
+	switch field {
+	case "priority":
+		return task.Priority == 0
+	case "due":
+		return task.Due == nil
+	case "completed":
+		return task.CompletedAt == nil
+	}
+
+	return false
+}
+
+func compareBy(field string, a, b *tasks.Task, names map[string]string) int {
+	if missing(field, a) || missing(field, b) {
+		return boolOrder(missing(field, a), missing(field, b))
+	}
+
+	switch field {
+	case "priority":
+		return a.Priority - b.Priority
+	case "due":
+		return timeOrder(a.Due.Before(*b.Due), b.Due.Before(*a.Due))
+	case "created":
+		return timeOrder(a.CreatedAt.Before(b.CreatedAt), b.CreatedAt.Before(a.CreatedAt))
+	case "completed":
+		return timeOrder(a.CompletedAt.Before(*b.CompletedAt), b.CompletedAt.Before(*a.CompletedAt))
+	case "text":
+		return strings.Compare(strings.ToLower(a.Text), strings.ToLower(b.Text))
+	case "list":
+		return strings.Compare(strings.ToLower(names[a.ListID]), strings.ToLower(names[b.ListID]))
+	}
+
+	return 0
+}
+
+// boolOrder orders false before true
+func boolOrder(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+
+	return -1
+}
+
+func timeOrder(before, after bool) int {
+	switch {
+	case before:
+		return -1
+	case after:
+		return 1
+	}
+
+	return 0
+}
+
+// GroupTasks splits the sorted tasks into the groups of the query, keeping their
+// order inside the groups. Tasks with several tags are part of the group of every
+// tag. Without group field all tasks end up in a single group without name
+func (q *Query) GroupTasks(all []*tasks.Task, lists []*lists.List) []*Group {
+	if q.Group == "" {
+		return []*Group{{Tasks: all}}
+	}
+
+	names := listNames(lists)
+	byName := map[string]*Group{}
+	groups := []*Group{}
+
+	for _, task := range all {
+		for _, name := range groupNames(q.Group, task, names) {
+			group, ok := byName[name]
+
+			if !ok {
+				group = &Group{Name: name}
+				byName[name] = group
+				groups = append(groups, group)
+			}
+
+			group.Tasks = append(group.Tasks, task)
+		}
+	}
+
+	order := groupOrder(q.Group, lists)
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		return order(groups[i].Name) < order(groups[j].Name)
+	})
+
+	return groups
+}
+
+// Names of the groups of tasks without the grouped value
+const (
+	NoList     = "No list"
+	NoTag      = "No tag"
+	NoPriority = "No priority"
+	NoDue      = "No due date"
+	Open       = "Open"
+	Done       = "Done"
+)
+
+func groupNames(field string, task *tasks.Task, names map[string]string) []string {
+	switch field {
+	case "list":
+		if name, ok := names[task.ListID]; ok {
+			return []string{name}
+		}
+
+		return []string{NoList}
+
+	case "tag":
+		if len(task.Tags) == 0 {
+			return []string{NoTag}
+		}
+
+		return task.Tags
+
+	case "priority":
+		if task.Priority == 0 {
+			return []string{NoPriority}
+		}
+
+		return []string{"Priority " + strconv.Itoa(task.Priority)}
+
+	case "due":
+		if task.Due == nil {
+			return []string{NoDue}
+		}
+
+		return []string{task.Due.Format("2006-01-02")}
+
+	case "done":
+		if task.Completed {
+			return []string{Done}
+		}
+
+		return []string{Open}
+	}
+
+	return []string{""}
+}
+
+// groupOrder returns a function ranking the group names of the field, groups of
+// tasks without the value come last
+func groupOrder(field string, all []*lists.List) func(string) string {
+	return func(name string) string {
+		switch name {
+		case NoList, NoTag, NoPriority, NoDue:
+			return "\xff"
+		}
+
+		switch field {
+		case "done":
+			return strconv.FormatBool(name == Done)
+		case "list":
+			for i, list := range all {
+				if list.Name == name {
+					return strconv.Itoa(1e6 + i)
+				}
+			}
+		case "priority":
+			priority, _ := strconv.Atoi(strings.TrimPrefix(name, "Priority "))
+			return strconv.Itoa(1e6 + priority)
+		}
+
+		return strings.ToLower(name)
+	}
+}
+
+func listNames(all []*lists.List) map[string]string {
+	names := map[string]string{}
+
+	for _, list := range all {
+		names[list.ID] = list.Name
+	}
+
+	return names
+}
@@ -0,0 +1,104 @@
+package query_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/julez-dev/go2todo/query"
+)
+
+func TestSortTasks(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"", []string{"write report", "call Bob", "buy milk", "fix bug"}},
+		// tasks without the value come last in both directions and keep their order
+		{"sort:priority", []string{"write report", "call Bob", "buy milk", "fix bug"}},
+		{"sort:-priority", []string{"call Bob", "write report", "buy milk", "fix bug"}},
+		{"sort:due", []string{"write report", "buy milk", "call Bob", "fix bug"}},
+		{"sort:-due", []string{"buy milk", "write report", "call Bob", "fix bug"}},
+		{"sort:completed", []string{"call Bob", "write report", "buy milk", "fix bug"}},
+		{"sort:-created", []string{"fix bug", "buy milk", "call Bob", "write report"}},
+		{"sort:list sort:text", []string{"buy milk", "call Bob", "fix bug", "write report"}},
+	}
+
+	all, _ := fixture()
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			q, err := query.Parse(test.input)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, sorted := fixture()
+			q.SortTasks(sorted, all)
+
+			if got := texts(sorted); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("SortTasks = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestGroupTasks(t *testing.T) {
+	tests := []struct {
+		input string
+		want  map[string][]string
+		order []string
+	}{
+		{"", map[string][]string{"": {"write report", "call Bob", "buy milk", "fix bug"}}, []string{""}},
+		// tasks are part of the group of every tag
+		{"group:tag", map[string][]string{
+			"backend":   {"write report", "fix bug"},
+			"Shop":      {"buy milk"},
+			query.NoTag: {"call Bob"},
+		}, []string{"backend", "Shop", query.NoTag}},
+		{"group:done", map[string][]string{
+			query.Open: {"write report", "buy milk", "fix bug"},
+			query.Done: {"call Bob"},
+		}, []string{query.Open, query.Done}},
+		// lists keep the order of the lists
+		{"group:list", map[string][]string{
+			"Work": {"write report", "call Bob", "fix bug"},
+			"Home": {"buy milk"},
+		}, []string{"Work", "Home"}},
+		{"group:priority", map[string][]string{
+			"Priority 1":     {"write report"},
+			"Priority 3":     {"call Bob"},
+			query.NoPriority: {"buy milk", "fix bug"},
+		}, []string{"Priority 1", "Priority 3", query.NoPriority}},
+		{"group:due", map[string][]string{
+			"2021-03-10": {"write report"},
+			"2021-03-20": {"buy milk"},
+			query.NoDue:  {"call Bob", "fix bug"},
+		}, []string{"2021-03-10", "2021-03-20", query.NoDue}},
+	}
+
+	all, grouped := fixture()
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			q, err := query.Parse(test.input)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			groups := q.GroupTasks(grouped, all)
+			order := []string{}
+			got := map[string][]string{}
+
+			for _, group := range groups {
+				order = append(order, group.Name)
+				got[group.Name] = texts(group.Tasks)
+			}
+
+			if !reflect.DeepEqual(order, test.order) || !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GroupTasks = %v %q, want %v %q", order, got, test.order, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// queryTasks prints the tasks matching a query, see the query package for the syntax
+func queryTasks(args []string) {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the groups of tasks as JSON")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: go2todo query [-json] 'list:Work tag:backend !done due<7d sort:priority'")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	q, err := query.Parse(strings.Join(flags.Args(), " "))
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	store := chooseStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// queries publish no events, but every command reports a broken hook configuration the same way
+	registerHooks(ctx, store, loadConfig(), log.Default())
+
+	matching, err := store.QueryTasks(ctx, q)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	lists, err := store.GetLists(ctx)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	groups := q.GroupTasks(matching, lists)
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		err := encoder.Encode(groups)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		return
+	}
+
+	indent := ""
+
+	if q.Group != "" {
+		indent = "  "
+	}
+
+	for i, group := range groups {
+		if q.Group != "" {
+			if i > 0 {
+				fmt.Println()
+			}
+
+			fmt.Println(group.Name)
+		}
+
+		for _, task := range group.Tasks {
+			fmt.Println(indent + formatTask(task))
+		}
+	}
+}
+
+// formatTask formats a task as a single line for the terminal
+func formatTask(task *tasks.Task) string {
+	s := &strings.Builder{}
+
+	if task.Completed {
+		s.WriteString("[x] ")
+	} else {
+		s.WriteString("[ ] ")
+	}
+
+	if task.Priority > 0 {
+		fmt.Fprintf(s, "(%d) ", task.Priority)
+	}
+
+	s.WriteString(task.Text)
+
+	if task.Due != nil {
+		s.WriteString("  due " + task.Due.Format("2006-01-02"))
+	}
+
+	for _, tag := range task.Tags {
+		s.WriteString(" #" + tag)
+	}
+
+	return s.String()
+}
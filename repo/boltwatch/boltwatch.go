@@ -0,0 +1,67 @@
+// Package boltwatch notices the commits made to a bolt database
+package boltwatch
+
+import (
+	"context"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Interval is the time between two checks of the database
+var Interval = time.Second
+
+// Watch polls the ID of the last committed transaction, which grows with every commit.
+// Bolt locks the file against other processes while it is open, so the commits come
+// from this process, for example from an API server sharing the database
+func Watch(ctx context.Context, db *bolt.DB) (<-chan struct{}, error) {
+	last, err := lastTx(db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+
+		ticker := time.NewTicker(Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			id, err := lastTx(db)
+
+			if err != nil || id == last {
+				continue
+			}
+
+			last = id
+
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// lastTx returns the ID of the last committed transaction, read transactions see its ID
+func lastTx(db *bolt.DB) (int, error) {
+	var id int
+
+	err := db.View(func(tx *bolt.Tx) error {
+		id = tx.ID()
+		return nil
+	})
+
+	return id, err
+}
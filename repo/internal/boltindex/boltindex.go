@@ -0,0 +1,74 @@
+// Package boltindex orders the entries of bolt buckets by creation time. Bolt sorts
+// keys bytewise, so the keys start with the time in big-endian form. The sequence of
+// an index bucket counts its entries, so the total of a page is known without a scan
+package boltindex
+
+import (
+	"encoding/binary"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Key returns the key of an item created at the given time. The ID makes keys of items
+// created at the same time unique and orders them like ORDER BY created_at, id
+func Key(created time.Time, id string) []byte {
+	key := make([]byte, 12, 12+len(id))
+
+	// flipping the sign bit sorts times before 1970, including the zero time, first
+	binary.BigEndian.PutUint64(key, uint64(created.Unix())^1<<63)
+	binary.BigEndian.PutUint32(key[8:], uint32(created.Nanosecond()))
+
+	return append(key, id...)
+}
+
+// Put adds the entry to the index bucket or replaces the value of an existing one
+func Put(bucket *bolt.Bucket, key, value []byte) error {
+	if bucket.Get(key) == nil {
+		err := bucket.SetSequence(bucket.Sequence() + 1)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return bucket.Put(key, value)
+}
+
+// Delete removes the entry from the index bucket, missing entries are ignored
+func Delete(bucket *bolt.Bucket, key []byte) error {
+	if bucket.Get(key) == nil {
+		return nil
+	}
+
+	err := bucket.SetSequence(bucket.Sequence() - 1)
+
+	if err != nil {
+		return err
+	}
+
+	return bucket.Delete(key)
+}
+
+// Page returns the values of limit entries of the bucket starting at offset and the
+// number of all entries. The values before the page are skipped without being read
+func Page(bucket *bolt.Bucket, offset, limit int) ([][]byte, int) {
+	values := [][]byte{}
+
+	if bucket == nil {
+		return values, 0
+	}
+
+	cursor := bucket.Cursor()
+	key, value := cursor.First()
+
+	for i := 0; key != nil && i < offset; i++ {
+		key, value = cursor.Next()
+	}
+
+	for ; key != nil && len(values) < limit; key, value = cursor.Next() {
+		values = append(values, value)
+	}
+
+	return values, int(bucket.Sequence())
+}
@@ -0,0 +1,100 @@
+// Package sqltest opens the databases the sql repos are tested against. SQLite is always
+// tested, PostgreSQL only if GO2TODO_TEST_POSTGRES contains the URL of a database
+package sqltest
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/migrate"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// DB is an empty database of the dialect
+type DB struct {
+	*sql.DB
+	Dialect migrate.Dialect
+}
+
+// Open returns an empty SQLite database and, if configured, an empty PostgreSQL schema.
+// Both are removed once the test finished
+func Open(t *testing.T) []DB {
+	t.Helper()
+
+	sqlite, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "go2todo.sqlite"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { sqlite.Close() })
+
+	dbs := []DB{{DB: sqlite, Dialect: migrate.SQLite}}
+	url := os.Getenv("GO2TODO_TEST_POSTGRES")
+
+	if url == "" {
+		t.Log("GO2TODO_TEST_POSTGRES is not set, skipping PostgreSQL")
+		return dbs
+	}
+
+	return append(dbs, DB{DB: openPostgres(t, url), Dialect: migrate.Postgres})
+}
+
+// openPostgres creates a schema of its own for the test, so tests don't see each others tables
+func openPostgres(t *testing.T, url string) *sql.DB {
+	t.Helper()
+
+	admin, err := sql.Open("postgres", url)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { admin.Close() })
+
+	err = admin.Ping()
+
+	if err != nil {
+		t.Fatalf("GO2TODO_TEST_POSTGRES is set, but the database is not reachable: %v", err)
+	}
+
+	schema := fmt.Sprintf("go2todo_test_%d", time.Now().UnixNano())
+
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	db, err := sql.Open("postgres", withSearchPath(url, schema))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// withSearchPath adds the schema to the connection string, lib/pq passes unknown
+// parameters on to the server as run-time parameters
+func withSearchPath(url, schema string) string {
+	if !strings.HasPrefix(url, "postgres://") && !strings.HasPrefix(url, "postgresql://") {
+		return url + " search_path=" + schema
+	}
+
+	if strings.Contains(url, "?") {
+		return url + "&search_path=" + schema
+	}
+
+	return url + "?search_path=" + schema
+}
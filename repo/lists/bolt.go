@@ -0,0 +1,220 @@
+package lists
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/julez-dev/go2todo/repo/internal/boltindex"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	listsBucket = []byte("lists")
+	// createdBucket holds the IDs of the lists in creation order, see boltindex
+	createdBucket = []byte("lists_by_created")
+)
+
+type InBolt struct {
+	db *bolt.DB
+}
+
+func NewInBolt(db *bolt.DB) (*InBolt, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(listsBucket)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(createdBucket)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &InBolt{
+		db: db,
+	}, nil
+}
+
+func (b *InBolt) CreateList(_ context.Context, list *List) (*List, error) {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return putList(tx, list)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (b *InBolt) UpdateList(_ context.Context, list *List) (*List, error) {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		existing, err := getList(tx, list.ID)
+
+		if err != nil {
+			return err
+		}
+
+		err = boltindex.Delete(tx.Bucket(createdBucket), boltindex.Key(existing.CreatedAt, existing.ID))
+
+		if err != nil {
+			return err
+		}
+
+		return putList(tx, list)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (b *InBolt) GetList(_ context.Context, id string) (*List, error) {
+	var list *List
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		list, err = getList(tx, id)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (b *InBolt) GetLists(_ context.Context) ([]*List, error) {
+	lists := []*List{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(listsBucket).ForEach(func(_, value []byte) error {
+			list := &List{}
+			err := json.Unmarshal(value, list)
+
+			if err != nil {
+				return err
+			}
+
+			lists = append(lists, list)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return lists, nil
+}
+
+// GetListsPage reads a page of the lists, ordered by creation time
+func (b *InBolt) GetListsPage(_ context.Context, offset, limit int) ([]*List, int, error) {
+	page := []*List{}
+	total := 0
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var ids [][]byte
+		ids, total = boltindex.Page(tx.Bucket(createdBucket), offset, limit)
+
+		for _, id := range ids {
+			list, err := getList(tx, string(id))
+
+			if err != nil {
+				return err
+			}
+
+			page = append(page, list)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return page, total, nil
+}
+
+func (b *InBolt) DeleteList(_ context.Context, id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		list, err := getList(tx, id)
+
+		if err == ErrNotFound {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = boltindex.Delete(tx.Bucket(createdBucket), boltindex.Key(list.CreatedAt, list.ID))
+
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(listsBucket).Delete([]byte(id))
+	})
+}
+
+func (b *InBolt) DeleteLists(_ context.Context) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{listsBucket, createdBucket} {
+			err := tx.DeleteBucket(name)
+
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.CreateBucket(name)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func getList(tx *bolt.Tx, id string) (*List, error) {
+	value := tx.Bucket(listsBucket).Get([]byte(id))
+
+	if value == nil {
+		return nil, ErrNotFound
+	}
+
+	list := &List{}
+	err := json.Unmarshal(value, list)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func putList(tx *bolt.Tx, list *List) error {
+	value, err := json.Marshal(list)
+
+	if err != nil {
+		return err
+	}
+
+	err = tx.Bucket(listsBucket).Put([]byte(list.ID), value)
+
+	if err != nil {
+		return err
+	}
+
+	return boltindex.Put(tx.Bucket(createdBucket), boltindex.Key(list.CreatedAt, list.ID), []byte(list.ID))
+}
@@ -0,0 +1,88 @@
+package lists_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestInBoltPages(t *testing.T) {
+	ctx := context.Background()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "go2todo.db"), 0600, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo, err := lists.NewInBolt(db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, id := range []string{"z", "c", "b", "a"} {
+		_, err := repo.CreateList(ctx, &lists.List{ID: id, Name: id, CreatedAt: start.Add(time.Duration(i) * time.Hour)})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(offset, limit int) (string, int) {
+		page, total, err := repo.GetListsPage(ctx, offset, limit)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := ""
+
+		for _, list := range page {
+			ids += list.ID
+		}
+
+		return ids, total
+	}
+
+	if ids, total := names(0, 2); ids != "zc" || total != 4 {
+		t.Errorf("first page = %q of %d, want zc of 4", ids, total)
+	}
+
+	if ids, total := names(2, 5); ids != "ba" || total != 4 {
+		t.Errorf("second page = %q of %d, want ba of 4", ids, total)
+	}
+
+	// renaming keeps the position, deleting removes it from the pages
+	_, err = repo.UpdateList(ctx, &lists.List{ID: "c", Name: "renamed", CreatedAt: start})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = repo.DeleteList(ctx, "z")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids, total := names(0, 10); ids != "cba" || total != 3 {
+		t.Errorf("page = %q of %d, want cba of 3", ids, total)
+	}
+
+	err = repo.DeleteLists(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ids, total := names(0, 10); ids != "" || total != 0 {
+		t.Errorf("page after DeleteLists = %q of %d, want none", ids, total)
+	}
+}
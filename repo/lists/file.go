@@ -58,18 +58,9 @@ func NewInFile(path string) (*InFile, error) {
 }
 
 func (inFile *InFile) CreateList(ctx context.Context, list *List) (*List, error) {
-	f, err := fileutil.OpenAndTruncate(inFile.fileName)
-
-	if err != nil {
-		return nil, err
-	}
-
-	defer f.Close()
-
 	_, _ = inFile.inMem.CreateList(ctx, list)
-	lists, _ := inFile.inMem.GetLists(ctx)
 
-	err = json.NewEncoder(f).Encode(lists)
+	err := inFile.save(ctx)
 
 	if err != nil {
 		return nil, err
@@ -87,18 +78,21 @@ func (inFile *InFile) GetLists(ctx context.Context) ([]*List, error) {
 }
 
 func (inFile *InFile) DeleteList(ctx context.Context, id string) error {
-	f, err := fileutil.OpenAndTruncate(inFile.fileName)
+	_ = inFile.inMem.DeleteList(ctx, id)
+
+	err := inFile.save(ctx)
 
 	if err != nil {
 		return err
 	}
 
-	defer f.Close()
+	return nil
+}
 
-	_ = inFile.inMem.DeleteList(ctx, id)
-	lists, _ := inFile.inMem.GetLists(ctx)
+func (inFile *InFile) DeleteLists(ctx context.Context) error {
+	_ = inFile.inMem.DeleteLists(ctx)
 
-	err = json.NewEncoder(f).Encode(lists)
+	err := inFile.save(ctx)
 
 	if err != nil {
 		return err
@@ -107,44 +101,79 @@ func (inFile *InFile) DeleteList(ctx context.Context, id string) error {
 	return nil
 }
 
-func (inFile *InFile) DeleteLists(ctx context.Context) error {
-	f, err := fileutil.OpenAndTruncate(inFile.fileName)
+func (inFile *InFile) UpdateList(ctx context.Context, list *List) (*List, error) {
+	_, _ = inFile.inMem.UpdateList(ctx, list)
+
+	err := inFile.save(ctx)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	defer f.Close()
+	return list, nil
+}
 
-	_ = inFile.inMem.DeleteLists(ctx)
+// save replaces the file with the current state of the memory
+func (inFile *InFile) save(ctx context.Context) error {
 	lists, _ := inFile.inMem.GetLists(ctx)
 
-	err = json.NewEncoder(f).Encode(lists)
+	data, err := json.Marshal(lists)
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return fileutil.WriteFileAtomic(inFile.fileName, append(data, '\n'))
 }
 
-func (inFile *InFile) UpdateList(ctx context.Context, list *List) (*List, error) {
-	f, err := fileutil.OpenAndTruncate(inFile.fileName)
+// Watch reloads the file whenever it changes on disk, for example because
+// another go2todo process wrote to it
+func (inFile *InFile) Watch(ctx context.Context) (<-chan struct{}, error) {
+	fileChanges, err := fileutil.Watch(ctx, inFile.fileName)
 
 	if err != nil {
 		return nil, err
 	}
 
-	defer f.Close()
+	changes := make(chan struct{}, 1)
 
-	_, _ = inFile.inMem.UpdateList(ctx, list)
-	lists, _ := inFile.inMem.GetLists(ctx)
+	go func() {
+		defer close(changes)
+
+		for range fileChanges {
+			// a file which can't be decoded is usually still being written
+			if inFile.reload() != nil {
+				continue
+			}
+
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changes, nil
+}
 
-	err = json.NewEncoder(f).Encode(lists)
+func (inFile *InFile) reload() error {
+	file, err := os.Open(inFile.fileName)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return list, nil
+	defer file.Close()
+
+	lists := []*List{}
+
+	err = json.NewDecoder(file).Decode(&lists)
+
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	inFile.inMem.replace(lists)
+
+	return nil
 }
@@ -105,3 +105,15 @@ func (mem *InMemory) DeleteLists(context.Context) error {
 
 	return nil
 }
+
+// replace swaps all stored lists at once
+func (mem *InMemory) replace(lists []*List) {
+	mem.l.Lock()
+	defer mem.l.Unlock()
+
+	mem.lists = make(map[string]*List)
+
+	for _, item := range lists {
+		mem.lists[item.ID] = item
+	}
+}
@@ -3,33 +3,63 @@ package lists
 import (
 	"context"
 	"database/sql"
+	"fmt"
+
+	"github.com/julez-dev/go2todo/repo"
+	"github.com/julez-dev/go2todo/repo/migrate"
+	"github.com/julez-dev/go2todo/repo/sqlwatch"
 )
 
+var migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Up: func(d migrate.Dialect) string {
+			return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS lists (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL,
+			created_at %s
+		)`, d.Timestamp)
+		},
+	},
+	{
+		Version: 2,
+		Up: func(d migrate.Dialect) string {
+			return "ALTER TABLE lists ADD COLUMN query TEXT NOT NULL DEFAULT ''"
+		},
+	},
+}
+
 type InSQL struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect migrate.Dialect
 }
 
+// NewInSQL creates the repo on top of a SQLite database
 func NewInSQL(db *sql.DB) (*InSQL, error) {
-	const query = `CREATE TABLE IF NOT EXISTS lists (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		created_at DATETIME
-	)`
+	return newInSQL(db, migrate.SQLite)
+}
 
-	_, err := db.Exec(query)
+// NewInPostgres creates the repo on top of a PostgreSQL database
+func NewInPostgres(db *sql.DB) (*InSQL, error) {
+	return newInSQL(db, migrate.Postgres)
+}
+
+func newInSQL(db *sql.DB, dialect migrate.Dialect) (*InSQL, error) {
+	err := migrate.Run(context.Background(), db, dialect, "lists", migrations)
 
 	if err != nil {
 		return nil, err
 	}
 
 	return &InSQL{
-		db: db,
+		db:      db,
+		dialect: dialect,
 	}, nil
 }
 
 func (sql *InSQL) CreateList(ctx context.Context, list *List) (*List, error) {
-	const query = "INSERT INTO lists (id, name, created_at) VALUES (?, ?, ?)"
-	_, err := sql.db.ExecContext(ctx, query, list.ID, list.Name, list.CreatedAt)
+	const query = "INSERT INTO lists (id, name, query, created_at) VALUES (?, ?, ?, ?)"
+	_, err := sql.db.ExecContext(ctx, sql.dialect.Rebind(query), list.ID, list.Name, list.Query, list.CreatedAt)
 
 	if err != nil {
 		return nil, err
@@ -39,8 +69,8 @@ func (sql *InSQL) CreateList(ctx context.Context, list *List) (*List, error) {
 }
 
 func (sql *InSQL) UpdateList(ctx context.Context, list *List) (*List, error) {
-	const query = "UPDATE lists SET name = ? WHERE id = ?"
-	_, err := sql.db.ExecContext(ctx, query, list.Name, list.ID)
+	const query = "UPDATE lists SET name = ?, query = ?, created_at = ? WHERE id = ?"
+	_, err := sql.db.ExecContext(ctx, sql.dialect.Rebind(query), list.Name, list.Query, list.CreatedAt, list.ID)
 
 	if err != nil {
 		return nil, err
@@ -50,23 +80,48 @@ func (sql *InSQL) UpdateList(ctx context.Context, list *List) (*List, error) {
 }
 
 func (sql *InSQL) GetList(ctx context.Context, id string) (*List, error) {
-	const query = "SELECT id, name, created_at FROM lists WHERE id = ?"
+	const query = "SELECT id, name, query, created_at FROM lists WHERE id = ?"
 	list := &List{}
 
-	err := sql.db.QueryRowContext(ctx, query, id).Scan(&list.ID, &list.Name, &list.CreatedAt)
+	err := sql.db.QueryRowContext(ctx, sql.dialect.Rebind(query), id).Scan(&list.ID, &list.Name, &list.Query, &list.CreatedAt)
 
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
 	return list, nil
 }
 
 func (sql *InSQL) GetLists(ctx context.Context) ([]*List, error) {
-	const query = "SELECT id, name, created_at FROM lists"
+	const query = "SELECT id, name, query, created_at FROM lists"
+	return sql.query(ctx, query)
+}
+
+// GetListsPage reads a page of the lists, ordered by creation time
+func (sql *InSQL) GetListsPage(ctx context.Context, offset, limit int) ([]*List, int, error) {
+	const count = "SELECT COUNT(*) FROM lists"
+	total := 0
+
+	err := sql.db.QueryRowContext(ctx, sql.dialect.Rebind(count)).Scan(&total)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	const query = "SELECT id, name, query, created_at FROM lists ORDER BY created_at, id LIMIT ? OFFSET ?"
+	page, err := sql.query(ctx, query, limit, offset)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return page, total, nil
+}
+
+func (sql *InSQL) query(ctx context.Context, query string, args ...interface{}) ([]*List, error) {
 	lists := []*List{}
 
-	rows, err := sql.db.QueryContext(ctx, query)
+	rows, err := sql.db.QueryContext(ctx, sql.dialect.Rebind(query), args...)
 
 	if err != nil {
 		return nil, err
@@ -76,7 +131,7 @@ func (sql *InSQL) GetLists(ctx context.Context) ([]*List, error) {
 
 	for rows.Next() {
 		list := &List{}
-		err := rows.Scan(&list.ID, &list.Name, &list.CreatedAt)
+		err := rows.Scan(&list.ID, &list.Name, &list.Query, &list.CreatedAt)
 
 		if err != nil {
 			return nil, err
@@ -96,7 +151,7 @@ func (sql *InSQL) GetLists(ctx context.Context) ([]*List, error) {
 
 func (sql *InSQL) DeleteList(ctx context.Context, id string) error {
 	const query = "DELETE FROM lists WHERE id = ?"
-	_, err := sql.db.ExecContext(ctx, query, id)
+	_, err := sql.db.ExecContext(ctx, sql.dialect.Rebind(query), id)
 
 	if err != nil {
 		return err
@@ -107,7 +162,7 @@ func (sql *InSQL) DeleteList(ctx context.Context, id string) error {
 
 func (sql *InSQL) DeleteLists(ctx context.Context) error {
 	const query = "DELETE FROM lists"
-	_, err := sql.db.ExecContext(ctx, query)
+	_, err := sql.db.ExecContext(ctx, sql.dialect.Rebind(query))
 
 	if err != nil {
 		return err
@@ -116,3 +171,21 @@ func (sql *InSQL) DeleteLists(ctx context.Context) error {
 	return nil
 
 }
+
+// Watch notices the changes made by other processes, only SQLite databases are supported
+func (sql *InSQL) Watch(ctx context.Context) (<-chan struct{}, error) {
+	if sql.dialect.Name != migrate.SQLite.Name {
+		return nil, repo.ErrWatchNotSupported
+	}
+
+	return sqlwatch.Watch(ctx, sql.db)
+}
+
+// notFound maps the missing row error of database/sql to ErrNotFound
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,110 @@
+package lists_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/internal/sqltest"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/migrate"
+)
+
+func TestInSQL(t *testing.T) {
+	for _, db := range sqltest.Open(t) {
+		db := db
+
+		t.Run(db.Dialect.Name, func(t *testing.T) {
+			ctx := context.Background()
+			newRepo := lists.NewInSQL
+
+			if db.Dialect.Name == migrate.Postgres.Name {
+				newRepo = lists.NewInPostgres
+			}
+
+			repo, err := newRepo(db.DB)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the migrations are only applied once
+			_, err = newRepo(db.DB)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			created := time.Date(2021, time.March, 4, 10, 0, 0, 0, time.UTC)
+
+			for i, name := range []string{"Work", "Home", "Today"} {
+				list := &lists.List{ID: name, Name: name, CreatedAt: created.Add(time.Duration(i) * time.Minute)}
+
+				if name == "Today" {
+					list.Query = "due:today"
+				}
+
+				_, err = repo.CreateList(ctx, list)
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := repo.GetList(ctx, "Today")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got.Name != "Today" || got.Query != "due:today" || !got.CreatedAt.Equal(created.Add(2*time.Minute)) {
+				t.Errorf("GetList = %+v", got)
+			}
+
+			_, err = repo.UpdateList(ctx, &lists.List{ID: "Home", Name: "Household", CreatedAt: created.Add(time.Minute)})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			page, total, err := repo.GetListsPage(ctx, 1, 1)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if total != 3 || len(page) != 1 || page[0].Name != "Household" {
+				t.Errorf("GetListsPage(1, 1) = %v, %d", page, total)
+			}
+
+			err = repo.DeleteList(ctx, "Work")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = repo.GetList(ctx, "Work")
+
+			if !errors.Is(err, lists.ErrNotFound) {
+				t.Errorf("GetList of a deleted list = %v, want ErrNotFound", err)
+			}
+
+			err = repo.DeleteLists(ctx)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			all, err := repo.GetLists(ctx)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(all) != 0 {
+				t.Errorf("%d lists remain after DeleteLists", len(all))
+			}
+		})
+	}
+}
@@ -9,11 +9,19 @@ import (
 var ErrNotFound = errors.New("list does not exist")
 
 type List struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Query makes the list a smart list, which shows the tasks of all lists
+	// matching the query instead of tasks of its own
+	Query     string    `json:"query,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// Smart reports whether the list is a smart list
+func (l *List) Smart() bool {
+	return l.Query != ""
+}
+
 type Interface interface {
 	CreateList(context.Context, *List) (*List, error)
 	UpdateList(context.Context, *List) (*List, error)
@@ -22,3 +30,9 @@ type Interface interface {
 	DeleteList(context.Context, string) error
 	DeleteLists(context.Context) error
 }
+
+// Pager is implemented by repos which read a page of the lists without loading
+// all of them. Pages are in creation order, total counts all lists
+type Pager interface {
+	GetListsPage(ctx context.Context, offset, limit int) (page []*List, total int, err error)
+}
@@ -0,0 +1,499 @@
+// Package markdown contains a storage backend for markdown checklists,
+// implementing both tasks.Interface and lists.Interface.
+//
+// Every "## Heading" is a list and every "- [ ]" or "- [x]" item below it is a task.
+// IDs and metadata are kept in hidden HTML comments at the end of the line,
+// all other content of the file is written back untouched
+package markdown
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/julez-dev/go2todo/fileutil"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// ErrLineBreak is returned for tasks and lists whose text spans several lines, each of them is one line of the file
+var ErrLineBreak = errors.New("markdown: task texts and list names can't contain line breaks")
+
+type File struct {
+	fileName string
+	l        *sync.Mutex
+}
+
+func New(path string) (*File, error) {
+	if !fileutil.FileExists(path) {
+		f, err := os.Create(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		f.Close()
+	}
+
+	return &File{
+		fileName: path,
+		l:        &sync.Mutex{},
+	}, nil
+}
+
+func (f *File) load() ([]*line, error) {
+	content, err := ioutil.ReadFile(f.fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(string(content)), nil
+}
+
+// save writes the lines. Lines with derived IDs get their ID comments, otherwise editing
+// a line by hand would change its ID and deleting one of identical lines would pass its ID on
+func (f *File) save(lines []*line) error {
+	b := &strings.Builder{}
+
+	for _, l := range lines {
+		if l.derived {
+			l.raw = ""
+			l.derived = false
+		}
+
+		b.WriteString(l.String() + "\n")
+	}
+
+	return fileutil.WriteFileAtomic(f.fileName, []byte(b.String()))
+}
+
+// update loads the file, passes the lines to fn and saves the returned lines.
+// The lock file keeps other go2todo processes from writing in between
+func (f *File) update(fn func([]*line) ([]*line, error)) error {
+	unlock, err := fileutil.Lock(f.fileName)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	lines, err := f.load()
+
+	if err != nil {
+		return err
+	}
+
+	lines, err = fn(lines)
+
+	if err != nil {
+		return err
+	}
+
+	return f.save(lines)
+}
+
+// Watch notices the changes made to the file, for example by an editor or a git checkout
+func (f *File) Watch(ctx context.Context) (<-chan struct{}, error) {
+	return fileutil.Watch(ctx, f.fileName)
+}
+
+func (f *File) CreateTask(_ context.Context, task *tasks.Task) (*tasks.Task, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	err := f.update(func(lines []*line) ([]*line, error) {
+		return insertTask(lines, &line{kind: taskLine, task: task})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (f *File) UpdateTask(_ context.Context, task *tasks.Task) (*tasks.Task, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	err := f.update(func(lines []*line) ([]*line, error) {
+		return updateTask(lines, task)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (f *File) GetTask(_ context.Context, id string) (*tasks.Task, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	lines, err := f.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	i := findTask(lines, id)
+
+	if i < 0 {
+		return nil, tasks.ErrNotFound
+	}
+
+	return lines[i].task, nil
+}
+
+func (f *File) GetTasks(_ context.Context, listID string) ([]*tasks.Task, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	lines, err := f.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	matchingTasks := []*tasks.Task{}
+
+	for _, l := range lines {
+		if l.kind == taskLine && l.task.ListID == listID {
+			matchingTasks = append(matchingTasks, l.task)
+		}
+	}
+
+	return matchingTasks, nil
+}
+
+func (f *File) GetAllTasks(_ context.Context) ([]*tasks.Task, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	lines, err := f.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	allTasks := []*tasks.Task{}
+
+	for _, l := range lines {
+		if l.kind == taskLine {
+			allTasks = append(allTasks, l.task)
+		}
+	}
+
+	return allTasks, nil
+}
+
+func (f *File) DeleteTask(_ context.Context, id string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.update(func(lines []*line) ([]*line, error) {
+		if i := findTask(lines, id); i >= 0 {
+			lines = append(lines[:i], lines[i+1:]...)
+		}
+
+		return lines, nil
+	})
+}
+
+func (f *File) DeleteTasks(_ context.Context, listID string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.update(func(lines []*line) ([]*line, error) {
+		kept := []*line{}
+
+		for _, l := range lines {
+			if l.kind != taskLine || l.task.ListID != listID {
+				kept = append(kept, l)
+			}
+		}
+
+		return kept, nil
+	})
+}
+
+// ApplyTasks updates and deletes the tasks, the file is written once
+func (f *File) ApplyTasks(_ context.Context, updated []*tasks.Task, deleted []string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.update(func(lines []*line) ([]*line, error) {
+		var err error
+
+		for _, task := range updated {
+			lines, err = updateTask(lines, task)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, id := range deleted {
+			if i := findTask(lines, id); i >= 0 {
+				lines = append(lines[:i], lines[i+1:]...)
+			}
+		}
+
+		return lines, nil
+	})
+}
+
+func (f *File) CreateList(_ context.Context, list *lists.List) (*lists.List, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if multiline(list.Name) {
+		return nil, ErrLineBreak
+	}
+
+	err := f.update(func(lines []*line) ([]*line, error) {
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1].String()) != "" {
+			lines = append(lines, &line{kind: proseLine})
+		}
+
+		return append(lines, &line{kind: headingLine, list: list}), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (f *File) UpdateList(_ context.Context, list *lists.List) (*lists.List, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if multiline(list.Name) {
+		return nil, ErrLineBreak
+	}
+
+	err := f.update(func(lines []*line) ([]*line, error) {
+		i := findHeading(lines, list.ID)
+
+		if i < 0 {
+			return nil, lists.ErrNotFound
+		}
+
+		lines[i].list = list
+		lines[i].raw = ""
+
+		return lines, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (f *File) GetList(_ context.Context, id string) (*lists.List, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	lines, err := f.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	i := findHeading(lines, id)
+
+	if i < 0 {
+		return nil, lists.ErrNotFound
+	}
+
+	return lines[i].list, nil
+}
+
+func (f *File) GetLists(_ context.Context) ([]*lists.List, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	lines, err := f.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	allLists := []*lists.List{}
+
+	for _, l := range lines {
+		if l.kind == headingLine {
+			allLists = append(allLists, l.list)
+		}
+	}
+
+	return allLists, nil
+}
+
+// DeleteList removes the heading and the tasks of the list, the prose below the heading is kept
+func (f *File) DeleteList(_ context.Context, id string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.update(func(lines []*line) ([]*line, error) {
+		i := findHeading(lines, id)
+
+		if i < 0 {
+			return lines, nil
+		}
+
+		return deleteSection(lines, i), nil
+	})
+}
+
+func (f *File) DeleteLists(_ context.Context) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.update(func(lines []*line) ([]*line, error) {
+		for i := findNextHeading(lines, 0); i >= 0; i = findNextHeading(lines, i) {
+			lines = deleteSection(lines, i)
+		}
+
+		return lines, nil
+	})
+}
+
+// deleteSection removes the heading at i and the task lines of its section
+func deleteSection(lines []*line, i int) []*line {
+	end := sectionEnd(lines, i)
+	kept := append([]*line{}, lines[:i]...)
+
+	for _, l := range lines[i+1 : end] {
+		if l.kind != taskLine {
+			kept = append(kept, l)
+		}
+	}
+
+	return append(kept, lines[end:]...)
+}
+
+// insertTask adds the task line after the last task of its list,
+// or after the last non-empty line if the list has no tasks yet
+func insertTask(lines []*line, task *line) ([]*line, error) {
+	if multiline(task.task.Text) {
+		return nil, ErrLineBreak
+	}
+
+	i := findHeading(lines, task.task.ListID)
+
+	if i < 0 {
+		return nil, lists.ErrNotFound
+	}
+
+	pos, lastTask := i+1, -1
+
+	for j := i + 1; j < sectionEnd(lines, i); j++ {
+		if strings.TrimSpace(lines[j].String()) != "" {
+			pos = j + 1
+		}
+
+		if lines[j].kind == taskLine {
+			lastTask = j
+		}
+	}
+
+	if lastTask >= 0 {
+		pos = lastTask + 1
+	}
+
+	insert := []*line{task}
+
+	// keep the checklist separated from headings and prose
+	if lines[pos-1].kind != taskLine {
+		insert = append([]*line{{kind: proseLine}}, insert...)
+	}
+
+	if pos < len(lines) && strings.TrimSpace(lines[pos].String()) != "" {
+		insert = append(insert, &line{kind: proseLine})
+	}
+
+	rest := append(insert, lines[pos:]...)
+
+	return append(lines[:pos], rest...), nil
+}
+
+// updateTask replaces the task of its line, tasks moved to another list move to its section
+func updateTask(lines []*line, task *tasks.Task) ([]*line, error) {
+	i := findTask(lines, task.ID)
+
+	if i < 0 {
+		return nil, tasks.ErrNotFound
+	}
+
+	existing := lines[i]
+
+	if existing.task.ListID != task.ListID {
+		lines = append(lines[:i], lines[i+1:]...)
+		return insertTask(lines, &line{kind: taskLine, task: task, prefix: existing.prefix})
+	}
+
+	if multiline(task.Text) {
+		return nil, ErrLineBreak
+	}
+
+	existing.task = task
+	existing.raw = ""
+
+	return lines, nil
+}
+
+func multiline(text string) bool {
+	return strings.ContainsAny(text, "\r\n")
+}
+
+func findTask(lines []*line, id string) int {
+	for i, l := range lines {
+		if l.kind == taskLine && l.task.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func findHeading(lines []*line, id string) int {
+	for i, l := range lines {
+		if l.kind == headingLine && l.list.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func findNextHeading(lines []*line, from int) int {
+	for i := from; i < len(lines); i++ {
+		if lines[i].kind == headingLine {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// sectionEnd returns the index of the line following the section started by the heading at i
+func sectionEnd(lines []*line, i int) int {
+	for j := i + 1; j < len(lines); j++ {
+		if endsSection(lines[j].String()) {
+			return j
+		}
+	}
+
+	return len(lines)
+}
@@ -0,0 +1,220 @@
+package markdown_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/markdown"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+func newFile(t *testing.T, content string) (*markdown.File, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "todo.md")
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := markdown.New(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file, path
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+func TestIdenticalLines(t *testing.T) {
+	ctx := context.Background()
+	file, path := newFile(t, "## Work <!-- list:work -->\n\n- [ ] TBD\n- [ ] TBD\n- [ ] TBD\n")
+
+	all, err := file.GetTasks(ctx, "work")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != 3 || all[0].ID == all[1].ID || all[1].ID == all[2].ID || all[0].ID == all[2].ID {
+		t.Fatalf("tasks = %v, want three different IDs", all)
+	}
+
+	second := all[1]
+	second.Completed = true
+
+	_, err = file.UpdateTask(ctx, second)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Count(read(t, path), "- [x] TBD"); got != 1 {
+		t.Errorf("%d items checked, want 1:\n%s", got, read(t, path))
+	}
+
+	// deleting one of them doesn't pass its ID on to the others
+	err = file.DeleteTask(ctx, all[0].ID)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := file.GetTasks(ctx, "work")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(remaining) != 2 || remaining[0].ID != all[1].ID || remaining[1].ID != all[2].ID {
+		t.Errorf("remaining = %v, want the IDs %s and %s", remaining, all[1].ID, all[2].ID)
+	}
+}
+
+func TestIdenticalHeadings(t *testing.T) {
+	file, _ := newFile(t, "## Later\n\n- [ ] one\n\n## Later\n\n- [ ] two\n")
+
+	all, err := file.GetLists(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != 2 || all[0].ID == all[1].ID {
+		t.Errorf("lists = %v, want two different IDs", all)
+	}
+}
+
+func TestDeleteListKeepsProse(t *testing.T) {
+	content := `# Plans
+
+## Work <!-- list:work -->
+
+Notes about work, keep them.
+
+- [ ] write report
+- [x] call Bob
+  - [ ] follow up
+
+More notes.
+
+## Home <!-- list:home -->
+
+- [ ] buy milk <!-- id:milk -->
+`
+	want := `# Plans
+
+
+Notes about work, keep them.
+
+
+More notes.
+
+## Home <!-- list:home -->
+
+- [ ] buy milk <!-- id:milk -->
+`
+
+	file, path := newFile(t, content)
+
+	err := file.DeleteList(context.Background(), "work")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := read(t, path); got != want {
+		t.Errorf("content =\n%s\nwant\n%s", got, want)
+	}
+
+	err = file.DeleteLists(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := read(t, path); strings.Contains(got, "Home") || strings.Contains(got, "buy milk") || !strings.Contains(got, "More notes.") {
+		t.Errorf("content after DeleteLists =\n%s", got)
+	}
+}
+
+func TestLineBreaks(t *testing.T) {
+	ctx := context.Background()
+	content := "## Work <!-- list:work -->\n\n- [ ] write report <!-- id:1 -->\n"
+	file, path := newFile(t, content)
+
+	_, err := file.CreateTask(ctx, &tasks.Task{ID: "2", ListID: "work", Text: "call Bob\n- [x] injected"})
+
+	if !errors.Is(err, markdown.ErrLineBreak) {
+		t.Errorf("CreateTask: err = %v, want ErrLineBreak", err)
+	}
+
+	_, err = file.UpdateTask(ctx, &tasks.Task{ID: "1", ListID: "work", Text: "write\r\nreport"})
+
+	if !errors.Is(err, markdown.ErrLineBreak) {
+		t.Errorf("UpdateTask: err = %v, want ErrLineBreak", err)
+	}
+
+	_, err = file.UpdateList(ctx, &lists.List{ID: "work", Name: "Work\n## Home"})
+
+	if !errors.Is(err, markdown.ErrLineBreak) {
+		t.Errorf("UpdateList: err = %v, want ErrLineBreak", err)
+	}
+
+	if got := read(t, path); got != content {
+		t.Errorf("content = %q, want it unchanged", got)
+	}
+}
+
+func TestDerivedIDsWritten(t *testing.T) {
+	ctx := context.Background()
+	file, path := newFile(t, "## Work\n\n- [ ] write report\n- [ ] call Bob <!-- id:bob -->\n")
+
+	all, err := file.GetLists(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	work := all[0].ID
+	before, _ := file.GetTasks(ctx, work)
+
+	// the first write keeps the derived IDs in comments
+	_, err = file.UpdateTask(ctx, &tasks.Task{ID: "bob", ListID: work, Text: "call Bob", Completed: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edited := strings.Replace(read(t, path), "## Work", "## Office", 1)
+	edited = strings.Replace(edited, "write report", "write the report", 1)
+	err = ioutil.WriteFile(path, []byte(edited), 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := file.GetTask(ctx, before[0].ID)
+
+	if err != nil || task.Text != "write the report" || task.ListID != work {
+		t.Errorf("task after editing = %v, %v, want write the report in the renamed list", task, err)
+	}
+}
@@ -0,0 +1,259 @@
+package markdown
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+var (
+	headingPattern = regexp.MustCompile(`^##\s+(.*?)\s*(?:<!--(.*?)-->)?\s*$`)
+	taskPattern    = regexp.MustCompile(`^(\s*[-*+]\s+)\[([ xX])\]\s+(.*?)\s*(?:<!--(.*?)-->)?\s*$`)
+)
+
+type kind int
+
+const (
+	proseLine   kind = 0
+	headingLine kind = 1
+	taskLine    kind = 2
+)
+
+// line is a single line of the document. Prose is kept as is, headings and
+// checklist items keep their raw text until they are modified
+type line struct {
+	kind kind
+	raw  string
+
+	list *lists.List
+
+	task   *tasks.Task
+	prefix string
+
+	// derived is set if the ID was derived from raw, the line has no ID comment
+	derived bool
+}
+
+// parse splits the content into lines. Headings and items without an ID comment get IDs
+// derived from their text, identical lines are told apart by the number of the occurrence
+func parse(content string) []*line {
+	parsed := []*line{}
+	fenced := false
+	listID := ""
+	occurrences := map[string]int{}
+
+	for _, raw := range strings.Split(strings.TrimRight(content, "\n"), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+
+		if strings.HasPrefix(strings.TrimSpace(raw), "```") {
+			fenced = !fenced
+		}
+
+		if fenced {
+			parsed = append(parsed, &line{kind: proseLine, raw: raw})
+			continue
+		}
+
+		if match := headingPattern.FindStringSubmatch(raw); match != nil {
+			fields := commentFields(match[2])
+			list := &lists.List{
+				ID:   fields["list"],
+				Name: match[1],
+			}
+
+			derived := list.ID == ""
+
+			if derived {
+				list.ID = hash(raw, occurrences[raw])
+				occurrences[raw]++
+			}
+
+			if createdAt, err := time.Parse(time.RFC3339, fields["created"]); err == nil {
+				list.CreatedAt = createdAt
+			}
+
+			list.Query, _ = url.PathUnescape(fields["query"])
+			listID = list.ID
+			parsed = append(parsed, &line{kind: headingLine, raw: raw, list: list, derived: derived})
+			continue
+		}
+
+		if endsSection(raw) {
+			listID = ""
+		}
+
+		if match := taskPattern.FindStringSubmatch(raw); match != nil && listID != "" {
+			fields := commentFields(match[4])
+			task := &tasks.Task{
+				ID:        fields["id"],
+				ListID:    listID,
+				Text:      match[3],
+				Completed: match[2] != " ",
+			}
+
+			derived := task.ID == ""
+
+			if derived {
+				task.ID = hash(raw, occurrences[raw])
+				occurrences[raw]++
+			}
+
+			if createdAt, err := time.Parse(time.RFC3339, fields["created"]); err == nil {
+				task.CreatedAt = createdAt
+			}
+
+			if completedAt, err := time.Parse(time.RFC3339, fields["completed"]); err == nil {
+				task.CompletedAt = &completedAt
+			}
+
+			if due, err := time.Parse(time.RFC3339, fields["due"]); err == nil {
+				task.Due = &due
+			}
+
+			task.Priority, _ = strconv.Atoi(fields["priority"])
+			task.Tags = parseTags(fields["tags"])
+			task.ParentID = fields["parent"]
+			task.Notes, _ = url.PathUnescape(fields["notes"])
+
+			parsed = append(parsed, &line{kind: taskLine, raw: raw, task: task, prefix: match[1], derived: derived})
+			continue
+		}
+
+		parsed = append(parsed, &line{kind: proseLine, raw: raw})
+	}
+
+	if len(parsed) == 1 && parsed[0].raw == "" {
+		return []*line{}
+	}
+
+	return parsed
+}
+
+func (l *line) String() string {
+	if l.raw != "" || l.kind == proseLine {
+		return l.raw
+	}
+
+	if l.kind == headingLine {
+		comment := []string{"list:" + l.list.ID}
+
+		if !l.list.CreatedAt.IsZero() {
+			comment = append(comment, "created:"+l.list.CreatedAt.Format(time.RFC3339))
+		}
+
+		if l.list.Query != "" {
+			comment = append(comment, "query:"+url.PathEscape(l.list.Query))
+		}
+
+		return "## " + l.list.Name + " <!-- " + strings.Join(comment, " ") + " -->"
+	}
+
+	mark := " "
+	if l.task.Completed {
+		mark = "x"
+	}
+
+	comment := []string{"id:" + l.task.ID}
+
+	if !l.task.CreatedAt.IsZero() {
+		comment = append(comment, "created:"+l.task.CreatedAt.Format(time.RFC3339))
+	}
+
+	if l.task.Priority > 0 {
+		comment = append(comment, "priority:"+strconv.Itoa(l.task.Priority))
+	}
+
+	if l.task.CompletedAt != nil {
+		comment = append(comment, "completed:"+l.task.CompletedAt.Format(time.RFC3339))
+	}
+
+	if l.task.Due != nil {
+		comment = append(comment, "due:"+l.task.Due.Format(time.RFC3339))
+	}
+
+	if len(l.task.Tags) > 0 {
+		comment = append(comment, "tags:"+formatTags(l.task.Tags))
+	}
+
+	if l.task.ParentID != "" {
+		comment = append(comment, "parent:"+l.task.ParentID)
+	}
+
+	if l.task.Notes != "" {
+		comment = append(comment, "notes:"+url.PathEscape(l.task.Notes))
+	}
+
+	prefix := l.prefix
+	if prefix == "" {
+		prefix = "- "
+	}
+
+	return prefix + "[" + mark + "] " + l.task.Text + " <!-- " + strings.Join(comment, " ") + " -->"
+}
+
+// endsSection reports whether raw is a heading of level one or two,
+// which ends the checklist of the previous list
+func endsSection(raw string) bool {
+	return strings.HasPrefix(raw, "# ") || strings.HasPrefix(raw, "## ")
+}
+
+func commentFields(comment string) map[string]string {
+	fields := map[string]string{}
+
+	for _, field := range strings.Fields(comment) {
+		i := strings.Index(field, ":")
+
+		if i <= 0 {
+			continue
+		}
+
+		fields[field[:i]] = field[i+1:]
+	}
+
+	return fields
+}
+
+// formatTags joins the tags with commas, escaping what would end the comment field
+func formatTags(tags []string) string {
+	escaped := make([]string, len(tags))
+
+	for i, tag := range tags {
+		escaped[i] = url.PathEscape(tag)
+	}
+
+	return strings.Join(escaped, ",")
+}
+
+func parseTags(field string) []string {
+	if field == "" {
+		return nil
+	}
+
+	tags := []string{}
+
+	for _, tag := range strings.Split(field, ",") {
+		if unescaped, err := url.PathUnescape(tag); err == nil && unescaped != "" {
+			tags = append(tags, unescaped)
+		}
+	}
+
+	return tags
+}
+
+// hash derives an ID from the line and the number of identical lines above it
+func hash(raw string, occurrence int) string {
+	if occurrence > 0 {
+		raw = fmt.Sprintf("%s\x00%d", raw, occurrence)
+	}
+
+	sum := sha1.Sum([]byte(raw))
+	return hex.EncodeToString(sum[:8])
+}
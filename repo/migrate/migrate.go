@@ -0,0 +1,132 @@
+// Package migrate contains the schema migrations shared by the sql backends
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+)
+
+// Dialect describes the differences between the supported sql databases
+type Dialect struct {
+	Name      string
+	Boolean   string
+	False     string
+	Timestamp string
+
+	// numbered placeholders ($1, $2, ...) instead of question marks
+	numbered bool
+}
+
+var (
+	SQLite = Dialect{
+		Name:      "sqlite",
+		Boolean:   "INTEGER",
+		False:     "0",
+		Timestamp: "DATETIME",
+	}
+
+	Postgres = Dialect{
+		Name:      "postgres",
+		Boolean:   "BOOLEAN",
+		False:     "FALSE",
+		Timestamp: "TIMESTAMPTZ",
+		numbered:  true,
+	}
+)
+
+// Rebind replaces the question mark placeholders in query with the ones
+// used by the dialect
+func (d Dialect) Rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	b := &strings.Builder{}
+	n := 0
+
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+
+	return b.String()
+}
+
+// Migration is a single schema change, identified by its version
+type Migration struct {
+	Version int
+	Up      func(Dialect) string
+}
+
+// Run applies all migrations of the given set that were not applied yet.
+// The set name allows multiple repos to keep their own versions in the same database
+func Run(ctx context.Context, db *sql.DB, dialect Dialect, set string, migrations []Migration) error {
+	const createQuery = `CREATE TABLE IF NOT EXISTS schema_migrations (
+		name TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		PRIMARY KEY (name, version)
+	)`
+
+	_, err := db.ExecContext(ctx, createQuery)
+
+	if err != nil {
+		return err
+	}
+
+	for _, migration := range migrations {
+		err = apply(ctx, db, dialect, set, migration)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func apply(ctx context.Context, db *sql.DB, dialect Dialect, set string, migration Migration) error {
+	const (
+		selectQuery = "SELECT COUNT(*) FROM schema_migrations WHERE name = ? AND version = ?"
+		insertQuery = "INSERT INTO schema_migrations (name, version) VALUES (?, ?)"
+	)
+
+	tx, err := db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	applied := 0
+	err = tx.QueryRowContext(ctx, dialect.Rebind(selectQuery), set, migration.Version).Scan(&applied)
+
+	if err != nil {
+		return err
+	}
+
+	if applied > 0 {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, migration.Up(dialect))
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, dialect.Rebind(insertQuery), set, migration.Version)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,75 @@
+package migrate_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julez-dev/go2todo/repo/internal/sqltest"
+	"github.com/julez-dev/go2todo/repo/migrate"
+)
+
+func TestRebind(t *testing.T) {
+	const query = "SELECT * FROM tasks WHERE list_id = ? AND completed = ? LIMIT ?"
+
+	if got := migrate.SQLite.Rebind(query); got != query {
+		t.Errorf("SQLite.Rebind = %q, want the query unchanged", got)
+	}
+
+	want := "SELECT * FROM tasks WHERE list_id = $1 AND completed = $2 LIMIT $3"
+
+	if got := migrate.Postgres.Rebind(query); got != want {
+		t.Errorf("Postgres.Rebind = %q, want %q", got, want)
+	}
+}
+
+func TestRun(t *testing.T) {
+	migrations := []migrate.Migration{
+		{Version: 1, Up: func(d migrate.Dialect) string {
+			return "CREATE TABLE things (id TEXT PRIMARY KEY, done " + d.Boolean + " DEFAULT " + d.False + ", at " + d.Timestamp + ")"
+		}},
+		{Version: 2, Up: func(d migrate.Dialect) string {
+			return "ALTER TABLE things ADD COLUMN name TEXT NOT NULL DEFAULT ''"
+		}},
+	}
+
+	for _, db := range sqltest.Open(t) {
+		db := db
+
+		t.Run(db.Dialect.Name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// the second run finds both versions applied, adding the column again would fail
+			for i := 0; i < 2; i++ {
+				err := migrate.Run(ctx, db.DB, db.Dialect, "things", migrations)
+
+				if err != nil {
+					t.Fatalf("run %d: %v", i+1, err)
+				}
+			}
+
+			_, err := db.Exec(db.Dialect.Rebind("INSERT INTO things (id, name) VALUES (?, ?)"), "1", "one")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			applied := 0
+			err = db.QueryRow("SELECT COUNT(*) FROM schema_migrations WHERE name = 'things'").Scan(&applied)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if applied != 2 {
+				t.Errorf("%d versions recorded, want 2", applied)
+			}
+
+			// the versions of other sets are counted on their own
+			err = migrate.Run(ctx, db.DB, db.Dialect, "others", migrations[:1])
+
+			if err == nil {
+				t.Errorf("the first version of another set was not applied, things exists already")
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+// Package remote contains a storage backend talking to the REST API of a
+// go2todo server, implementing both tasks.Interface and lists.Interface
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+const pageSize = 500
+
+// the server applies batches of changes in one request
+var _ tasks.Batcher = (*Client)(nil)
+
+// errEndpointNotFound is returned when the server does not know a collection endpoint,
+// which usually means the base URL is wrong
+var errEndpointNotFound = errors.New("remote: endpoint not found, check the server URL")
+
+type Client struct {
+	baseURL string
+	token   string
+
+	// HTTPClient is used for all requests, its timeout applies to every single attempt
+	HTTPClient *http.Client
+	// Retries is the number of additional attempts for failed idempotent requests
+	Retries int
+	// Backoff is the wait before the first retry, it doubles with every further one
+	Backoff time.Duration
+}
+
+// New creates a client for the server at baseURL, for example http://localhost:8080.
+// The token is sent as bearer token when it is not empty
+func New(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimRight(baseURL, "/") + "/v1",
+		token:   token,
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		Retries: 3,
+		Backoff: 200 * time.Millisecond,
+	}
+}
+
+// Server returns the URL of the server, it implements service.Remote
+func (c *Client) Server() string {
+	return strings.TrimSuffix(c.baseURL, "/v1")
+}
+
+// batch is the body of POST /tasks/batch
+type batch struct {
+	Updated []*tasks.Task `json:"updated"`
+	Deleted []string      `json:"deleted"`
+}
+
+type page struct {
+	Items json.RawMessage `json:"items"`
+	Total int             `json:"total"`
+}
+
+// statusError is returned for all unexpected responses of the server
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("remote: %s (status %d)", e.message, e.status)
+}
+
+// do sends the request and decodes the response into out, if given.
+// notFound is returned when the server responds with 404
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}, notFound error) error {
+	var body []byte
+
+	if in != nil {
+		encoded, err := json.Marshal(in)
+
+		if err != nil {
+			return err
+		}
+
+		body = encoded
+	}
+
+	attempts := 1
+
+	// creating items is not idempotent, a retry could create them twice
+	if method != http.MethodPost {
+		attempts += c.Retries
+	}
+
+	var err error
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(c.Backoff << (attempt - 1)):
+			}
+		}
+
+		var retry bool
+		retry, err = c.attempt(ctx, method, path, body, out, notFound)
+
+		if !retry {
+			return err
+		}
+	}
+
+	return err
+}
+
+// attempt sends the request once and reports whether a failure is worth retrying
+func (c *Client) attempt(ctx context.Context, method, path string, body []byte, out interface{}, notFound error) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, notFound
+
+	case resp.StatusCode >= 300:
+		failure := &struct {
+			Error string `json:"error"`
+		}{}
+
+		_ = json.NewDecoder(resp.Body).Decode(failure)
+
+		if failure.Error == "" {
+			failure.Error = http.StatusText(resp.StatusCode)
+		}
+
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, &statusError{status: resp.StatusCode, message: failure.Error}
+	}
+
+	if out == nil {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return false, nil
+	}
+
+	return false, json.NewDecoder(resp.Body).Decode(out)
+}
+
+// collectRange fetches limit items of a collection starting at offset, all remaining ones
+// if limit is 0, passing the items of each page to add. It returns the total number of
+// items of the collection
+func (c *Client) collectRange(ctx context.Context, path string, offset, limit int, notFound error, add func(json.RawMessage) (int, error)) (int, error) {
+	query := url.Values{}
+	fetched := 0
+
+	for {
+		size := pageSize
+
+		if limit > 0 && limit-fetched < size {
+			size = limit - fetched
+		}
+
+		query.Set("limit", strconv.Itoa(size))
+		query.Set("offset", strconv.Itoa(offset+fetched))
+
+		result := &page{}
+		err := c.do(ctx, http.MethodGet, path+"?"+query.Encode(), nil, result, notFound)
+
+		if err != nil {
+			return 0, err
+		}
+
+		count, err := add(result.Items)
+
+		if err != nil {
+			return 0, err
+		}
+
+		fetched += count
+
+		if count == 0 || offset+fetched >= result.Total || (limit > 0 && fetched >= limit) {
+			return result.Total, nil
+		}
+	}
+}
+
+func (c *Client) collectTasks(ctx context.Context, path string, notFound error) ([]*tasks.Task, error) {
+	all, _, err := c.collectTaskRange(ctx, path, 0, 0, notFound)
+	return all, err
+}
+
+func (c *Client) collectTaskRange(ctx context.Context, path string, offset, limit int, notFound error) ([]*tasks.Task, int, error) {
+	all := []*tasks.Task{}
+
+	total, err := c.collectRange(ctx, path, offset, limit, notFound, func(items json.RawMessage) (int, error) {
+		page := []*tasks.Task{}
+		err := json.Unmarshal(items, &page)
+		all = append(all, page...)
+
+		return len(page), err
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return all, total, nil
+}
+
+func (c *Client) CreateTask(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
+	created := &tasks.Task{}
+	err := c.do(ctx, http.MethodPost, "/lists/"+url.PathEscape(task.ListID)+"/tasks", task, created, lists.ErrNotFound)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (c *Client) UpdateTask(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
+	updated := &tasks.Task{}
+	err := c.do(ctx, http.MethodPut, "/tasks/"+url.PathEscape(task.ID), task, updated, tasks.ErrNotFound)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (c *Client) GetTask(ctx context.Context, id string) (*tasks.Task, error) {
+	task := &tasks.Task{}
+	err := c.do(ctx, http.MethodGet, "/tasks/"+url.PathEscape(id), nil, task, tasks.ErrNotFound)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (c *Client) GetTasks(ctx context.Context, listID string) ([]*tasks.Task, error) {
+	return c.collectTasks(ctx, "/lists/"+url.PathEscape(listID)+"/tasks", lists.ErrNotFound)
+}
+
+func (c *Client) GetTasksPage(ctx context.Context, listID string, offset, limit int) ([]*tasks.Task, int, error) {
+	return c.collectTaskRange(ctx, "/lists/"+url.PathEscape(listID)+"/tasks", offset, limit, lists.ErrNotFound)
+}
+
+func (c *Client) GetAllTasks(ctx context.Context) ([]*tasks.Task, error) {
+	return c.collectTasks(ctx, "/tasks", errEndpointNotFound)
+}
+
+func (c *Client) DeleteTask(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/tasks/"+url.PathEscape(id), nil, nil, tasks.ErrNotFound)
+}
+
+// ApplyTasks sends the updates and deletions in one request, the server makes them
+// in one transaction if its storage supports it
+func (c *Client) ApplyTasks(ctx context.Context, updated []*tasks.Task, deleted []string) error {
+	return c.do(ctx, http.MethodPost, "/tasks/batch", &batch{Updated: updated, Deleted: deleted}, nil, tasks.ErrNotFound)
+}
+
+func (c *Client) DeleteTasks(ctx context.Context, listID string) error {
+	return c.do(ctx, http.MethodDelete, "/lists/"+url.PathEscape(listID)+"/tasks", nil, nil, lists.ErrNotFound)
+}
+
+func (c *Client) CreateList(ctx context.Context, list *lists.List) (*lists.List, error) {
+	created := &lists.List{}
+	err := c.do(ctx, http.MethodPost, "/lists", list, created, errEndpointNotFound)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+func (c *Client) UpdateList(ctx context.Context, list *lists.List) (*lists.List, error) {
+	updated := &lists.List{}
+	err := c.do(ctx, http.MethodPut, "/lists/"+url.PathEscape(list.ID), list, updated, lists.ErrNotFound)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (c *Client) GetList(ctx context.Context, id string) (*lists.List, error) {
+	list := &lists.List{}
+	err := c.do(ctx, http.MethodGet, "/lists/"+url.PathEscape(id), nil, list, lists.ErrNotFound)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (c *Client) GetLists(ctx context.Context) ([]*lists.List, error) {
+	all, _, err := c.GetListsPage(ctx, 0, 0)
+	return all, err
+}
+
+func (c *Client) GetListsPage(ctx context.Context, offset, limit int) ([]*lists.List, int, error) {
+	all := []*lists.List{}
+
+	total, err := c.collectRange(ctx, "/lists", offset, limit, errEndpointNotFound, func(items json.RawMessage) (int, error) {
+		page := []*lists.List{}
+		err := json.Unmarshal(items, &page)
+		all = append(all, page...)
+
+		return len(page), err
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return all, total, nil
+}
+
+func (c *Client) DeleteList(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/lists/"+url.PathEscape(id), nil, nil, lists.ErrNotFound)
+}
+
+func (c *Client) DeleteLists(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/lists", nil, nil, errEndpointNotFound)
+}
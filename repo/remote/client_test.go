@@ -0,0 +1,267 @@
+package remote_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/api"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/remote"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+// events counts the events published by a storage
+type events struct {
+	counts map[service.EventType]int
+	l      *sync.Mutex
+}
+
+func count(storage *service.Storage) *events {
+	e := &events{counts: map[service.EventType]int{}, l: &sync.Mutex{}}
+
+	storage.Events.After(func(event *service.Event) {
+		e.l.Lock()
+		defer e.l.Unlock()
+
+		e.counts[event.Type]++
+	})
+
+	return e
+}
+
+func (e *events) get(t service.EventType) int {
+	e.l.Lock()
+	defer e.l.Unlock()
+
+	return e.counts[t]
+}
+
+func (e *events) total() int {
+	e.l.Lock()
+	defer e.l.Unlock()
+
+	total := 0
+
+	for _, n := range e.counts {
+		total += n
+	}
+
+	return total
+}
+
+// newRemote starts an API server on an in-memory storage and returns a storage using it remotely
+func newRemote(t *testing.T) (local, server *service.Storage) {
+	t.Helper()
+
+	server = service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+	httpServer := httptest.NewServer(api.RequireToken("secret", api.NewServer(server)))
+	t.Cleanup(httpServer.Close)
+
+	client := remote.New(httpServer.URL, "secret")
+
+	return service.NewStorage(client, client), server
+}
+
+func TestPassThrough(t *testing.T) {
+	ctx := context.Background()
+	local, server := newRemote(t)
+	localEvents, serverEvents := count(local), count(server)
+
+	list, err := local.StoreList(ctx, &lists.List{Name: "Work"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, err := local.StoreTask(ctx, &tasks.Task{ListID: list.ID, Text: "write report"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the IDs are the ones of the server
+	stored, err := server.GetTask(ctx, task.ID)
+
+	if err != nil || stored.Text != "write report" || stored.ListID != list.ID {
+		t.Fatalf("server task %s = %v, %v, want write report in %s", task.ID, stored, err, list.ID)
+	}
+
+	task.Completed = true
+	_, err = local.UpdateTask(ctx, task)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = local.DeleteList(ctx, list.ID)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining, _ := server.GetAllTasks(ctx); len(remaining) != 0 {
+		t.Errorf("the server kept the tasks %v", remaining)
+	}
+
+	server.Events.Wait()
+	local.Events.Wait()
+
+	if n := localEvents.total(); n != 0 {
+		t.Errorf("%d events were published locally, want none", n)
+	}
+
+	// the tasks of the list are deleted by the server, not by a separate request
+	want := map[service.EventType]int{
+		service.ListCreated:   1,
+		service.TaskCreated:   1,
+		service.TaskCompleted: 1,
+		service.ListDeleted:   1,
+		service.TasksDeleted:  0,
+	}
+
+	for eventType, n := range want {
+		if got := serverEvents.get(eventType); got != n {
+			t.Errorf("the server published %s %d times, want %d", eventType, got, n)
+		}
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	ctx := context.Background()
+	local, _ := newRemote(t)
+
+	_, err := local.GetTask(ctx, "missing")
+
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("GetTask: err = %v, want tasks.ErrNotFound", err)
+	}
+
+	_, err = local.GetList(ctx, "missing")
+
+	if !errors.Is(err, lists.ErrNotFound) {
+		t.Errorf("GetList: err = %v, want lists.ErrNotFound", err)
+	}
+
+	_, err = local.StoreTask(ctx, &tasks.Task{ListID: "missing", Text: "lost"})
+
+	if !errors.Is(err, lists.ErrNotFound) {
+		t.Errorf("StoreTask: err = %v, want lists.ErrNotFound", err)
+	}
+}
+
+func TestPages(t *testing.T) {
+	ctx := context.Background()
+	local, _ := newRemote(t)
+
+	for _, name := range []string{"One", "Two", "Three"} {
+		_, err := local.StoreList(ctx, &lists.List{Name: name})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the lists are ordered by creation time
+		time.Sleep(time.Millisecond)
+	}
+
+	page, total, err := local.GetListsPage(ctx, 1, 1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if total != 3 || len(page) != 1 || page[0].Name != "Two" {
+		t.Errorf("page = %v of %d, want Two of 3", page, total)
+	}
+
+	all, err := local.GetLists(ctx)
+
+	if err != nil || len(all) != 3 {
+		t.Errorf("GetLists = %v, %v, want 3 lists", all, err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	attempts := map[string]int{}
+	l := &sync.Mutex{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		l.Lock()
+		attempts[r.Method]++
+		n := attempts[r.Method]
+		l.Unlock()
+
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "busy"}`))
+			return
+		}
+
+		w.Write([]byte(`{"id": "1", "name": "Work"}`))
+	}))
+	defer server.Close()
+
+	client := remote.New(server.URL, "")
+	client.Backoff = time.Millisecond
+
+	list, err := client.GetList(context.Background(), "1")
+
+	if err != nil || list.Name != "Work" || attempts[http.MethodGet] != 3 {
+		t.Errorf("GetList = %v, %v after %d attempts, want Work after 3", list, err, attempts[http.MethodGet])
+	}
+
+	// creating is not retried, the first attempt might have been stored
+	_, err = client.CreateList(context.Background(), &lists.List{Name: "Work"})
+
+	if err == nil || attempts[http.MethodPost] != 1 {
+		t.Errorf("CreateList = %v after %d attempts, want an error after 1", err, attempts[http.MethodPost])
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	local, server := newRemote(t)
+
+	list, _ := local.StoreList(ctx, &lists.List{Name: "Work"})
+	report, _ := local.StoreTask(ctx, &tasks.Task{ListID: list.ID, Text: "write report"})
+	mail, _ := local.StoreTask(ctx, &tasks.Task{ListID: list.ID, Text: "write mail"})
+
+	// the server asks its hooks about the whole batch, one veto keeps every task
+	server.Events.Before(func(_ context.Context, event *service.Event) error {
+		return errors.New("mail is important")
+	}, service.TaskDeleted)
+
+	report.Completed = true
+	err := local.ApplyTasks(ctx, []*tasks.Task{report}, []string{mail.ID})
+
+	if err == nil {
+		t.Fatal("the veto of the server was ignored")
+	}
+
+	if stored, _ := server.GetTask(ctx, report.ID); stored.Completed {
+		t.Error("the update was made despite the veto")
+	}
+
+	_, err = local.UpdateTasks(ctx, []*tasks.Task{report})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if stored, _ := server.GetTask(ctx, report.ID); !stored.Completed {
+		t.Error("the update was not made")
+	}
+
+	// nothing is changed if a task to update is missing
+	err = local.ApplyTasks(ctx, []*tasks.Task{{ID: "missing", Text: "lost"}}, nil)
+
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("err = %v, want tasks.ErrNotFound", err)
+	}
+}
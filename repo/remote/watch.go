@@ -0,0 +1,118 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo"
+)
+
+// maxWatchBackoff limits the wait between two attempts to reconnect the change stream
+const maxWatchBackoff = 30 * time.Second
+
+// Watch subscribes to the change stream of the server. A lost connection is
+// reconnected until ctx is done, a change is reported after each reconnect
+// because events might have been missed in between
+func (c *Client) Watch(ctx context.Context) (<-chan struct{}, error) {
+	body, err := c.openWatch(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+
+		backoff := c.Backoff
+
+		for {
+			readEvents(body, changes)
+			body.Close()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+
+				body, err = c.openWatch(ctx)
+
+				if err == nil {
+					break
+				}
+
+				if backoff *= 2; backoff > maxWatchBackoff {
+					backoff = maxWatchBackoff
+				}
+			}
+
+			backoff = c.Backoff
+			signal(changes)
+		}
+	}()
+
+	return changes, nil
+}
+
+// openWatch connects to the change stream, servers without one report repo.ErrWatchNotSupported
+func (c *Client) openWatch(ctx context.Context) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/watch", nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "text/event-stream")
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	// the timeout of HTTPClient would end the stream, so only its transport is used
+	streamClient := &http.Client{
+		Transport: c.HTTPClient.Transport,
+	}
+
+	resp, err := streamClient.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, repo.ErrWatchNotSupported
+
+	case resp.StatusCode >= 300:
+		resp.Body.Close()
+		return nil, &statusError{status: resp.StatusCode, message: http.StatusText(resp.StatusCode)}
+	}
+
+	return resp.Body, nil
+}
+
+// readEvents signals changes for every "change" event until the stream ends
+func readEvents(body io.Reader, changes chan<- struct{}) {
+	scanner := bufio.NewScanner(body)
+
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "event: change" {
+			signal(changes)
+		}
+	}
+}
+
+func signal(changes chan<- struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default:
+	}
+}
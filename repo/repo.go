@@ -1,2 +1,17 @@
 // Package repo contains packages for storing and fetching data models
 package repo
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrWatchNotSupported is returned by watchers which can't watch their current storage
+var ErrWatchNotSupported = errors.New("watching for changes is not supported by this storage")
+
+// Watcher is implemented by the backends which can notice changes made by
+// other processes. The channel receives a value whenever the stored data may
+// have changed and is closed once the context is done
+type Watcher interface {
+	Watch(context.Context) (<-chan struct{}, error)
+}
@@ -0,0 +1,69 @@
+// Package sqlwatch notices changes of a SQLite database made by other connections
+package sqlwatch
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Interval is the time between two checks of the database
+var Interval = time.Second
+
+// Watch polls the data_version pragma of the database, which changes whenever
+// another connection commits. A dedicated connection is kept open while watching,
+// since the version is tracked per connection
+func Watch(ctx context.Context, db *sql.DB) (<-chan struct{}, error) {
+	conn, err := db.Conn(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	last, err := dataVersion(ctx, conn)
+
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+
+	go func() {
+		defer close(changes)
+		defer conn.Close()
+
+		ticker := time.NewTicker(Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			version, err := dataVersion(ctx, conn)
+
+			if err != nil || version == last {
+				continue
+			}
+
+			last = version
+
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+func dataVersion(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var version int64
+	err := conn.QueryRowContext(ctx, "PRAGMA data_version").Scan(&version)
+
+	return version, err
+}
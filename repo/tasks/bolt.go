@@ -0,0 +1,316 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/julez-dev/go2todo/repo/boltwatch"
+	"github.com/julez-dev/go2todo/repo/internal/boltindex"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	tasksBucket = []byte("tasks")
+	// listIndexBucket holds one nested bucket per list, containing the IDs of its tasks
+	// in creation order, see boltindex
+	listIndexBucket = []byte("tasks_by_list_created")
+	// noListKey is the index bucket of the tasks without a list, bolt doesn't allow empty bucket names
+	noListKey = []byte{0}
+)
+
+type InBolt struct {
+	db *bolt.DB
+}
+
+func NewInBolt(db *bolt.DB) (*InBolt, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(tasksBucket)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.CreateBucketIfNotExists(listIndexBucket)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &InBolt{
+		db: db,
+	}, nil
+}
+
+func (b *InBolt) CreateTask(_ context.Context, task *Task) (*Task, error) {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return putTask(tx, task)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (b *InBolt) UpdateTask(_ context.Context, task *Task) (*Task, error) {
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		return updateTask(tx, task)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (b *InBolt) GetTask(_ context.Context, id string) (*Task, error) {
+	var task *Task
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		task, err = getTask(tx, id)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (b *InBolt) GetTasks(_ context.Context, listID string) ([]*Task, error) {
+	tasks := []*Task{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		index := tx.Bucket(listIndexBucket).Bucket(indexKey(listID))
+
+		if index == nil {
+			return nil
+		}
+
+		return index.ForEach(func(_, id []byte) error {
+			task, err := getTask(tx, string(id))
+
+			if err != nil {
+				return err
+			}
+
+			tasks = append(tasks, task)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// GetTasksPage reads a page of the tasks of the list, ordered by creation time
+func (b *InBolt) GetTasksPage(_ context.Context, listID string, offset, limit int) ([]*Task, int, error) {
+	page := []*Task{}
+	total := 0
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var ids [][]byte
+		ids, total = boltindex.Page(tx.Bucket(listIndexBucket).Bucket(indexKey(listID)), offset, limit)
+
+		for _, id := range ids {
+			task, err := getTask(tx, string(id))
+
+			if err != nil {
+				return err
+			}
+
+			page = append(page, task)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return page, total, nil
+}
+
+func (b *InBolt) GetAllTasks(_ context.Context) ([]*Task, error) {
+	tasks := []*Task{}
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(tasksBucket).ForEach(func(_, value []byte) error {
+			task := &Task{}
+			err := json.Unmarshal(value, task)
+
+			if err != nil {
+				return err
+			}
+
+			tasks = append(tasks, task)
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func (b *InBolt) DeleteTask(_ context.Context, id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return deleteTask(tx, id)
+	})
+}
+
+// ApplyTasks updates and deletes the tasks in one transaction
+func (b *InBolt) ApplyTasks(_ context.Context, updated []*Task, deleted []string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		for _, task := range updated {
+			err := updateTask(tx, task)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, id := range deleted {
+			err := deleteTask(tx, id)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *InBolt) DeleteTasks(_ context.Context, listID string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		index := tx.Bucket(listIndexBucket).Bucket(indexKey(listID))
+
+		if index == nil {
+			return nil
+		}
+
+		err := index.ForEach(func(_, id []byte) error {
+			return tx.Bucket(tasksBucket).Delete(id)
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return tx.Bucket(listIndexBucket).DeleteBucket(indexKey(listID))
+	})
+}
+
+// Watch notices the commits to the database, which holds the lists as well. Bolt doesn't
+// let other processes open it, so the commits are made through the same handle
+func (b *InBolt) Watch(ctx context.Context) (<-chan struct{}, error) {
+	return boltwatch.Watch(ctx, b.db)
+}
+
+func getTask(tx *bolt.Tx, id string) (*Task, error) {
+	value := tx.Bucket(tasksBucket).Get([]byte(id))
+
+	if value == nil {
+		return nil, ErrNotFound
+	}
+
+	task := &Task{}
+	err := json.Unmarshal(value, task)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func updateTask(tx *bolt.Tx, task *Task) error {
+	existing, err := getTask(tx, task.ID)
+
+	if err != nil {
+		return err
+	}
+
+	// the list or the creation time may have changed, both move the task in the index
+	err = unindexTask(tx, existing)
+
+	if err != nil {
+		return err
+	}
+
+	return putTask(tx, task)
+}
+
+func deleteTask(tx *bolt.Tx, id string) error {
+	task, err := getTask(tx, id)
+
+	if err == ErrNotFound {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = unindexTask(tx, task)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Bucket(tasksBucket).Delete([]byte(id))
+}
+
+func putTask(tx *bolt.Tx, task *Task) error {
+	value, err := json.Marshal(task)
+
+	if err != nil {
+		return err
+	}
+
+	err = tx.Bucket(tasksBucket).Put([]byte(task.ID), value)
+
+	if err != nil {
+		return err
+	}
+
+	index, err := tx.Bucket(listIndexBucket).CreateBucketIfNotExists(indexKey(task.ListID))
+
+	if err != nil {
+		return err
+	}
+
+	return boltindex.Put(index, boltindex.Key(task.CreatedAt, task.ID), []byte(task.ID))
+}
+
+func unindexTask(tx *bolt.Tx, task *Task) error {
+	index := tx.Bucket(listIndexBucket).Bucket(indexKey(task.ListID))
+
+	if index == nil {
+		return nil
+	}
+
+	return boltindex.Delete(index, boltindex.Key(task.CreatedAt, task.ID))
+}
+
+// indexKey returns the name of the index bucket of the list
+func indexKey(listID string) []byte {
+	if listID == "" {
+		return noListKey
+	}
+
+	return []byte(listID)
+}
@@ -0,0 +1,261 @@
+package tasks_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/boltwatch"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newBoltRepo(t *testing.T) *tasks.InBolt {
+	t.Helper()
+
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "go2todo.db"), 0600, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := tasks.NewInBolt(db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return repo
+}
+
+func TestInBolt(t *testing.T) {
+	ctx := context.Background()
+	repo := newBoltRepo(t)
+
+	due := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	task := &tasks.Task{ID: "1", ListID: "work", Text: "write report", Priority: 1, Due: &due, Tags: []string{"office"}}
+
+	_, err := repo.CreateTask(ctx, task)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := repo.GetTask(ctx, "1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertTask(t, got, task)
+
+	// moving the task to another list moves it in the index
+	task.ListID = "home"
+	_, err = repo.UpdateTask(ctx, task)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if work, _ := repo.GetTasks(ctx, "work"); len(work) != 0 {
+		t.Errorf("work still holds %v", work)
+	}
+
+	if home, _ := repo.GetTasks(ctx, "home"); len(home) != 1 {
+		t.Errorf("home = %v, want the moved task", home)
+	}
+
+	_, err = repo.UpdateTask(ctx, &tasks.Task{ID: "missing"})
+
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("updating a missing task: err = %v, want ErrNotFound", err)
+	}
+
+	err = repo.DeleteTask(ctx, "1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.GetTask(ctx, "1")
+
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestInBoltWithoutList(t *testing.T) {
+	ctx := context.Background()
+	repo := newBoltRepo(t)
+
+	for _, task := range []*tasks.Task{{ID: "1", Text: "no list"}, {ID: "2", ListID: "work", Text: "listed"}} {
+		_, err := repo.CreateTask(ctx, task)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	unlisted, err := repo.GetTasks(ctx, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(unlisted) != 1 || unlisted[0].ID != "1" {
+		t.Fatalf("tasks without list = %v, want no list", unlisted)
+	}
+
+	err = repo.DeleteTasks(ctx, "")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := repo.GetAllTasks(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != 1 || all[0].ID != "2" {
+		t.Errorf("tasks = %v, want only listed", all)
+	}
+}
+
+func TestInBoltApplyTasks(t *testing.T) {
+	ctx := context.Background()
+	repo := newBoltRepo(t)
+
+	for _, id := range []string{"1", "2"} {
+		_, err := repo.CreateTask(ctx, &tasks.Task{ID: id, ListID: "work", Text: "task " + id})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := repo.ApplyTasks(ctx, []*tasks.Task{{ID: "1", ListID: "work", Text: "changed"}}, []string{"2"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, _ := repo.GetTasks(ctx, "work")
+
+	if len(all) != 1 || all[0].Text != "changed" {
+		t.Fatalf("tasks = %v, want only changed", all)
+	}
+
+	// a missing task makes the whole batch fail
+	err = repo.ApplyTasks(ctx, []*tasks.Task{{ID: "missing"}}, []string{"1"})
+
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+
+	if all, _ := repo.GetTasks(ctx, "work"); len(all) != 1 {
+		t.Errorf("the failed batch deleted %v", all)
+	}
+}
+
+func TestInBoltWatch(t *testing.T) {
+	interval := boltwatch.Interval
+	boltwatch.Interval = 10 * time.Millisecond
+	defer func() { boltwatch.Interval = interval }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	repo := newBoltRepo(t)
+
+	changes, err := repo.Watch(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = repo.CreateTask(ctx, &tasks.Task{ID: "1", ListID: "work"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("the commit was not noticed")
+	}
+
+	cancel()
+
+	for range changes {
+	}
+}
+
+func TestInBoltPages(t *testing.T) {
+	ctx := context.Background()
+	repo := newBoltRepo(t)
+	start := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// the IDs sort the other way round than the creation times
+	for i, id := range []string{"e", "d", "c", "b", "a"} {
+		_, err := repo.CreateTask(ctx, &tasks.Task{ID: id, ListID: "work", CreatedAt: start.Add(time.Duration(i) * time.Hour)})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := repo.CreateTask(ctx, &tasks.Task{ID: "other", ListID: "home", CreatedAt: start})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		offset, limit int
+		ids           string
+	}{
+		{0, 2, "ed"},
+		{2, 2, "cb"},
+		{4, 2, "a"},
+		{5, 2, ""},
+		{0, 10, "edcba"},
+	}
+
+	for _, test := range tests {
+		page, total, err := repo.GetTasksPage(ctx, "work", test.offset, test.limit)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := ""
+
+		for _, task := range page {
+			ids += task.ID
+		}
+
+		if ids != test.ids || total != 5 {
+			t.Errorf("page %d+%d = %q of %d, want %q of 5", test.offset, test.limit, ids, total, test.ids)
+		}
+	}
+
+	// restoring an older version of a task moves it in the order
+	_, err = repo.UpdateTask(ctx, &tasks.Task{ID: "a", ListID: "work", CreatedAt: start.Add(-time.Hour)})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if page, total, _ := repo.GetTasksPage(ctx, "work", 0, 1); total != 5 || len(page) != 1 || page[0].ID != "a" {
+		t.Errorf("first page = %v of %d, want a of 5", page, total)
+	}
+
+	if page, total, _ := repo.GetTasksPage(ctx, "missing", 0, 10); total != 0 || len(page) != 0 {
+		t.Errorf("page of a missing list = %v of %d, want none", page, total)
+	}
+}
@@ -58,18 +58,9 @@ func NewInFile(path string) (*InFile, error) {
 }
 
 func (inFile *InFile) CreateTask(ctx context.Context, task *Task) (*Task, error) {
-	f, err := fileutil.OpenAndTruncate(inFile.fileName)
-
-	if err != nil {
-		return nil, err
-	}
-
-	defer f.Close()
-
 	_, _ = inFile.inMem.CreateTask(ctx, task)
-	tasks, _ := inFile.inMem.GetAllTasks(ctx)
 
-	err = json.NewEncoder(f).Encode(tasks)
+	err := inFile.save(ctx)
 
 	if err != nil {
 		return nil, err
@@ -91,18 +82,21 @@ func (inFile *InFile) GetTasks(ctx context.Context, listID string) ([]*Task, err
 }
 
 func (inFile *InFile) DeleteTask(ctx context.Context, id string) error {
-	f, err := fileutil.OpenAndTruncate(inFile.fileName)
+	_ = inFile.inMem.DeleteTask(ctx, id)
+
+	err := inFile.save(ctx)
 
 	if err != nil {
 		return err
 	}
 
-	defer f.Close()
+	return nil
+}
 
-	_ = inFile.inMem.DeleteTask(ctx, id)
-	tasks, _ := inFile.inMem.GetAllTasks(ctx)
+func (inFile *InFile) DeleteTasks(ctx context.Context, listID string) error {
+	_ = inFile.inMem.DeleteTasks(ctx, listID)
 
-	err = json.NewEncoder(f).Encode(tasks)
+	err := inFile.save(ctx)
 
 	if err != nil {
 		return err
@@ -111,44 +105,90 @@ func (inFile *InFile) DeleteTask(ctx context.Context, id string) error {
 	return nil
 }
 
-func (inFile *InFile) DeleteTasks(ctx context.Context, listID string) error {
-	f, err := fileutil.OpenAndTruncate(inFile.fileName)
+func (inFile *InFile) UpdateTask(ctx context.Context, task *Task) (*Task, error) {
+	_, _ = inFile.inMem.UpdateTask(ctx, task)
+
+	err := inFile.save(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// ApplyTasks updates and deletes the tasks, the file is written once
+func (inFile *InFile) ApplyTasks(ctx context.Context, updated []*Task, deleted []string) error {
+	err := inFile.inMem.ApplyTasks(ctx, updated, deleted)
 
 	if err != nil {
 		return err
 	}
 
-	defer f.Close()
+	return inFile.save(ctx)
+}
 
-	_ = inFile.inMem.DeleteTasks(ctx, listID)
+// save replaces the file with the current state of the memory
+func (inFile *InFile) save(ctx context.Context) error {
 	tasks, _ := inFile.inMem.GetAllTasks(ctx)
 
-	err = json.NewEncoder(f).Encode(tasks)
+	data, err := json.Marshal(tasks)
 
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return fileutil.WriteFileAtomic(inFile.fileName, append(data, '\n'))
 }
 
-func (inFile *InFile) UpdateTask(ctx context.Context, task *Task) (*Task, error) {
-	f, err := fileutil.OpenAndTruncate(inFile.fileName)
+// Watch reloads the file whenever it changes on disk, for example because
+// another go2todo process wrote to it
+func (inFile *InFile) Watch(ctx context.Context) (<-chan struct{}, error) {
+	fileChanges, err := fileutil.Watch(ctx, inFile.fileName)
 
 	if err != nil {
 		return nil, err
 	}
 
-	defer f.Close()
+	changes := make(chan struct{}, 1)
 
-	_, _ = inFile.inMem.UpdateTask(ctx, task)
-	tasks, _ := inFile.inMem.GetAllTasks(ctx)
+	go func() {
+		defer close(changes)
 
-	err = json.NewEncoder(f).Encode(tasks)
+		for range fileChanges {
+			// a file which can't be decoded is usually still being written
+			if inFile.reload() != nil {
+				continue
+			}
+
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+func (inFile *InFile) reload() error {
+	file, err := os.Open(inFile.fileName)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return task, nil
+	defer file.Close()
+
+	tasks := []*Task{}
+
+	err = json.NewDecoder(file).Decode(&tasks)
+
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	inFile.inMem.replace(tasks)
+
+	return nil
 }
@@ -103,3 +103,37 @@ func (mem *InMemory) DeleteTasks(_ context.Context, listID string) error {
 
 	return nil
 }
+
+// ApplyTasks updates and deletes the tasks at once, nothing is changed if an updated task is missing
+func (mem *InMemory) ApplyTasks(_ context.Context, updated []*Task, deleted []string) error {
+	mem.l.Lock()
+	defer mem.l.Unlock()
+
+	for _, task := range updated {
+		if _, ok := mem.tasks[task.ID]; !ok {
+			return ErrNotFound
+		}
+	}
+
+	for _, task := range updated {
+		mem.tasks[task.ID] = task
+	}
+
+	for _, id := range deleted {
+		delete(mem.tasks, id)
+	}
+
+	return nil
+}
+
+// replace swaps all stored tasks at once
+func (mem *InMemory) replace(tasks []*Task) {
+	mem.l.Lock()
+	defer mem.l.Unlock()
+
+	mem.tasks = make(map[string]*Task)
+
+	for _, item := range tasks {
+		mem.tasks[item.ID] = item
+	}
+}
@@ -3,35 +3,105 @@ package tasks
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/julez-dev/go2todo/repo"
+	"github.com/julez-dev/go2todo/repo/migrate"
+	"github.com/julez-dev/go2todo/repo/sqlwatch"
 )
 
+var migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Up: func(d migrate.Dialect) string {
+			return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS tasks (
+			id TEXT PRIMARY KEY,
+			list_id TEXT NOT NULL,
+			text TEXT NOT NULL,
+			completed %s DEFAULT %s,
+			created_at %s
+		)`, d.Boolean, d.False, d.Timestamp)
+		},
+	},
+	{
+		Version: 2,
+		Up: func(d migrate.Dialect) string {
+			return "ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 0"
+		},
+	},
+	{
+		Version: 3,
+		Up: func(d migrate.Dialect) string {
+			return "ALTER TABLE tasks ADD COLUMN completed_at " + d.Timestamp
+		},
+	},
+	{
+		Version: 4,
+		Up: func(d migrate.Dialect) string {
+			return "ALTER TABLE tasks ADD COLUMN due " + d.Timestamp
+		},
+	},
+	{
+		Version: 5,
+		Up: func(d migrate.Dialect) string {
+			return "ALTER TABLE tasks ADD COLUMN tags TEXT NOT NULL DEFAULT '[]'"
+		},
+	},
+	{
+		Version: 6,
+		Up: func(d migrate.Dialect) string {
+			return "ALTER TABLE tasks ADD COLUMN parent_id TEXT NOT NULL DEFAULT ''"
+		},
+	},
+	{
+		Version: 7,
+		Up: func(d migrate.Dialect) string {
+			return "ALTER TABLE tasks ADD COLUMN notes TEXT NOT NULL DEFAULT ''"
+		},
+	},
+}
+
+// taskColumns are selected by all queries reading tasks, in the order of scanTask
+const taskColumns = "id, list_id, text, notes, completed, completed_at, priority, due, tags, parent_id, created_at"
+
 type InSQL struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect migrate.Dialect
 }
 
+// NewInSQL creates the repo on top of a SQLite database
 func NewInSQL(db *sql.DB) (*InSQL, error) {
-	const query = `CREATE TABLE IF NOT EXISTS tasks (
-		id TEXT PRIMARY KEY,
-		list_id TEXT NOT NULL,
-		text TEXT NOT NULL,
-		completed INTEGER DEFAULT 0,
-		created_at DATETIME
-	)`
+	return newInSQL(db, migrate.SQLite)
+}
+
+// NewInPostgres creates the repo on top of a PostgreSQL database
+func NewInPostgres(db *sql.DB) (*InSQL, error) {
+	return newInSQL(db, migrate.Postgres)
+}
 
-	_, err := db.Exec(query)
+func newInSQL(db *sql.DB, dialect migrate.Dialect) (*InSQL, error) {
+	err := migrate.Run(context.Background(), db, dialect, "tasks", migrations)
 
 	if err != nil {
 		return nil, err
 	}
 
 	return &InSQL{
-		db: db,
+		db:      db,
+		dialect: dialect,
 	}, nil
 }
 
 func (sql *InSQL) CreateTask(ctx context.Context, task *Task) (*Task, error) {
-	const query = "INSERT INTO tasks (id, list_id, text, completed, created_at) VALUES (?, ?, ?, ?, ?)"
-	_, err := sql.db.ExecContext(ctx, query, task.ID, task.ListID, task.Text, task.Completed, task.CreatedAt)
+	tags, err := encodeTags(task.Tags)
+
+	if err != nil {
+		return nil, err
+	}
+
+	const query = "INSERT INTO tasks (" + taskColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	_, err = sql.db.ExecContext(ctx, sql.dialect.Rebind(query), task.ID, task.ListID, task.Text, task.Notes, task.Completed, task.CompletedAt, task.Priority, task.Due, tags, task.ParentID, task.CreatedAt)
 
 	if err != nil {
 		return nil, err
@@ -41,8 +111,7 @@ func (sql *InSQL) CreateTask(ctx context.Context, task *Task) (*Task, error) {
 }
 
 func (sql *InSQL) UpdateTask(ctx context.Context, task *Task) (*Task, error) {
-	const query = "UPDATE tasks SET text = ?, completed = ? WHERE id = ?"
-	_, err := sql.db.ExecContext(ctx, query, task.Text, task.Completed, task.ID)
+	err := sql.update(ctx, sql.db, task)
 
 	if err != nil {
 		return nil, err
@@ -51,57 +120,122 @@ func (sql *InSQL) UpdateTask(ctx context.Context, task *Task) (*Task, error) {
 	return task, nil
 }
 
-func (sql *InSQL) GetTask(ctx context.Context, id string) (*Task, error) {
-	const query = "SELECT id, list_id, text, completed, created_at FROM tasks WHERE id = ?"
+// update writes the task with db, which may be a transaction
+func (sql *InSQL) update(ctx context.Context, db execer, task *Task) error {
+	tags, err := encodeTags(task.Tags)
 
-	task := &Task{}
+	if err != nil {
+		return err
+	}
 
-	err := sql.db.QueryRowContext(ctx, query, id).Scan(&task.ID, &task.ListID, &task.Text, &task.Completed, &task.CreatedAt)
+	const query = "UPDATE tasks SET list_id = ?, text = ?, notes = ?, completed = ?, completed_at = ?, priority = ?, due = ?, tags = ?, parent_id = ?, created_at = ? WHERE id = ?"
+	_, err = db.ExecContext(ctx, sql.dialect.Rebind(query), task.ListID, task.Text, task.Notes, task.Completed, task.CompletedAt, task.Priority, task.Due, tags, task.ParentID, task.CreatedAt, task.ID)
+
+	return err
+}
+
+// ApplyTasks updates and deletes the tasks in one transaction
+func (sql *InSQL) ApplyTasks(ctx context.Context, updated []*Task, deleted []string) error {
+	tx, err := sql.db.BeginTx(ctx, nil)
 
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return task, err
+	defer tx.Rollback()
+
+	for _, task := range updated {
+		err = sql.update(ctx, tx, task)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, id := range deleted {
+		const query = "DELETE FROM tasks WHERE id = ?"
+		_, err = tx.ExecContext(ctx, sql.dialect.Rebind(query), id)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-func (sql *InSQL) GetTasks(ctx context.Context, listsID string) ([]*Task, error) {
-	const query = "SELECT id, list_id, text, completed, created_at FROM tasks WHERE list_id = ?"
+func (sql *InSQL) GetTask(ctx context.Context, id string) (*Task, error) {
+	const query = "SELECT " + taskColumns + " FROM tasks WHERE id = ?"
 
-	rows, err := sql.db.QueryContext(ctx, query, listsID)
+	task, err := scanTask(sql.db.QueryRowContext(ctx, sql.dialect.Rebind(query), id))
 
 	if err != nil {
-		return nil, err
+		return nil, notFound(err)
 	}
 
-	defer rows.Close()
+	return task, nil
+}
 
-	tasks := []*Task{}
+func (sql *InSQL) GetTasks(ctx context.Context, listsID string) ([]*Task, error) {
+	const query = "SELECT " + taskColumns + " FROM tasks WHERE list_id = ?"
+	return sql.query(ctx, query, listsID)
+}
 
-	for rows.Next() {
-		task := &Task{}
-		err := rows.Scan(&task.ID, &task.ListID, &task.Text, &task.Completed, &task.CreatedAt)
+func (sql *InSQL) GetAllTasks(ctx context.Context) ([]*Task, error) {
+	const query = "SELECT " + taskColumns + " FROM tasks"
+	return sql.query(ctx, query)
+}
 
-		if err != nil {
-			return nil, err
-		}
+// GetTasksPage reads a page of the tasks of the list, ordered by creation time
+func (sql *InSQL) GetTasksPage(ctx context.Context, listID string, offset, limit int) ([]*Task, int, error) {
+	const count = "SELECT COUNT(*) FROM tasks WHERE list_id = ?"
+	total := 0
 
-		tasks = append(tasks, task)
+	err := sql.db.QueryRowContext(ctx, sql.dialect.Rebind(count), listID).Scan(&total)
+
+	if err != nil {
+		return nil, 0, err
 	}
 
-	err = rows.Err()
+	const query = "SELECT " + taskColumns + " FROM tasks WHERE list_id = ? ORDER BY created_at, id LIMIT ? OFFSET ?"
+	page, err := sql.query(ctx, query, listID, limit, offset)
 
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	return tasks, nil
+	return page, total, nil
 }
 
-func (sql *InSQL) GetAllTasks(ctx context.Context) ([]*Task, error) {
-	const query = "SELECT id, list_id, text, completed, created_at FROM tasks"
+// FindTasks selects the tasks in the database, conditions which can't be
+// expressed in SQL are checked after reading the rows
+func (sql *InSQL) FindTasks(ctx context.Context, filter Filter) ([]*Task, error) {
+	condition, args, exact := filter.SQL(sql.dialect)
+	query := "SELECT " + taskColumns + " FROM tasks"
+
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+
+	all, err := sql.query(ctx, query, args...)
+
+	if err != nil || exact {
+		return all, err
+	}
+
+	matching := []*Task{}
+
+	for _, task := range all {
+		if filter.Match(task) {
+			matching = append(matching, task)
+		}
+	}
+
+	return matching, nil
+}
 
-	rows, err := sql.db.QueryContext(ctx, query)
+func (sql *InSQL) query(ctx context.Context, query string, args ...interface{}) ([]*Task, error) {
+	rows, err := sql.db.QueryContext(ctx, sql.dialect.Rebind(query), args...)
 
 	if err != nil {
 		return nil, err
@@ -112,8 +246,7 @@ func (sql *InSQL) GetAllTasks(ctx context.Context) ([]*Task, error) {
 	tasks := []*Task{}
 
 	for rows.Next() {
-		task := &Task{}
-		err := rows.Scan(&task.ID, &task.ListID, &task.Text, &task.Completed, &task.CreatedAt)
+		task, err := scanTask(rows)
 
 		if err != nil {
 			return nil, err
@@ -133,7 +266,7 @@ func (sql *InSQL) GetAllTasks(ctx context.Context) ([]*Task, error) {
 
 func (sql *InSQL) DeleteTask(ctx context.Context, taskID string) error {
 	const query = "DELETE FROM tasks WHERE id = ?"
-	_, err := sql.db.ExecContext(ctx, query, taskID)
+	_, err := sql.db.ExecContext(ctx, sql.dialect.Rebind(query), taskID)
 
 	if err != nil {
 		return err
@@ -144,7 +277,7 @@ func (sql *InSQL) DeleteTask(ctx context.Context, taskID string) error {
 
 func (sql *InSQL) DeleteTasks(ctx context.Context, listID string) error {
 	const query = "DELETE FROM tasks WHERE list_id = ?"
-	_, err := sql.db.ExecContext(ctx, query, listID)
+	_, err := sql.db.ExecContext(ctx, sql.dialect.Rebind(query), listID)
 
 	if err != nil {
 		return err
@@ -152,3 +285,74 @@ func (sql *InSQL) DeleteTasks(ctx context.Context, listID string) error {
 
 	return nil
 }
+
+// Watch notices the changes made by other processes, only SQLite databases are supported
+func (sql *InSQL) Watch(ctx context.Context) (<-chan struct{}, error) {
+	if sql.dialect.Name != migrate.SQLite.Name {
+		return nil, repo.ErrWatchNotSupported
+	}
+
+	return sqlwatch.Watch(ctx, sql.db)
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanTask reads a row selected with taskColumns
+func scanTask(row scanner) (*Task, error) {
+	task := &Task{}
+	var tags string
+
+	err := row.Scan(&task.ID, &task.ListID, &task.Text, &task.Notes, &task.Completed, &task.CompletedAt, &task.Priority, &task.Due, &tags, &task.ParentID, &task.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	task.Tags, err = decodeTags(tags)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// encodeTags stores the tags as JSON array, which keeps them searchable with LIKE
+func encodeTags(tags []string) (string, error) {
+	if len(tags) == 0 {
+		return "[]", nil
+	}
+
+	encoded, err := json.Marshal(tags)
+
+	return string(encoded), err
+}
+
+func decodeTags(encoded string) ([]string, error) {
+	tags := []string{}
+
+	err := json.Unmarshal([]byte(encoded), &tags)
+
+	if err != nil || len(tags) == 0 {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// notFound maps the missing row error of database/sql to ErrNotFound
+func notFound(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,232 @@
+package tasks_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/internal/sqltest"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/migrate"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+func newSQLRepos(t *testing.T) map[string]*tasks.InSQL {
+	repos := map[string]*tasks.InSQL{}
+
+	for _, db := range sqltest.Open(t) {
+		newRepo := tasks.NewInSQL
+
+		if db.Dialect.Name == migrate.Postgres.Name {
+			newRepo = tasks.NewInPostgres
+		}
+
+		repo, err := newRepo(db.DB)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the migrations are only applied once
+		_, err = newRepo(db.DB)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repos[db.Dialect.Name] = repo
+	}
+
+	return repos
+}
+
+func TestInSQL(t *testing.T) {
+	for dialect, repo := range newSQLRepos(t) {
+		repo := repo
+
+		t.Run(dialect, func(t *testing.T) {
+			ctx := context.Background()
+			created := time.Date(2021, time.March, 4, 10, 0, 0, 0, time.UTC)
+
+			for i, text := range []string{"one", "two", "three"} {
+				_, err := repo.CreateTask(ctx, &tasks.Task{
+					ID:        text,
+					ListID:    "work",
+					Text:      text,
+					CreatedAt: created.Add(time.Duration(i) * time.Minute),
+				})
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			_, err := repo.CreateTask(ctx, &tasks.Task{ID: "other", ListID: "home", Text: "other", CreatedAt: created})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			due := time.Date(2021, time.March, 10, 0, 0, 0, 0, time.UTC)
+			done := created.Add(time.Hour)
+			updated := &tasks.Task{
+				ID:          "two",
+				ListID:      "work",
+				Text:        "two, edited",
+				Notes:       "# Notes\n\nacross lines",
+				Completed:   true,
+				CompletedAt: &done,
+				Priority:    2,
+				Due:         &due,
+				Tags:        []string{"a", "b"},
+				ParentID:    "one",
+				CreatedAt:   created.Add(time.Minute),
+			}
+
+			_, err = repo.UpdateTask(ctx, updated)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := repo.GetTask(ctx, "two")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertTask(t, got, updated)
+
+			page, total, err := repo.GetTasksPage(ctx, "work", 1, 5)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if total != 3 || len(page) != 2 || page[0].ID != "two" || page[1].ID != "three" {
+				t.Errorf("GetTasksPage(work, 1, 5) = %v, %d", page, total)
+			}
+
+			err = repo.DeleteTask(ctx, "one")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = repo.GetTask(ctx, "one")
+
+			if !errors.Is(err, tasks.ErrNotFound) {
+				t.Errorf("GetTask of a deleted task = %v, want ErrNotFound", err)
+			}
+
+			err = repo.DeleteTasks(ctx, "work")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			all, err := repo.GetAllTasks(ctx)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(all) != 1 || all[0].ID != "other" {
+				t.Errorf("GetAllTasks after DeleteTasks(work) = %v, want only other", all)
+			}
+		})
+	}
+}
+
+func TestInSQLApplyTasks(t *testing.T) {
+	for dialect, repo := range newSQLRepos(t) {
+		repo := repo
+
+		t.Run(dialect, func(t *testing.T) {
+			ctx := context.Background()
+
+			for _, id := range []string{"a", "b", "c"} {
+				_, err := repo.CreateTask(ctx, &tasks.Task{ID: id, ListID: "work", Text: id, CreatedAt: time.Now()})
+
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := repo.ApplyTasks(ctx, []*tasks.Task{{ID: "a", ListID: "home", Text: "a moved"}}, []string{"b"})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			home, _ := repo.GetTasks(ctx, "home")
+			work, _ := repo.GetTasks(ctx, "work")
+
+			if len(home) != 1 || home[0].Text != "a moved" || len(work) != 1 || work[0].ID != "c" {
+				t.Errorf("after ApplyTasks home = %v, work = %v", home, work)
+			}
+
+		})
+	}
+}
+
+func TestInSQLRestore(t *testing.T) {
+	for name, repo := range newSQLRepos(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			listRepo := lists.NewInMemory()
+			store := service.NewStorage(repo, listRepo)
+			work, _ := store.StoreList(ctx, &lists.List{Name: "Work"})
+
+			stored, err := store.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "write report"})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// restoring over an existing task keeps the creation time of the archive
+			archived := *stored
+			archived.Text = "write the report"
+			archived.CreatedAt = time.Date(2021, 3, 5, 9, 30, 0, 0, time.UTC)
+
+			_, err = store.RestoreTask(ctx, &archived)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := repo.GetTask(ctx, stored.ID)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			assertTask(t, got, &archived)
+		})
+	}
+}
+
+// assertTask compares the tasks, the times only need to be the same instant
+func assertTask(t *testing.T, got, want *tasks.Task) {
+	t.Helper()
+
+	sameTime := func(a, b *time.Time) bool {
+		return (a == nil && b == nil) || (a != nil && b != nil && a.Equal(*b))
+	}
+
+	if !sameTime(got.Due, want.Due) || !sameTime(got.CompletedAt, want.CompletedAt) || !got.CreatedAt.Equal(want.CreatedAt) {
+		t.Errorf("times differ: got due %v completed %v created %v, want %v %v %v",
+			got.Due, got.CompletedAt, got.CreatedAt, want.Due, want.CompletedAt, want.CreatedAt)
+	}
+
+	gotCopy, wantCopy := *got, *want
+	gotCopy.Due, gotCopy.CompletedAt, gotCopy.CreatedAt = nil, nil, time.Time{}
+	wantCopy.Due, wantCopy.CompletedAt, wantCopy.CreatedAt = nil, nil, time.Time{}
+
+	if !reflect.DeepEqual(gotCopy, wantCopy) {
+		t.Errorf("got %+v, want %+v", gotCopy, wantCopy)
+	}
+}
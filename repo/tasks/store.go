@@ -4,15 +4,26 @@ import (
 	"context"
 	"errors"
 	"time"
+
+	"github.com/julez-dev/go2todo/repo/migrate"
 )
 
 var ErrNotFound = errors.New("task does not exist")
 
 type Task struct {
-	ID        string    `json:"id"`
-	ListID    string    `json:"list_id"`
-	Text      string    `json:"text"`
-	Completed bool      `json:"completed"`
+	ID          string     `json:"id"`
+	ListID      string     `json:"list_id"`
+	Text        string     `json:"text"`
+	Completed   bool       `json:"completed"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// Priority ranges from 1 (highest) downwards, 0 means no priority
+	Priority int        `json:"priority,omitempty"`
+	Due      *time.Time `json:"due,omitempty"`
+	Tags     []string   `json:"tags,omitempty"`
+	// Notes holds the details of the task as Markdown, it may span several lines
+	Notes string `json:"notes,omitempty"`
+	// ParentID is the ID of the task this one is a subtask of
+	ParentID  string    `json:"parent_id,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
@@ -25,3 +36,29 @@ type Interface interface {
 	DeleteTask(context.Context, string) error
 	DeleteTasks(context.Context, string) error
 }
+
+// Pager is implemented by repos which read a page of the tasks of a list without
+// loading all of them. Pages are in creation order, total counts all tasks of the list
+type Pager interface {
+	GetTasksPage(ctx context.Context, listID string, offset, limit int) (page []*Task, total int, err error)
+}
+
+// Filter selects tasks, it is implemented by the query package
+type Filter interface {
+	Match(*Task) bool
+	// SQL returns a condition for the WHERE clause of the tasks table with ? placeholders.
+	// If exact is false the condition selects a superset, whose rows have to be checked with Match
+	SQL(dialect migrate.Dialect) (condition string, args []interface{}, exact bool)
+}
+
+// Querier is implemented by repos which evaluate filters themselves instead of
+// returning all tasks to be filtered in memory
+type Querier interface {
+	FindTasks(context.Context, Filter) ([]*Task, error)
+}
+
+// Batcher is implemented by repos which change several tasks in one transaction,
+// either all updates and deletions are made or none of them
+type Batcher interface {
+	ApplyTasks(ctx context.Context, updated []*Task, deleted []string) error
+}
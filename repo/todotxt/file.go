@@ -0,0 +1,635 @@
+// Package todotxt contains a storage backend for the todo.txt format
+// (https://github.com/todotxt/todo.txt), implementing both tasks.Interface and lists.Interface.
+//
+// Lists are the +project of a task and use the project as their ID, renamed lists keep
+// their first ID. The task ID is kept in an id:... extension. Smart lists and the IDs
+// of renamed lists are stored in JSON files next to the todo.txt file. The file is read
+// again before every operation and replaced atomically on writes, so other todo.txt clients
+// can work on the same file. Writes hold a lock file against other go2todo processes and
+// start over if another client changed the file in the meantime
+package todotxt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/julez-dev/go2todo/fileutil"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// InboxName is the name of the list holding the tasks without a project
+const InboxName = "Inbox"
+
+// maxAttempts limits how often a write starts over because the file changed while it was edited
+const maxAttempts = 5
+
+// ErrListExists is returned when a list is renamed to the name of another list
+var ErrListExists = errors.New("todotxt: a list with this name exists already")
+
+// ErrLineBreak is returned for tasks whose text spans several lines, each task is one line of the file
+var ErrLineBreak = errors.New("todotxt: the text of a task can't contain line breaks")
+
+// ErrConcurrentModification is returned when another client changed the file during every attempt of a write
+var ErrConcurrentModification = errors.New("todotxt: the file keeps being changed by another client, nothing was written")
+
+type File struct {
+	fileName string
+
+	// empty holds the lists without tasks, todo.txt has no way to store
+	// them so they only live as long as the process
+	empty map[string]*lists.List
+	l     *sync.Mutex
+}
+
+func New(path string) (*File, error) {
+	if !fileutil.FileExists(path) {
+		f, err := os.Create(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		f.Close()
+	}
+
+	return &File{
+		fileName: path,
+		empty:    make(map[string]*lists.List),
+		l:        &sync.Mutex{},
+	}, nil
+}
+
+// load parses the file, the tasks get the IDs of the lists of their projects
+func (f *File) load() ([]*Line, error) {
+	content, err := ioutil.ReadFile(f.fileName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := f.loadListIDs()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(content, ids), nil
+}
+
+// parse splits the content into lines. Lines without an id extension which occur more
+// than once get different IDs, so each of them can be changed on its own
+func parse(content []byte, ids listIDs) []*Line {
+	parsed := []*Line{}
+	occurrences := map[string]int{}
+
+	for _, raw := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+		raw = strings.TrimRight(raw, "\r")
+
+		if strings.TrimSpace(raw) == "" {
+			parsed = append(parsed, &Line{raw: raw})
+			continue
+		}
+
+		line := Parse(raw)
+		line.Task.ListID = ids.id(line.Project)
+
+		if line.derivedID {
+			line.Task.ID = derivedID(raw, occurrences[raw])
+			occurrences[raw]++
+		}
+
+		parsed = append(parsed, line)
+	}
+
+	// an empty file still yields one empty line
+	if len(parsed) == 1 && parsed[0].Task == nil {
+		return []*Line{}
+	}
+
+	return parsed
+}
+
+// save writes the lines, the tasks are stored in the projects of their lists
+func (f *File) save(lines []*Line, ids listIDs) error {
+	b := &strings.Builder{}
+	occurrences := map[string]int{}
+
+	for _, line := range lines {
+		if line.Task != nil && line.derivedID {
+			occurrences[line.raw]++
+		}
+	}
+
+	for _, line := range lines {
+		if line.Task == nil {
+			b.WriteString(line.raw + "\n")
+			continue
+		}
+
+		// identical lines get their IDs written, otherwise deleting one would pass its ID on
+		if line.derivedID && occurrences[line.raw] > 1 {
+			line.raw = ""
+		}
+
+		if project := ids.project(line.Task.ListID); project != line.Project {
+			line.Project = project
+			line.raw = ""
+		}
+
+		b.WriteString(line.String() + "\n")
+	}
+
+	return fileutil.WriteFileAtomic(f.fileName, []byte(b.String()))
+}
+
+// locked runs fn while holding the lock file of the todo.txt file
+func (f *File) locked(fn func() error) error {
+	unlock, err := fileutil.Lock(f.fileName)
+
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	return fn()
+}
+
+// update loads the file, passes the lines to fn and saves the returned lines
+func (f *File) update(fn func([]*Line) ([]*Line, error)) error {
+	return f.locked(func() error {
+		return f.rewrite(func(lines []*Line, _ listIDs) ([]*Line, error) {
+			return fn(lines)
+		})
+	})
+}
+
+// rewrite is update for callers holding the lock, fn may change the IDs of the lists as well.
+// Other todo.txt clients don't know the lock, so the file is compared before it is replaced
+// and fn runs again on a changed file. The write is given up after maxAttempts
+func (f *File) rewrite(fn func([]*Line, listIDs) ([]*Line, error)) error {
+	for attempt := 1; ; attempt++ {
+		content, err := ioutil.ReadFile(f.fileName)
+
+		if err != nil {
+			return err
+		}
+
+		ids, err := f.loadListIDs()
+
+		if err != nil {
+			return err
+		}
+
+		before := listIDs{}
+
+		for id, project := range ids {
+			before[id] = project
+		}
+
+		lines, err := fn(parse(content, before), ids)
+
+		if err != nil {
+			return err
+		}
+
+		current, err := ioutil.ReadFile(f.fileName)
+
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(content, current) {
+			if attempt < maxAttempts {
+				continue
+			}
+
+			return ErrConcurrentModification
+		}
+
+		err = f.save(lines, ids)
+
+		if err != nil || reflect.DeepEqual(before, ids) {
+			return err
+		}
+
+		return f.saveListIDs(ids)
+	}
+}
+
+// Watch notices the changes made to the file, for example by other todo.txt clients
+func (f *File) Watch(ctx context.Context) (<-chan struct{}, error) {
+	return fileutil.Watch(ctx, f.fileName)
+}
+
+func (f *File) CreateTask(_ context.Context, task *tasks.Task) (*tasks.Task, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if strings.ContainsAny(task.Text, "\r\n") {
+		return nil, ErrLineBreak
+	}
+
+	err := f.update(func(lines []*Line) ([]*Line, error) {
+		delete(f.empty, task.ListID)
+		return append(lines, &Line{Task: task}), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (f *File) UpdateTask(_ context.Context, task *tasks.Task) (*tasks.Task, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	err := f.update(func(lines []*Line) ([]*Line, error) {
+		return lines, updateLine(lines, task)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return task, nil
+}
+
+func (f *File) GetTask(_ context.Context, id string) (*tasks.Task, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	lines, err := f.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		if line.Task != nil && line.Task.ID == id {
+			return line.Task, nil
+		}
+	}
+
+	return nil, tasks.ErrNotFound
+}
+
+func (f *File) GetTasks(_ context.Context, listID string) ([]*tasks.Task, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	lines, err := f.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	matchingTasks := []*tasks.Task{}
+
+	for _, line := range lines {
+		if line.Task != nil && line.Task.ListID == listID {
+			matchingTasks = append(matchingTasks, line.Task)
+		}
+	}
+
+	return matchingTasks, nil
+}
+
+func (f *File) GetAllTasks(_ context.Context) ([]*tasks.Task, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	lines, err := f.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	allTasks := []*tasks.Task{}
+
+	for _, line := range lines {
+		if line.Task != nil {
+			allTasks = append(allTasks, line.Task)
+		}
+	}
+
+	return allTasks, nil
+}
+
+func (f *File) DeleteTask(_ context.Context, id string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.update(func(lines []*Line) ([]*Line, error) {
+		return filter(lines, func(line *Line) bool {
+			return line.Task.ID != id
+		}), nil
+	})
+}
+
+func (f *File) DeleteTasks(_ context.Context, listID string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.update(func(lines []*Line) ([]*Line, error) {
+		return filter(lines, func(line *Line) bool {
+			return line.Task.ListID != listID
+		}), nil
+	})
+}
+
+// ApplyTasks updates and deletes the tasks, the file is written once
+func (f *File) ApplyTasks(_ context.Context, updated []*tasks.Task, deleted []string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	removed := map[string]bool{}
+
+	for _, id := range deleted {
+		removed[id] = true
+	}
+
+	return f.update(func(lines []*Line) ([]*Line, error) {
+		for _, task := range updated {
+			err := updateLine(lines, task)
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		return filter(lines, func(line *Line) bool {
+			return !removed[line.Task.ID]
+		}), nil
+	})
+}
+
+// updateLine replaces the task of its line, save moves it to the project of its list
+func updateLine(lines []*Line, task *tasks.Task) error {
+	if strings.ContainsAny(task.Text, "\r\n") {
+		return ErrLineBreak
+	}
+
+	for _, line := range lines {
+		if line.Task != nil && line.Task.ID == task.ID {
+			line.Task = task
+			line.raw = ""
+
+			return nil
+		}
+	}
+
+	return tasks.ErrNotFound
+}
+
+func (f *File) CreateList(_ context.Context, list *lists.List) (*lists.List, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if list.Smart() {
+		err := f.locked(func() error {
+			return f.updateSmartLists(func(all []*lists.List) ([]*lists.List, error) {
+				return append(all, list), nil
+			})
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return list, nil
+	}
+
+	ids, err := f.loadListIDs()
+
+	if err != nil {
+		return nil, err
+	}
+
+	list.ID = ids.id(ProjectName(list.Name))
+	f.empty[list.ID] = list
+
+	return list, nil
+}
+
+// UpdateList renames the project of the list, the list keeps its ID
+func (f *File) UpdateList(_ context.Context, list *lists.List) (*lists.List, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	err := f.locked(func() error {
+		smart := false
+		err := f.updateSmartLists(func(all []*lists.List) ([]*lists.List, error) {
+			if i := indexOfSmartList(all, list.ID); i >= 0 {
+				smart = true
+				all[i] = list
+			}
+
+			return all, nil
+		})
+
+		if err != nil || smart {
+			return err
+		}
+
+		project := ProjectName(list.Name)
+
+		// the lines move to the new project when they are saved with the changed IDs
+		return f.rewrite(func(lines []*Line, ids listIDs) ([]*Line, error) {
+			if owner := ids.id(project); owner != list.ID && f.used(lines, owner) {
+				return nil, ErrListExists
+			}
+
+			ids.rename(list.ID, project)
+
+			return lines, nil
+		})
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// used reports whether the list with the ID has tasks or was created without them
+func (f *File) used(lines []*Line, id string) bool {
+	if _, ok := f.empty[id]; ok {
+		return true
+	}
+
+	for _, line := range lines {
+		if line.Task != nil && line.Task.ListID == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (f *File) GetList(_ context.Context, id string) (*lists.List, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	all, err := f.lists()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, list := range all {
+		if list.ID == id {
+			return list, nil
+		}
+	}
+
+	return nil, lists.ErrNotFound
+}
+
+func (f *File) GetLists(_ context.Context) ([]*lists.List, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	return f.lists()
+}
+
+// lists collects the projects in the order they first appear in the file,
+// followed by the lists without tasks
+func (f *File) lists() ([]*lists.List, error) {
+	lines, err := f.load()
+
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[string]*lists.List{}
+	all := []*lists.List{}
+	smart, err := f.loadSmartLists()
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		if line.Task == nil {
+			continue
+		}
+
+		list, ok := found[line.Task.ListID]
+
+		if !ok {
+			list = &lists.List{
+				ID:        line.Task.ListID,
+				Name:      ListName(line.Project),
+				CreatedAt: line.Task.CreatedAt,
+			}
+
+			if line.Project == "" {
+				list.Name = InboxName
+			}
+
+			found[line.Task.ListID] = list
+			all = append(all, list)
+		}
+
+		if !line.Task.CreatedAt.IsZero() && (list.CreatedAt.IsZero() || line.Task.CreatedAt.Before(list.CreatedAt)) {
+			list.CreatedAt = line.Task.CreatedAt
+		}
+	}
+
+	for id, list := range f.empty {
+		if _, ok := found[id]; !ok {
+			all = append(all, list)
+		}
+	}
+
+	return append(all, smart...), nil
+}
+
+func (f *File) DeleteList(_ context.Context, id string) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	delete(f.empty, id)
+
+	return f.locked(func() error {
+		err := f.updateSmartLists(func(all []*lists.List) ([]*lists.List, error) {
+			if i := indexOfSmartList(all, id); i >= 0 {
+				all = append(all[:i], all[i+1:]...)
+			}
+
+			return all, nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return f.rewrite(func(lines []*Line, ids listIDs) ([]*Line, error) {
+			lines = filter(lines, func(line *Line) bool {
+				return line.Task.ListID != id
+			})
+
+			// a project named like the original name of the list gets its ID back
+			delete(ids, id)
+
+			for _, line := range lines {
+				if line.Task != nil {
+					line.Task.ListID = ids.id(line.Project)
+				}
+			}
+
+			return lines, nil
+		})
+	})
+}
+
+func (f *File) DeleteLists(_ context.Context) error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	f.empty = make(map[string]*lists.List)
+
+	return f.locked(func() error {
+		err := f.updateSmartLists(func([]*lists.List) ([]*lists.List, error) {
+			return nil, nil
+		})
+
+		if err != nil {
+			return err
+		}
+
+		return f.rewrite(func(lines []*Line, ids listIDs) ([]*Line, error) {
+			for id := range ids {
+				delete(ids, id)
+			}
+
+			return filter(lines, func(line *Line) bool {
+				return line.Project == ""
+			}), nil
+		})
+	})
+}
+
+// filter keeps the blank lines and all task lines for which keep returns true
+func filter(lines []*Line, keep func(*Line) bool) []*Line {
+	kept := []*Line{}
+
+	for _, line := range lines {
+		if line.Task == nil || keep(line) {
+			kept = append(kept, line)
+		}
+	}
+
+	return kept
+}
@@ -0,0 +1,38 @@
+package todotxt
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewriteGivesUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	file, err := New(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	runs := 0
+
+	// another client changes the file while every attempt is edited
+	err = file.rewrite(func(lines []*Line, _ listIDs) ([]*Line, error) {
+		runs++
+
+		return lines, ioutil.WriteFile(path, []byte(fmt.Sprintf("change %d\n", runs)), 0644)
+	})
+
+	if !errors.Is(err, ErrConcurrentModification) || runs != maxAttempts {
+		t.Fatalf("err = %v after %d runs, want ErrConcurrentModification after %d", err, runs, maxAttempts)
+	}
+
+	// the change of the other client is kept
+	content, _ := ioutil.ReadFile(path)
+
+	if string(content) != fmt.Sprintf("change %d\n", maxAttempts) {
+		t.Errorf("content = %q, want the last change", content)
+	}
+}
@@ -0,0 +1,272 @@
+package todotxt_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/fileutil"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/repo/todotxt"
+)
+
+func newFile(t *testing.T, content string) (*todotxt.File, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "todo.txt")
+	err := ioutil.WriteFile(path, []byte(content), 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := todotxt.New(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return file, path
+}
+
+func read(t *testing.T, path string) string {
+	t.Helper()
+
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(content)
+}
+
+func TestIdenticalLines(t *testing.T) {
+	ctx := context.Background()
+	file, path := newFile(t, "TBD +Work\nTBD +Work\nTBD +Work\n")
+
+	all, err := file.GetTasks(ctx, "Work")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(all) != 3 || all[0].ID == all[1].ID || all[1].ID == all[2].ID || all[0].ID == all[2].ID {
+		t.Fatalf("tasks = %v, want three different IDs", all)
+	}
+
+	second := all[1]
+	second.Completed = true
+
+	_, err = file.UpdateTask(ctx, second)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Count(read(t, path), "x "); got != 1 {
+		t.Errorf("%d lines completed, want 1:\n%s", got, read(t, path))
+	}
+
+	// deleting one of them doesn't pass its ID on to the others
+	err = file.DeleteTask(ctx, all[0].ID)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, err := file.GetTasks(ctx, "Work")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(remaining) != 2 || remaining[0].ID != all[1].ID || remaining[1].ID != all[2].ID {
+		t.Errorf("remaining = %v, want the IDs %s and %s", remaining, all[1].ID, all[2].ID)
+	}
+}
+
+func TestRenameKeepsListID(t *testing.T) {
+	ctx := context.Background()
+	file, path := newFile(t, "write report +Work\nbuy milk +Home\n")
+
+	list, err := file.GetList(ctx, "Work")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list.Name = "Office"
+	_, err = file.UpdateList(ctx, list)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content := read(t, path); !strings.Contains(content, "+Office") || strings.Contains(content, "+Work") {
+		t.Errorf("the project was not renamed:\n%s", content)
+	}
+
+	renamed, err := file.GetTasks(ctx, "Work")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(renamed) != 1 || renamed[0].ListID != "Work" {
+		t.Fatalf("tasks of Work = %v, want write report", renamed)
+	}
+
+	got, err := file.GetList(ctx, "Work")
+
+	if err != nil || got.Name != "Office" {
+		t.Errorf("GetList(Work) = %v, %v, want Office", got, err)
+	}
+
+	// another client adds a task to the old project, it becomes a list of its own
+	err = ioutil.WriteFile(path, []byte(read(t, path)+"old habit +Work\n"), 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all, err := file.GetLists(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := map[string]string{}
+
+	for _, list := range all {
+		names[list.ID] = list.Name
+	}
+
+	if names["Work"] != "Office" || names["Work~"] != "Work" {
+		t.Errorf("lists = %v, want Work named Office and Work~ named Work", names)
+	}
+
+	// renaming the list back makes the projects one list again
+	err = file.DeleteList(ctx, "Work~")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list.Name = "Work"
+	_, err = file.UpdateList(ctx, list)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content := read(t, path); strings.Contains(content, "+Office") || !strings.Contains(content, "write report +Work") {
+		t.Errorf("the project was not renamed back:\n%s", content)
+	}
+}
+
+func TestRenameToExistingList(t *testing.T) {
+	ctx := context.Background()
+	file, _ := newFile(t, "write report +Work\nbuy milk +Home\n")
+
+	_, err := file.UpdateList(ctx, &lists.List{ID: "Work", Name: "Home"})
+
+	if !errors.Is(err, todotxt.ErrListExists) {
+		t.Errorf("err = %v, want ErrListExists", err)
+	}
+}
+
+func TestDeleteRenamedList(t *testing.T) {
+	ctx := context.Background()
+	file, path := newFile(t, "write report +Work\n")
+
+	_, err := file.UpdateList(ctx, &lists.List{ID: "Work", Name: "Office"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ioutil.WriteFile(path, []byte(read(t, path)+"old habit +Work\n"), 0644)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = file.DeleteList(ctx, "Work")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if content := read(t, path); content != "old habit +Work\n" {
+		t.Errorf("content = %q, want only the task of the other client", content)
+	}
+
+	remaining, err := file.GetTasks(ctx, "Work")
+
+	if err != nil || len(remaining) != 1 {
+		t.Errorf("tasks of Work = %v, %v, want old habit", remaining, err)
+	}
+}
+
+func TestWritesWaitForLock(t *testing.T) {
+	ctx := context.Background()
+	file, path := newFile(t, "")
+
+	unlock, err := fileutil.Lock(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	released := make(chan struct{})
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		close(released)
+		unlock()
+	}()
+
+	_, err = file.CreateTask(ctx, &tasks.Task{ID: "1", Text: "wait"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-released:
+	default:
+		t.Error("the task was written while the file was locked")
+	}
+
+	if content := read(t, path); !strings.Contains(content, "wait id:1") {
+		t.Errorf("content = %q, want the task", content)
+	}
+}
+
+func TestLineBreaks(t *testing.T) {
+	ctx := context.Background()
+	content := "write report +Work id:1\n"
+	file, path := newFile(t, content)
+
+	_, err := file.CreateTask(ctx, &tasks.Task{ID: "2", ListID: "Work", Text: "call Bob\nx injected"})
+
+	if !errors.Is(err, todotxt.ErrLineBreak) {
+		t.Errorf("CreateTask: err = %v, want ErrLineBreak", err)
+	}
+
+	err = file.ApplyTasks(ctx, []*tasks.Task{{ID: "1", ListID: "Work", Text: "write\rreport"}}, nil)
+
+	if !errors.Is(err, todotxt.ErrLineBreak) {
+		t.Errorf("ApplyTasks: err = %v, want ErrLineBreak", err)
+	}
+
+	if got := read(t, path); got != content {
+		t.Errorf("content = %q, want it unchanged", got)
+	}
+}
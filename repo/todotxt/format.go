@@ -0,0 +1,236 @@
+package todotxt
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+const dateLayout = "2006-01-02"
+
+// Keys of the key:value extensions written by go2todo
+const (
+	idKey       = "id"
+	priorityKey = "pri"
+	dueKey      = "due"
+	parentKey   = "parent"
+	notesKey    = "notes"
+)
+
+// Line is a single parsed line of a todo.txt file
+type Line struct {
+	Task *tasks.Task
+	// Project is the first +project of the line, it names the list of the task
+	Project string
+
+	// raw holds the line as read from disk, it is written back unchanged
+	// as long as the task is not modified
+	raw string
+	// derivedID is set if the ID was derived from raw, the line has no id extension
+	derivedID bool
+}
+
+// Parse parses a single todo.txt line. Lines without an id extension get an
+// ID derived from their content, which stays stable until the line is edited.
+// The ID only tells identical lines apart in the context of the file, see parse
+func Parse(raw string) *Line {
+	line := &Line{
+		Task: &tasks.Task{},
+		raw:  raw,
+	}
+
+	fields := strings.Fields(raw)
+
+	if len(fields) > 0 && fields[0] == "x" {
+		line.Task.Completed = true
+		fields = fields[1:]
+
+		if len(fields) > 0 {
+			if completedAt, err := time.ParseInLocation(dateLayout, fields[0], time.Local); err == nil {
+				line.Task.CompletedAt = &completedAt
+				fields = fields[1:]
+			}
+		}
+	}
+
+	if len(fields) > 0 && !line.Task.Completed {
+		if priority := parsePriority(fields[0]); priority > 0 {
+			line.Task.Priority = priority
+			fields = fields[1:]
+		}
+	}
+
+	if len(fields) > 0 {
+		if createdAt, err := time.ParseInLocation(dateLayout, fields[0], time.Local); err == nil {
+			line.Task.CreatedAt = createdAt
+			fields = fields[1:]
+		}
+	}
+
+	text := []string{}
+
+	for _, field := range fields {
+		if line.Project == "" && len(field) > 1 && strings.HasPrefix(field, "+") {
+			line.Project = field[1:]
+			continue
+		}
+
+		// @contexts are the tags of the task
+		if len(field) > 1 && strings.HasPrefix(field, "@") {
+			line.Task.Tags = append(line.Task.Tags, ListName(field[1:]))
+			continue
+		}
+
+		if key, value, ok := extension(field); ok {
+			switch key {
+			case idKey:
+				line.Task.ID = value
+				continue
+			case priorityKey:
+				line.Task.Priority = parsePriority("(" + value + ")")
+				continue
+			case parentKey:
+				line.Task.ParentID = value
+				continue
+			case notesKey:
+				if notes, err := url.PathUnescape(value); err == nil {
+					line.Task.Notes = notes
+					continue
+				}
+			case dueKey:
+				if due, err := time.ParseInLocation(dateLayout, value, time.Local); err == nil {
+					line.Task.Due = &due
+					continue
+				}
+			}
+		}
+
+		text = append(text, field)
+	}
+
+	line.Task.Text = strings.Join(text, " ")
+	line.Task.ListID = line.Project
+
+	if line.Task.ID == "" {
+		line.Task.ID = derivedID(raw, 0)
+		line.derivedID = true
+	}
+
+	return line
+}
+
+// derivedID hashes the line and the number of identical lines above it
+func derivedID(raw string, occurrence int) string {
+	if occurrence > 0 {
+		raw = fmt.Sprintf("%s\x00%d", raw, occurrence)
+	}
+
+	sum := sha1.Sum([]byte(raw))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// String formats the line in the todo.txt format
+func (line *Line) String() string {
+	if line.raw != "" {
+		return line.raw
+	}
+
+	task := line.Task
+	fields := []string{}
+
+	if task.Completed {
+		fields = append(fields, "x")
+
+		if task.CompletedAt != nil {
+			fields = append(fields, task.CompletedAt.Format(dateLayout))
+		}
+	}
+
+	if task.Priority > 0 && !task.Completed {
+		fields = append(fields, "("+priorityLetter(task.Priority)+")")
+	}
+
+	if !task.CreatedAt.IsZero() {
+		fields = append(fields, task.CreatedAt.Format(dateLayout))
+	}
+
+	if task.Text != "" {
+		fields = append(fields, task.Text)
+	}
+
+	if line.Project != "" {
+		fields = append(fields, "+"+line.Project)
+	}
+
+	for _, tag := range task.Tags {
+		fields = append(fields, "@"+ProjectName(tag))
+	}
+
+	if task.Due != nil {
+		fields = append(fields, dueKey+":"+task.Due.Format(dateLayout))
+	}
+
+	fields = append(fields, idKey+":"+task.ID)
+
+	if task.ParentID != "" {
+		fields = append(fields, parentKey+":"+task.ParentID)
+	}
+
+	// notes may contain spaces and line breaks, which would end the extension
+	if task.Notes != "" {
+		fields = append(fields, notesKey+":"+url.PathEscape(task.Notes))
+	}
+
+	// completed tasks lose the leading priority, keep it as an extension instead
+	if task.Priority > 0 && task.Completed {
+		fields = append(fields, priorityKey+":"+priorityLetter(task.Priority))
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// ProjectName converts a list name to a todo.txt project, which can not contain spaces
+func ProjectName(name string) string {
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// ListName converts a todo.txt project back to a list name
+func ListName(project string) string {
+	return strings.ReplaceAll(project, "_", " ")
+}
+
+func extension(field string) (string, string, bool) {
+	i := strings.Index(field, ":")
+
+	if i <= 0 || i == len(field)-1 {
+		return "", "", false
+	}
+
+	return field[:i], field[i+1:], true
+}
+
+func parsePriority(field string) int {
+	if len(field) != 3 || field[0] != '(' || field[2] != ')' {
+		return 0
+	}
+
+	if field[1] < 'A' || field[1] > 'Z' {
+		return 0
+	}
+
+	return int(field[1]-'A') + 1
+}
+
+func priorityLetter(priority int) string {
+	if priority > 26 {
+		priority = 26
+	}
+
+	return string(rune('A' + priority - 1))
+}
@@ -0,0 +1,103 @@
+package todotxt
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/julez-dev/go2todo/fileutil"
+)
+
+// listIDs maps the IDs of renamed lists to their current project. A list keeps the ID it
+// was created with, the project name, when it is renamed, so clients holding the ID
+// don't lose the list. Lists which were never renamed have no entry
+type listIDs map[string]string
+
+// listIDsPath returns the file next to the todo.txt file storing the IDs of renamed lists
+func (f *File) listIDsPath() string {
+	return f.fileName + ".lists.json"
+}
+
+func (f *File) loadListIDs() (listIDs, error) {
+	content, err := ioutil.ReadFile(f.listIDsPath())
+
+	if os.IsNotExist(err) {
+		return listIDs{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids := listIDs{}
+	err = json.Unmarshal(content, &ids)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (f *File) saveListIDs(ids listIDs) error {
+	if len(ids) == 0 {
+		err := os.Remove(f.listIDsPath())
+
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	content, err := json.MarshalIndent(ids, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFileAtomic(f.listIDsPath(), content)
+}
+
+// project returns the project of the list with the ID
+func (ids listIDs) project(id string) string {
+	if project, ok := ids[id]; ok {
+		return project
+	}
+
+	// see id for the lists whose project is the ID of a renamed list
+	if project := strings.TrimSuffix(id, "~"); project != id {
+		if _, ok := ids[project]; ok {
+			return project
+		}
+	}
+
+	return id
+}
+
+// id returns the ID of the list of the project. A project named like the original name of a
+// renamed list, for example one written by another todo.txt client, gets the ID project~
+func (ids listIDs) id(project string) string {
+	for id, renamed := range ids {
+		if renamed == project {
+			return id
+		}
+	}
+
+	if _, ok := ids[project]; ok {
+		return project + "~"
+	}
+
+	return project
+}
+
+// rename moves the list with the ID to the project
+func (ids listIDs) rename(id, project string) {
+	if project == id {
+		delete(ids, id)
+		return
+	}
+
+	ids[id] = project
+}
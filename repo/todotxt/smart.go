@@ -0,0 +1,80 @@
+package todotxt
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+
+	"github.com/julez-dev/go2todo/fileutil"
+	"github.com/julez-dev/go2todo/repo/lists"
+)
+
+// smartListsPath returns the file next to the todo.txt file storing the smart
+// lists, which have no tasks and so can't be part of the todo.txt file
+func (f *File) smartListsPath() string {
+	return f.fileName + ".smartlists.json"
+}
+
+func (f *File) loadSmartLists() ([]*lists.List, error) {
+	content, err := ioutil.ReadFile(f.smartListsPath())
+
+	if os.IsNotExist(err) {
+		return []*lists.List{}, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	all := []*lists.List{}
+	err = json.Unmarshal(content, &all)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+// updateSmartLists loads the smart lists, passes them to fn and saves the returned lists
+func (f *File) updateSmartLists(fn func([]*lists.List) ([]*lists.List, error)) error {
+	all, err := f.loadSmartLists()
+
+	if err != nil {
+		return err
+	}
+
+	all, err = fn(all)
+
+	if err != nil {
+		return err
+	}
+
+	if len(all) == 0 {
+		err := os.Remove(f.smartListsPath())
+
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	content, err := json.MarshalIndent(all, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFileAtomic(f.smartListsPath(), content)
+}
+
+func indexOfSmartList(all []*lists.List, id string) int {
+	for i, list := range all {
+		if list.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
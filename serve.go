@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/julez-dev/go2todo/api"
+	"github.com/julez-dev/go2todo/caldav"
+	"github.com/julez-dev/go2todo/grpcapi"
+	todov1 "github.com/julez-dev/go2todo/proto/todo/v1"
+	"google.golang.org/grpc"
+)
+
+// serve runs the REST, CalDAV and gRPC APIs until the process receives an interrupt
+func serve(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", "localhost:8080", "address to listen on")
+	grpcAddr := flags.String("grpc-addr", "", "address for the gRPC API, empty disables it")
+	token := flags.String("token", os.Getenv("GO2TODO_TOKEN"), "bearer token clients have to send, empty disables authentication")
+	flags.Parse(args)
+
+	store := chooseStore()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	registerHooks(ctx, store, loadConfig(), log.Default())
+
+	// there is no write timeout because /v1/watch streams for as long as the client is connected,
+	// the base context ends those streams on shutdown
+	mux := http.NewServeMux()
+	mux.Handle("/", api.RequireToken(*token, api.NewServer(store)))
+	// clients often drop the trailing slash, which would otherwise be answered with a redirect
+	calendars := caldav.RequireToken(*token, caldav.NewHandler(store, "/caldav"))
+	mux.Handle("/caldav", calendars)
+	mux.Handle("/caldav/", calendars)
+	mux.Handle("/.well-known/caldav", http.RedirectHandler("/caldav/", http.StatusMovedPermanently))
+
+	srv := &http.Server{
+		Addr:        *addr,
+		Handler:     mux,
+		ReadTimeout: 10 * time.Second,
+		IdleTimeout: 2 * time.Minute,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	go func() {
+		log.Printf("listening on %s", *addr)
+
+		err := srv.ListenAndServe()
+
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalln(err)
+		}
+	}()
+
+	var grpcServer *grpc.Server
+
+	if *grpcAddr != "" {
+		grpcServer = grpc.NewServer(grpc.UnaryInterceptor(grpcapi.RequireToken(*token)), grpc.StreamInterceptor(grpcapi.RequireStreamToken(*token)))
+		todov1.RegisterTodoServiceServer(grpcServer, grpcapi.NewServer(store, store.Events))
+
+		listener, err := net.Listen("tcp", *grpcAddr)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		go func() {
+			log.Printf("gRPC listening on %s", *grpcAddr)
+
+			err := grpcServer.Serve(listener)
+
+			if err != nil {
+				log.Fatalln(err)
+			}
+		}()
+	}
+
+	<-ctx.Done()
+	log.Println("shutting down")
+
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := srv.Shutdown(shutdownCtx)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	store.Events.Wait()
+}
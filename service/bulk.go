@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// UpdateTasks updates several tasks at once, see ApplyTasks
+func (s *Storage) UpdateTasks(ctx context.Context, updated []*tasks.Task) ([]*tasks.Task, error) {
+	err := s.ApplyTasks(ctx, updated, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// DeleteTasksByID deletes several tasks at once, see ApplyTasks
+func (s *Storage) DeleteTasksByID(ctx context.Context, ids []string) error {
+	return s.ApplyTasks(ctx, nil, ids)
+}
+
+// ApplyTasks updates and deletes several tasks at once. The before hooks are asked about
+// all of them first, a single veto keeps every task unchanged. Missing tasks to delete are
+// skipped. Repos implementing tasks.Batcher make the changes in one transaction, all others
+// one after another until one fails
+func (s *Storage) ApplyTasks(ctx context.Context, updated []*tasks.Task, deleted []string) error {
+	events := make([]*Event, 0, len(updated)+len(deleted))
+
+	for _, task := range updated {
+		event, err := s.prepareUpdate(ctx, task)
+
+		if err != nil {
+			return err
+		}
+
+		events = append(events, event)
+	}
+
+	existing := []string{}
+
+	for _, id := range deleted {
+		event, err := s.prepareDelete(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		if event != nil {
+			events = append(events, event)
+			existing = append(existing, id)
+		}
+	}
+
+	return s.applyTasks(ctx, updated, existing, events)
+}
+
+// applyTasks makes the checked changes and publishes their events, in one transaction if the repo supports it
+func (s *Storage) applyTasks(ctx context.Context, updated []*tasks.Task, deleted []string, events []*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	if batcher, ok := s.TasksRepo.(tasks.Batcher); ok {
+		err := batcher.ApplyTasks(ctx, updated, deleted)
+
+		if err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			s.changed(event)
+		}
+
+		return nil
+	}
+
+	for i, task := range updated {
+		_, err := s.TasksRepo.UpdateTask(ctx, task)
+
+		if err != nil {
+			return err
+		}
+
+		s.changed(events[i])
+	}
+
+	for i, id := range deleted {
+		err := s.TasksRepo.DeleteTask(ctx, id)
+
+		if err != nil {
+			return err
+		}
+
+		s.changed(events[len(updated)+i])
+	}
+
+	return nil
+}
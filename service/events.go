@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+type EventType string
+
+const (
+	TaskCreated   EventType = "task.created"
+	TaskUpdated   EventType = "task.updated"
+	TaskCompleted EventType = "task.completed"
+	TaskReopened  EventType = "task.reopened"
+	TaskDeleted   EventType = "task.deleted"
+	TasksDeleted  EventType = "tasks.deleted"
+	ListCreated   EventType = "list.created"
+	ListUpdated   EventType = "list.updated"
+	ListDeleted   EventType = "list.deleted"
+	ListsDeleted  EventType = "lists.deleted"
+)
+
+// EventTypes contains all types of events published by Storage
+var EventTypes = []EventType{
+	TaskCreated, TaskUpdated, TaskCompleted, TaskReopened, TaskDeleted, TasksDeleted,
+	ListCreated, ListUpdated, ListDeleted, ListsDeleted,
+}
+
+// ParseEventTypes converts the names of event types, for example from the configuration file
+func ParseEventTypes(names []string) ([]EventType, error) {
+	types := []EventType{}
+
+	for _, name := range names {
+		known := false
+
+		for _, t := range EventTypes {
+			if string(t) == name {
+				known = true
+			}
+		}
+
+		if !known {
+			return nil, fmt.Errorf("unknown event %q", name)
+		}
+
+		types = append(types, EventType(name))
+	}
+
+	return types, nil
+}
+
+// Event describes a mutation of the storage. Only the fields relevant for
+// the type are set, hooks must not modify them
+type Event struct {
+	Type EventType `json:"type"`
+	Time time.Time `json:"time"`
+
+	Task *tasks.Task `json:"task,omitempty"`
+	// Previous is the task before an update
+	Previous *tasks.Task `json:"previous,omitempty"`
+	List     *lists.List `json:"list,omitempty"`
+	// ListID is set for TasksDeleted
+	ListID string `json:"list_id,omitempty"`
+}
+
+// BeforeHook runs before a change is made, returning an error vetoes the change
+type BeforeHook func(context.Context, *Event) error
+
+// AfterHook runs in its own goroutine after a change was made. After hooks get a copy
+// of the event, the caller may change the task or list while they run
+type AfterHook func(*Event)
+
+// VetoError is returned by the mutating methods of Storage when a before hook rejected the change
+type VetoError struct {
+	Type EventType
+	Err  error
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("%s was rejected by a hook: %v", e.Type, e.Err)
+}
+
+func (e *VetoError) Unwrap() error {
+	return e.Err
+}
+
+// EventBus dispatches the events of a Storage to the registered hooks
+type EventBus struct {
+	before    map[EventType][]BeforeHook
+	after     map[EventType][]AfterHook
+	observers []AfterHook
+
+	running *sync.WaitGroup
+	l       *sync.RWMutex
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		before:  make(map[EventType][]BeforeHook),
+		after:   make(map[EventType][]AfterHook),
+		running: &sync.WaitGroup{},
+		l:       &sync.RWMutex{},
+	}
+}
+
+// Before registers a hook for the given event types, or for all types if none are given
+func (b *EventBus) Before(hook BeforeHook, types ...EventType) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if len(types) == 0 {
+		types = EventTypes
+	}
+
+	for _, t := range types {
+		b.before[t] = append(b.before[t], hook)
+	}
+}
+
+// After registers a hook for the given event types, or for all types if none are given
+func (b *EventBus) After(hook AfterHook, types ...EventType) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	if len(types) == 0 {
+		types = EventTypes
+	}
+
+	for _, t := range types {
+		b.after[t] = append(b.after[t], hook)
+	}
+}
+
+// Observe registers a hook receiving all events in the order of the changes. Unlike after
+// hooks it runs synchronously while the change is reported, so it must not block
+func (b *EventBus) Observe(hook AfterHook) {
+	b.l.Lock()
+	defer b.l.Unlock()
+
+	b.observers = append(b.observers, hook)
+}
+
+// Wait blocks until all running after hooks returned
+func (b *EventBus) Wait() {
+	b.running.Wait()
+}
+
+// check runs the before hooks of the event in the order they were registered,
+// stopping at the first veto
+func (b *EventBus) check(ctx context.Context, event *Event) error {
+	b.l.RLock()
+	hooks := b.before[event.Type]
+	b.l.RUnlock()
+
+	for _, hook := range hooks {
+		err := hook(ctx, event)
+
+		if err != nil {
+			return &VetoError{Type: event.Type, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// publish passes the event to the observers and starts its after hooks
+func (b *EventBus) publish(event *Event) {
+	b.l.RLock()
+	hooks := b.after[event.Type]
+	observers := b.observers
+	b.l.RUnlock()
+
+	for _, observer := range observers {
+		observer(event)
+	}
+
+	for _, hook := range hooks {
+		b.running.Add(1)
+
+		go func(hook AfterHook, event *Event) {
+			defer b.running.Done()
+			hook(event)
+		}(hook, event.copy())
+	}
+}
+
+// copy returns a copy of the event which shares no task or list with it
+func (e *Event) copy() *Event {
+	c := *e
+	c.Task = copyTask(e.Task)
+	c.Previous = copyTask(e.Previous)
+
+	if e.List != nil {
+		list := *e.List
+		c.List = &list
+	}
+
+	return &c
+}
+
+func copyTask(task *tasks.Task) *tasks.Task {
+	if task == nil {
+		return nil
+	}
+
+	c := *task
+	c.Tags = append([]string(nil), task.Tags...)
+
+	if task.Due != nil {
+		due := *task.Due
+		c.Due = &due
+	}
+
+	if task.CompletedAt != nil {
+		completedAt := *task.CompletedAt
+		c.CompletedAt = &completedAt
+	}
+
+	return &c
+}
+
+// transition returns the TaskCompleted or TaskReopened event following a TaskUpdated
+// event which changes whether the task is completed, nil for all other events
+func (e *Event) transition() *Event {
+	if e.Type != TaskUpdated || e.Previous == nil || e.Task.Completed == e.Previous.Completed {
+		return nil
+	}
+
+	transition := *e
+	transition.Type = TaskReopened
+
+	if e.Task.Completed {
+		transition.Type = TaskCompleted
+	}
+
+	return &transition
+}
+
+func newEvent(t EventType) *Event {
+	return &Event{
+		Type: t,
+		Time: time.Now(),
+	}
+}
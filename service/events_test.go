@@ -0,0 +1,115 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+// record collects the types of the events published by the storage in order
+func record(store *service.Storage) func() []service.EventType {
+	l := &sync.Mutex{}
+	types := []service.EventType{}
+
+	store.Events.Observe(func(event *service.Event) {
+		l.Lock()
+		defer l.Unlock()
+
+		types = append(types, event.Type)
+	})
+
+	return func() []service.EventType {
+		l.Lock()
+		defer l.Unlock()
+
+		recorded := types
+		types = []service.EventType{}
+
+		return recorded
+	}
+}
+
+func TestCompletionEvents(t *testing.T) {
+	ctx := context.Background()
+	store := service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+	recorded := record(store)
+
+	list, _ := store.StoreList(ctx, &lists.List{Name: "Work"})
+	task, _ := store.StoreTask(ctx, &tasks.Task{ListID: list.ID, Text: "write report"})
+	recorded()
+
+	changes := []struct {
+		completed bool
+		text      string
+		want      []service.EventType
+	}{
+		{true, "write report", []service.EventType{service.TaskUpdated, service.TaskCompleted}},
+		{true, "write the report", []service.EventType{service.TaskUpdated}},
+		{false, "write the report", []service.EventType{service.TaskUpdated, service.TaskReopened}},
+	}
+
+	for _, change := range changes {
+		// the memory repo returns its own tasks, updates are made on copies
+		update := *task
+		update.Completed = change.completed
+		update.Text = change.text
+
+		_, err := store.UpdateTask(ctx, &update)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := recorded(); !reflect.DeepEqual(got, change.want) {
+			t.Errorf("completed %v, text %q: events = %v, want %v", change.completed, change.text, got, change.want)
+		}
+	}
+
+	// hooks listening only to completions may veto them
+	veto := errors.New("not today")
+	store.Events.Before(func(context.Context, *service.Event) error { return veto }, service.TaskCompleted)
+
+	update := *task
+	update.Completed = true
+	_, err := store.UpdateTask(ctx, &update)
+
+	if !errors.Is(err, veto) {
+		t.Errorf("err = %v, want the veto", err)
+	}
+}
+
+func TestDeleteLists(t *testing.T) {
+	ctx := context.Background()
+	store := service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+
+	for _, name := range []string{"Work", "Home"} {
+		list, _ := store.StoreList(ctx, &lists.List{Name: name})
+		store.StoreTask(ctx, &tasks.Task{ListID: list.ID, Text: "task of " + name})
+	}
+
+	recorded := record(store)
+
+	err := store.DeleteLists(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if remaining, _ := store.GetLists(ctx); len(remaining) != 0 {
+		t.Errorf("lists = %v, want none", remaining)
+	}
+
+	if remaining, _ := store.GetAllTasks(ctx); len(remaining) != 0 {
+		t.Errorf("tasks = %v, want none", remaining)
+	}
+
+	if got := recorded(); !reflect.DeepEqual(got, []service.EventType{service.ListsDeleted}) {
+		t.Errorf("events = %v, want only lists.deleted", got)
+	}
+}
@@ -0,0 +1,414 @@
+// Package fake contains an in-memory implementation of service.Interface
+// with predictable IDs and timestamps, meant for driving the frontends
+// without a real storage backend
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+var _ service.Interface = (*Service)(nil)
+
+type Service struct {
+	// Err is returned by every call when set
+	Err error
+
+	lists []*lists.List
+	tasks []*tasks.Task
+
+	nextID   int
+	now      time.Time
+	watchers []chan struct{}
+	l        *sync.Mutex
+}
+
+func New() *Service {
+	return &Service{
+		now: time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+		l:   &sync.Mutex{},
+	}
+}
+
+// id returns the next ID for the given prefix and advances the fake clock,
+// so items keep their insertion order when sorted by CreatedAt
+func (s *Service) id(prefix string) (string, time.Time) {
+	s.nextID++
+	s.now = s.now.Add(time.Second)
+
+	return fmt.Sprintf("%s-%d", prefix, s.nextID), s.now
+}
+
+func (s *Service) StoreTask(_ context.Context, task *tasks.Task) (*tasks.Task, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	for _, list := range s.lists {
+		if list.ID == task.ListID && list.Smart() {
+			return nil, service.ErrSmartList
+		}
+	}
+
+	// like the storage, IDs chosen by the caller are kept
+	id, now := s.id("task")
+
+	if task.ID == "" {
+		task.ID = id
+	}
+
+	task.CreatedAt = now
+	s.tasks = append(s.tasks, task)
+
+	return task, nil
+}
+
+func (s *Service) GetTask(_ context.Context, taskID string) (*tasks.Task, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	for _, task := range s.tasks {
+		if task.ID == taskID {
+			return task, nil
+		}
+	}
+
+	return nil, tasks.ErrNotFound
+}
+
+func (s *Service) GetTasks(_ context.Context, listID string) ([]*tasks.Task, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	for _, list := range s.lists {
+		if list.ID == listID && list.Smart() {
+			q, err := query.Parse(list.Query)
+
+			if err != nil {
+				return nil, err
+			}
+
+			matching := q.Bind(s.lists, s.now).Apply(s.tasks)
+			q.SortTasks(matching, s.lists)
+
+			return matching, nil
+		}
+	}
+
+	matching := []*tasks.Task{}
+	for _, task := range s.tasks {
+		if task.ListID == listID {
+			matching = append(matching, task)
+		}
+	}
+
+	return matching, nil
+}
+
+func (s *Service) GetTasksPage(ctx context.Context, listID string, offset, limit int) ([]*tasks.Task, int, error) {
+	all, err := s.GetTasks(ctx, listID)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start, end := service.Bounds(len(all), offset, limit)
+
+	return all[start:end], len(all), nil
+}
+
+func (s *Service) GetAllTasks(_ context.Context) ([]*tasks.Task, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	return append([]*tasks.Task{}, s.tasks...), nil
+}
+
+func (s *Service) QueryTasks(_ context.Context, q *query.Query) ([]*tasks.Task, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	matching := q.Bind(s.lists, s.now).Apply(s.tasks)
+	q.SortTasks(matching, s.lists)
+
+	return matching, nil
+}
+
+func (s *Service) UpdateTask(_ context.Context, task *tasks.Task) (*tasks.Task, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	for i := range s.tasks {
+		if s.tasks[i].ID == task.ID {
+			s.tasks[i] = task
+			return task, nil
+		}
+	}
+
+	return nil, tasks.ErrNotFound
+}
+
+func (s *Service) DeleteTask(_ context.Context, taskID string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+
+	s.tasks = filterTasks(s.tasks, func(task *tasks.Task) bool {
+		return task.ID != taskID
+	})
+
+	return nil
+}
+
+func (s *Service) UpdateTasks(ctx context.Context, updated []*tasks.Task) ([]*tasks.Task, error) {
+	err := s.ApplyTasks(ctx, updated, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+func (s *Service) DeleteTasksByID(ctx context.Context, ids []string) error {
+	return s.ApplyTasks(ctx, nil, ids)
+}
+
+// ApplyTasks replaces and deletes the tasks, nothing is changed if a task to replace is missing
+func (s *Service) ApplyTasks(_ context.Context, updated []*tasks.Task, ids []string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+
+	indexes := make([]int, len(updated))
+
+	for i, task := range updated {
+		indexes[i] = -1
+
+		for j := range s.tasks {
+			if s.tasks[j].ID == task.ID {
+				indexes[i] = j
+			}
+		}
+
+		if indexes[i] < 0 {
+			return tasks.ErrNotFound
+		}
+	}
+
+	for i, task := range updated {
+		s.tasks[indexes[i]] = task
+	}
+
+	deleted := map[string]bool{}
+
+	for _, id := range ids {
+		deleted[id] = true
+	}
+
+	s.tasks = filterTasks(s.tasks, func(task *tasks.Task) bool {
+		return !deleted[task.ID]
+	})
+
+	return nil
+}
+
+func (s *Service) DeleteTasks(_ context.Context, listID string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+
+	s.tasks = filterTasks(s.tasks, func(task *tasks.Task) bool {
+		return task.ListID != listID
+	})
+
+	return nil
+}
+
+func (s *Service) StoreList(_ context.Context, list *lists.List) (*lists.List, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	list.ID, list.CreatedAt = s.id("list")
+	s.lists = append(s.lists, list)
+
+	return list, nil
+}
+
+func (s *Service) GetList(_ context.Context, listID string) (*lists.List, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	for _, list := range s.lists {
+		if list.ID == listID {
+			return list, nil
+		}
+	}
+
+	return nil, lists.ErrNotFound
+}
+
+func (s *Service) GetLists(_ context.Context) ([]*lists.List, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	return append([]*lists.List{}, s.lists...), nil
+}
+
+func (s *Service) GetListsPage(ctx context.Context, offset, limit int) ([]*lists.List, int, error) {
+	all, err := s.GetLists(ctx)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start, end := service.Bounds(len(all), offset, limit)
+
+	return all[start:end], len(all), nil
+}
+
+func (s *Service) UpdateList(_ context.Context, list *lists.List) (*lists.List, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	for i := range s.lists {
+		if s.lists[i].ID == list.ID {
+			s.lists[i] = list
+			return list, nil
+		}
+	}
+
+	return nil, lists.ErrNotFound
+}
+
+func (s *Service) DeleteList(_ context.Context, listID string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+
+	s.tasks = filterTasks(s.tasks, func(task *tasks.Task) bool {
+		return task.ListID != listID
+	})
+
+	remaining := []*lists.List{}
+	for _, list := range s.lists {
+		if list.ID != listID {
+			remaining = append(remaining, list)
+		}
+	}
+	s.lists = remaining
+
+	return nil
+}
+
+func (s *Service) DeleteLists(_ context.Context) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return s.Err
+	}
+
+	s.lists = nil
+	s.tasks = nil
+
+	return nil
+}
+
+// Watch returns a channel receiving a value on every call of Notify
+func (s *Service) Watch(_ context.Context) (<-chan struct{}, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	changes := make(chan struct{}, 1)
+	s.watchers = append(s.watchers, changes)
+
+	return changes, nil
+}
+
+// Notify simulates a change made by another process
+func (s *Service) Notify() {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	for _, changes := range s.watchers {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func filterTasks(in []*tasks.Task, keep func(*tasks.Task) bool) []*tasks.Task {
+	out := []*tasks.Task{}
+
+	for _, task := range in {
+		if keep(task) {
+			out = append(out, task)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,114 @@
+package fake_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+	"github.com/julez-dev/go2todo/service/fake"
+)
+
+// TestContract runs the same calls against the fake and the storage, the frontends
+// are tested with the fake and must see the behaviour of the real service
+func TestContract(t *testing.T) {
+	implementations := map[string]func() service.Interface{
+		"fake": func() service.Interface {
+			return fake.New()
+		},
+		"storage": func() service.Interface {
+			return service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+		},
+	}
+
+	for name, create := range implementations {
+		t.Run(name, func(t *testing.T) {
+			testContract(t, create())
+		})
+	}
+}
+
+func testContract(t *testing.T, s service.Interface) {
+	ctx := context.Background()
+
+	work, err := s.StoreList(ctx, &lists.List{Name: "Work"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	home, _ := s.StoreList(ctx, &lists.List{Name: "Home"})
+
+	if all, _ := s.GetLists(ctx); len(all) != 2 || all[0].ID != work.ID || all[1].ID != home.ID {
+		t.Errorf("lists = %v, want Work and Home in creation order", all)
+	}
+
+	report, _ := s.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "write report"})
+	s.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "call Bob"})
+	s.StoreTask(ctx, &tasks.Task{ListID: home.ID, Text: "buy milk"})
+
+	if report.ID == "" || report.CreatedAt.IsZero() {
+		t.Errorf("stored task = %+v, want an ID and a creation time", report)
+	}
+
+	page, total, err := s.GetTasksPage(ctx, work.ID, 1, 5)
+
+	if err != nil || total != 2 || len(page) != 1 || page[0].Text != "call Bob" {
+		t.Errorf("page = %v of %d, %v, want call Bob of 2", page, total, err)
+	}
+
+	completed := *report
+	completed.Completed = true
+	_, err = s.UpdateTask(ctx, &completed)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if task, _ := s.GetTask(ctx, report.ID); !task.Completed {
+		t.Errorf("task = %+v, want it completed", task)
+	}
+
+	_, err = s.UpdateTask(ctx, &tasks.Task{ID: "missing", Text: "gone"})
+
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("UpdateTask of a missing task: err = %v, want tasks.ErrNotFound", err)
+	}
+
+	// tasks can't be added to smart lists
+	done, _ := s.StoreList(ctx, &lists.List{Name: "Done", Query: "done"})
+	_, err = s.StoreTask(ctx, &tasks.Task{ListID: done.ID, Text: "lost"})
+
+	if !errors.Is(err, service.ErrSmartList) {
+		t.Errorf("StoreTask into a smart list: err = %v, want service.ErrSmartList", err)
+	}
+
+	if matching, _ := s.GetTasks(ctx, done.ID); len(matching) != 1 || matching[0].ID != report.ID {
+		t.Errorf("smart list = %v, want write report", matching)
+	}
+
+	// deleting a list deletes its tasks
+	err = s.DeleteList(ctx, work.ID)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.GetTask(ctx, report.ID)
+
+	if !errors.Is(err, tasks.ErrNotFound) {
+		t.Errorf("GetTask after DeleteList: err = %v, want tasks.ErrNotFound", err)
+	}
+
+	_, err = s.GetList(ctx, work.ID)
+
+	if !errors.Is(err, lists.ErrNotFound) {
+		t.Errorf("GetList after DeleteList: err = %v, want lists.ErrNotFound", err)
+	}
+
+	if all, _ := s.GetAllTasks(ctx); len(all) != 1 || all[0].Text != "buy milk" {
+		t.Errorf("tasks = %v, want only buy milk", all)
+	}
+}
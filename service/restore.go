@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// RestoreList writes a list as it is, including its ID and creation time. An existing
+// list with the same ID is overwritten. Backends which assign their own IDs, like the
+// remote client, return the list with its new ID
+func (s *Storage) RestoreList(ctx context.Context, list *lists.List) (*lists.List, error) {
+	_, err := s.ListsRepo.GetList(ctx, list.ID)
+
+	if err != nil && !errors.Is(err, lists.ErrNotFound) {
+		return nil, err
+	}
+
+	exists := err == nil
+	event := newEvent(ListCreated)
+	event.List = list
+
+	if exists {
+		event.Type = ListUpdated
+	}
+
+	err = s.check(ctx, event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		list, err = s.ListsRepo.UpdateList(ctx, list)
+	} else {
+		list, err = s.ListsRepo.CreateList(ctx, list)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.changed(event)
+
+	return list, nil
+}
+
+// RestoreTask writes a task as it is, including its ID and timestamps. An existing
+// task with the same ID is overwritten
+func (s *Storage) RestoreTask(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
+	previous, err := s.TasksRepo.GetTask(ctx, task.ID)
+
+	if err != nil && !errors.Is(err, tasks.ErrNotFound) {
+		return nil, err
+	}
+
+	exists := err == nil
+	event := newEvent(TaskCreated)
+	event.Task = task
+
+	if exists {
+		event.Type = TaskUpdated
+		event.Previous = previous
+	}
+
+	err = s.check(ctx, event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if exists {
+		task, err = s.TasksRepo.UpdateTask(ctx, task)
+	} else {
+		task, err = s.TasksRepo.CreateTask(ctx, task)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.changed(event)
+
+	return task, nil
+}
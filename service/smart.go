@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// ErrSmartList is returned when a task is added to a smart list
+var ErrSmartList = errors.New("smart lists show the tasks of other lists, tasks can't be added to them")
+
+// DefaultSmartLists returns the smart lists offered to new users
+func DefaultSmartLists() []*lists.List {
+	return []*lists.List{
+		{Name: "Today", Query: "due:today !done"},
+		{Name: "Overdue", Query: "due<today !done sort:due"},
+		{Name: "Completed this week", Query: "completed:week sort:-completed"},
+		{Name: "All untagged", Query: "!has:tags !done group:list"},
+	}
+}
+
+// validateList checks the query of smart lists
+func validateList(list *lists.List) error {
+	if !list.Smart() {
+		return nil
+	}
+
+	_, err := query.Parse(list.Query)
+
+	if err != nil {
+		return fmt.Errorf("smart list %s: %w", list.Name, err)
+	}
+
+	return nil
+}
+
+// smartTasks returns the tasks matching the query of the list
+func (s *Storage) smartTasks(ctx context.Context, list *lists.List) ([]*tasks.Task, error) {
+	q, err := query.Parse(list.Query)
+
+	if err != nil {
+		return nil, fmt.Errorf("smart list %s: %w", list.Name, err)
+	}
+
+	return s.QueryTasks(ctx, q)
+}
+
+// checkTaskList makes sure the list of a task is no smart list, tasks of unknown
+// lists are accepted like they were before smart lists existed
+func (s *Storage) checkTaskList(ctx context.Context, listID string) error {
+	list, err := s.ListsRepo.GetList(ctx, listID)
+
+	if errors.Is(err, lists.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if list.Smart() {
+		return ErrSmartList
+	}
+
+	return nil
+}
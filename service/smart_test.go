@@ -0,0 +1,89 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+)
+
+func TestSmartLists(t *testing.T) {
+	ctx := context.Background()
+	store := service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+
+	work, _ := store.StoreList(ctx, &lists.List{Name: "Work"})
+	home, _ := store.StoreList(ctx, &lists.List{Name: "Home"})
+	report, _ := store.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "write report", Priority: 2})
+	store.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "call Bob", Priority: 1})
+	store.StoreTask(ctx, &tasks.Task{ListID: home.ID, Text: "buy milk"})
+
+	urgent, err := store.StoreList(ctx, &lists.List{Name: "Urgent", Query: "priority<=2 sort:priority"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the tasks of all lists are matched and sorted by the query
+	matching, err := store.GetTasks(ctx, urgent.ID)
+
+	if err != nil || len(matching) != 2 || matching[0].Text != "call Bob" || matching[1].Text != "write report" {
+		t.Errorf("GetTasks = %v, %v, want call Bob and write report", matching, err)
+	}
+
+	page, total, err := store.GetTasksPage(ctx, urgent.ID, 1, 1)
+
+	if err != nil || total != 2 || len(page) != 1 || page[0].ID != report.ID {
+		t.Errorf("GetTasksPage = %v of %d, %v, want write report of 2", page, total, err)
+	}
+
+	_, err = store.StoreTask(ctx, &tasks.Task{ListID: urgent.ID, Text: "lost"})
+
+	if !errors.Is(err, service.ErrSmartList) {
+		t.Errorf("StoreTask into a smart list: err = %v, want service.ErrSmartList", err)
+	}
+
+	moved := *report
+	moved.ListID = urgent.ID
+	_, err = store.UpdateTask(ctx, &moved)
+
+	if !errors.Is(err, service.ErrSmartList) {
+		t.Errorf("UpdateTask into a smart list: err = %v, want service.ErrSmartList", err)
+	}
+}
+
+func TestSmartListQueries(t *testing.T) {
+	ctx := context.Background()
+	store := service.NewStorage(tasks.NewInMemory(), lists.NewInMemory())
+
+	for _, list := range service.DefaultSmartLists() {
+		_, err := store.StoreList(ctx, list)
+
+		if err != nil {
+			t.Errorf("default smart list %s: %v", list.Name, err)
+		}
+	}
+
+	syntaxErr := &query.SyntaxError{}
+	_, err := store.StoreList(ctx, &lists.List{Name: "Broken", Query: "due:today OR"})
+
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("StoreList with an invalid query: err = %v, want a syntax error", err)
+	}
+
+	list, _ := store.StoreList(ctx, &lists.List{Name: "Open", Query: "!done"})
+	broken := *list
+	broken.Query = "(done"
+	_, err = store.UpdateList(ctx, &broken)
+
+	if !errors.As(err, &syntaxErr) {
+		t.Errorf("UpdateList with an invalid query: err = %v, want a syntax error", err)
+	}
+
+	if stored, _ := store.GetList(ctx, list.ID); stored.Query != "!done" {
+		t.Errorf("query = %q after the failed update, want !done", stored.Query)
+	}
+}
@@ -2,73 +2,368 @@ package service
 
 import (
 	"context"
+	"errors"
 	"sort"
 	"time"
 
+	"github.com/julez-dev/go2todo/query"
 	"github.com/julez-dev/go2todo/repo/lists"
 	"github.com/julez-dev/go2todo/repo/tasks"
 	uuid "github.com/satori/go.uuid"
 )
 
+// Interface is the application API the frontends talk to
 type Interface interface {
+	StoreTask(context.Context, *tasks.Task) (*tasks.Task, error)
+	GetTask(context.Context, string) (*tasks.Task, error)
+	GetTasks(context.Context, string) ([]*tasks.Task, error)
+	GetTasksPage(ctx context.Context, listID string, offset, limit int) ([]*tasks.Task, int, error)
+	GetAllTasks(context.Context) ([]*tasks.Task, error)
+	QueryTasks(context.Context, *query.Query) ([]*tasks.Task, error)
+	UpdateTask(context.Context, *tasks.Task) (*tasks.Task, error)
+	UpdateTasks(context.Context, []*tasks.Task) ([]*tasks.Task, error)
+	DeleteTask(context.Context, string) error
+	DeleteTasksByID(context.Context, []string) error
+	ApplyTasks(ctx context.Context, updated []*tasks.Task, deleted []string) error
+	DeleteTasks(context.Context, string) error
+
+	StoreList(context.Context, *lists.List) (*lists.List, error)
+	GetList(context.Context, string) (*lists.List, error)
+	GetLists(context.Context) ([]*lists.List, error)
+	GetListsPage(ctx context.Context, offset, limit int) ([]*lists.List, int, error)
+	UpdateList(context.Context, *lists.List) (*lists.List, error)
+	DeleteList(context.Context, string) error
+	DeleteLists(context.Context) error
+
+	Watch(context.Context) (<-chan struct{}, error)
+}
+
+// Remote is implemented by repos passing the changes on to another go2todo server.
+// The server assigns the IDs, deletes the tasks of deleted lists and runs the hooks,
+// so a Storage of remote repos leaves that to the server and publishes no events
+type Remote interface {
+	// Server returns the URL of the server
+	Server() string
 }
 
 type Storage struct {
 	TasksRepo tasks.Interface
 	ListsRepo lists.Interface
+	// Events runs the hooks registered for the changes made through the Storage
+	Events *EventBus
+
+	changes *notifier
+	remote  bool
 }
 
 func NewStorage(tasksRepo tasks.Interface, listsRepo lists.Interface) *Storage {
+	_, remote := tasksRepo.(Remote)
+
 	return &Storage{
 		TasksRepo: tasksRepo,
 		ListsRepo: listsRepo,
+		Events:    NewEventBus(),
+		changes:   newNotifier(),
+		remote:    remote,
 	}
 }
 
+// StoreTask creates the task, a new ID is only assigned if it has none. Frontends
+// which let clients choose IDs, like CalDAV, have to make sure they are unique
 func (s *Storage) StoreTask(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
-	uuid := uuid.NewV4().String()
-	task.ID = uuid
+	err := s.checkTaskList(ctx, task.ListID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if task.ID == "" {
+		task.ID = uuid.NewV4().String()
+	}
+
 	task.CreatedAt = time.Now()
 
-	return s.TasksRepo.CreateTask(ctx, task)
+	if task.Completed && task.CompletedAt == nil {
+		task.CompletedAt = &task.CreatedAt
+	}
+
+	event := newEvent(TaskCreated)
+	event.Task = task
+
+	err = s.check(ctx, event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	task, err = s.TasksRepo.CreateTask(ctx, task)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.changed(event)
+
+	return task, nil
 }
 
 func (s *Storage) GetTask(ctx context.Context, taskID string) (*tasks.Task, error) {
 	return s.TasksRepo.GetTask(ctx, taskID)
 }
 
+// GetTasks returns the tasks of the list, for smart lists the tasks matching its query
 func (s *Storage) GetTasks(ctx context.Context, listID string) ([]*tasks.Task, error) {
+	list, err := s.ListsRepo.GetList(ctx, listID)
+
+	if err == nil && list.Smart() {
+		return s.smartTasks(ctx, list)
+	}
+
 	tasks, err := s.TasksRepo.GetTasks(ctx, listID)
 
 	if err != nil {
 		return nil, err
 	}
 
-	sort.Slice(tasks, func(i, j int) bool {
+	sort.SliceStable(tasks, func(i, j int) bool {
 		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
 	})
 
 	return tasks, nil
 }
 
+// GetTasksPage returns limit tasks of the list starting at offset, in the order of
+// GetTasks, and the number of all tasks of the list. Repos implementing tasks.Pager
+// only read the page, smart lists are always evaluated completely
+func (s *Storage) GetTasksPage(ctx context.Context, listID string, offset, limit int) ([]*tasks.Task, int, error) {
+	pager, ok := s.TasksRepo.(tasks.Pager)
+
+	if list, err := s.ListsRepo.GetList(ctx, listID); !ok || (err == nil && list.Smart()) {
+		all, err := s.GetTasks(ctx, listID)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		start, end := Bounds(len(all), offset, limit)
+
+		return all[start:end], len(all), nil
+	}
+
+	page, total, err := pager.GetTasksPage(ctx, listID, offset, limit)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.SliceStable(page, func(i, j int) bool {
+		return page[i].CreatedAt.Before(page[j].CreatedAt)
+	})
+
+	return page, total, nil
+}
+
+func (s *Storage) GetAllTasks(ctx context.Context) ([]*tasks.Task, error) {
+	tasks, err := s.TasksRepo.GetAllTasks(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+
+	return tasks, nil
+}
+
+// QueryTasks returns the tasks matching the query in its order. Repos implementing
+// tasks.Querier evaluate the query themselves, all others are filtered in memory
+func (s *Storage) QueryTasks(ctx context.Context, q *query.Query) ([]*tasks.Task, error) {
+	lists, err := s.GetLists(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	filter := q.Bind(lists, time.Now())
+	var matching []*tasks.Task
+
+	if querier, ok := s.TasksRepo.(tasks.Querier); ok {
+		matching, err = querier.FindTasks(ctx, filter)
+	} else {
+		matching, err = s.TasksRepo.GetAllTasks(ctx)
+		matching = filter.Apply(matching)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the creation order is the default, like for GetAllTasks
+	sort.SliceStable(matching, func(i, j int) bool {
+		return matching[i].CreatedAt.Before(matching[j].CreatedAt)
+	})
+
+	q.SortTasks(matching, lists)
+
+	return matching, nil
+}
+
 func (s *Storage) UpdateTask(ctx context.Context, task *tasks.Task) (*tasks.Task, error) {
-	return s.TasksRepo.UpdateTask(ctx, task)
+	event, err := s.prepareUpdate(ctx, task)
+
+	if err != nil {
+		return nil, err
+	}
+
+	task, err = s.TasksRepo.UpdateTask(ctx, task)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.changed(event)
+
+	return task, nil
+}
+
+// prepareUpdate validates the update of the task and asks the before hooks about it
+func (s *Storage) prepareUpdate(ctx context.Context, task *tasks.Task) (*Event, error) {
+	previous, err := s.TasksRepo.GetTask(ctx, task.ID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if task.ListID != previous.ListID {
+		err := s.checkTaskList(ctx, task.ListID)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the backends write the creation time as well, updates without one keep it
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = previous.CreatedAt
+	}
+
+	if task.Completed && task.CompletedAt == nil {
+		now := time.Now()
+		task.CompletedAt = &now
+	}
+
+	if !task.Completed {
+		task.CompletedAt = nil
+	}
+
+	event := newEvent(TaskUpdated)
+	event.Task = task
+	event.Previous = previous
+
+	err = s.check(ctx, event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
 }
 
 func (s *Storage) DeleteTask(ctx context.Context, taskID string) error {
-	return s.TasksRepo.DeleteTask(ctx, taskID)
+	event, err := s.prepareDelete(ctx, taskID)
+
+	if err != nil || event == nil {
+		return err
+	}
+
+	err = s.TasksRepo.DeleteTask(ctx, taskID)
+
+	if err != nil {
+		return err
+	}
+
+	s.changed(event)
+
+	return nil
+}
+
+// prepareDelete asks the before hooks about deleting the task. The event is nil if
+// the task does not exist, deleting is idempotent so there is nothing to report
+func (s *Storage) prepareDelete(ctx context.Context, taskID string) (*Event, error) {
+	task, err := s.TasksRepo.GetTask(ctx, taskID)
+
+	if errors.Is(err, tasks.ErrNotFound) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	event := newEvent(TaskDeleted)
+	event.Task = task
+
+	err = s.check(ctx, event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return event, nil
 }
 
 func (s *Storage) DeleteTasks(ctx context.Context, listID string) error {
-	return s.TasksRepo.DeleteTasks(ctx, listID)
+	event := newEvent(TasksDeleted)
+	event.ListID = listID
+
+	err := s.check(ctx, event)
+
+	if err != nil {
+		return err
+	}
+
+	err = s.TasksRepo.DeleteTasks(ctx, listID)
+
+	if err != nil {
+		return err
+	}
+
+	s.changed(event)
+
+	return nil
 }
 
 func (s *Storage) StoreList(ctx context.Context, list *lists.List) (*lists.List, error) {
+	err := validateList(list)
+
+	if err != nil {
+		return nil, err
+	}
+
 	uuid := uuid.NewV4().String()
 	list.ID = uuid
 	list.CreatedAt = time.Now()
 
-	return s.ListsRepo.CreateList(ctx, list)
+	event := newEvent(ListCreated)
+	event.List = list
+
+	err = s.check(ctx, event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	list, err = s.ListsRepo.CreateList(ctx, list)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.changed(event)
+
+	return list, nil
 }
 
 func (s *Storage) GetList(ctx context.Context, listID string) (*lists.List, error) {
@@ -82,41 +377,198 @@ func (s *Storage) GetLists(ctx context.Context) ([]*lists.List, error) {
 		return nil, err
 	}
 
-	sort.Slice(lists, func(i, j int) bool {
+	sort.SliceStable(lists, func(i, j int) bool {
 		return lists[i].CreatedAt.Before(lists[j].CreatedAt)
 	})
 
 	return lists, nil
 }
 
+// GetListsPage returns limit lists starting at offset in creation order and the number of
+// all lists. Repos implementing lists.Pager only read the page
+func (s *Storage) GetListsPage(ctx context.Context, offset, limit int) ([]*lists.List, int, error) {
+	pager, ok := s.ListsRepo.(lists.Pager)
+
+	if !ok {
+		all, err := s.GetLists(ctx)
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		start, end := Bounds(len(all), offset, limit)
+
+		return all[start:end], len(all), nil
+	}
+
+	page, total, err := pager.GetListsPage(ctx, offset, limit)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sort.SliceStable(page, func(i, j int) bool {
+		return page[i].CreatedAt.Before(page[j].CreatedAt)
+	})
+
+	return page, total, nil
+}
+
 func (s *Storage) UpdateList(ctx context.Context, list *lists.List) (*lists.List, error) {
-	return s.ListsRepo.UpdateList(ctx, list)
+	err := validateList(list)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the backends write the creation time as well, updates without one keep it
+	if list.CreatedAt.IsZero() {
+		previous, err := s.ListsRepo.GetList(ctx, list.ID)
+
+		if err != nil {
+			return nil, err
+		}
+
+		list.CreatedAt = previous.CreatedAt
+	}
+
+	event := newEvent(ListUpdated)
+	event.List = list
+
+	err = s.check(ctx, event)
+
+	if err != nil {
+		return nil, err
+	}
+
+	list, err = s.ListsRepo.UpdateList(ctx, list)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.changed(event)
+
+	return list, nil
 }
 
 func (s *Storage) DeleteList(ctx context.Context, listID string) error {
-	err := s.TasksRepo.DeleteTasks(ctx, listID)
+	list, err := s.ListsRepo.GetList(ctx, listID)
+
+	if errors.Is(err, lists.ErrNotFound) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	event := newEvent(ListDeleted)
+	event.List = list
+
+	err = s.check(ctx, event)
+
+	if err != nil {
+		return err
+	}
+
+	if !s.remote {
+		err = s.TasksRepo.DeleteTasks(ctx, listID)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.ListsRepo.DeleteList(ctx, listID)
 
 	if err != nil {
 		return err
 	}
 
-	return s.ListsRepo.DeleteList(ctx, listID)
+	s.changed(event)
+
+	return nil
 }
 
 func (s *Storage) DeleteLists(ctx context.Context) error {
-	lists, err := s.GetLists(ctx)
+	event := newEvent(ListsDeleted)
+
+	err := s.check(ctx, event)
 
 	if err != nil {
 		return err
 	}
 
-	for _, list := range lists {
-		err = s.DeleteTasks(ctx, list.ID)
+	if !s.remote {
+		lists, err := s.GetLists(ctx)
 
 		if err != nil {
 			return err
 		}
+
+		for _, list := range lists {
+			err = s.TasksRepo.DeleteTasks(ctx, list.ID)
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = s.ListsRepo.DeleteLists(ctx)
+
+	if err != nil {
+		return err
 	}
 
+	s.changed(event)
+
 	return nil
 }
+
+// Bounds returns the slice bounds of the page starting at offset with up to limit
+// items of a collection of the given length, for the frontends paging in memory
+func Bounds(length, offset, limit int) (int, int) {
+	if offset > length {
+		offset = length
+	}
+
+	end := offset + limit
+
+	if end > length {
+		end = length
+	}
+
+	return offset, end
+}
+
+// check asks the before hooks about the change, the server asks its own hooks for remote repos
+func (s *Storage) check(ctx context.Context, event *Event) error {
+	if s.remote {
+		return nil
+	}
+
+	err := s.Events.check(ctx, event)
+
+	if transition := event.transition(); err == nil && transition != nil {
+		err = s.Events.check(ctx, transition)
+	}
+
+	return err
+}
+
+// changed notifies the watchers and after hooks about a successful change
+func (s *Storage) changed(event *Event) {
+	s.changes.notify()
+
+	if s.remote {
+		return
+	}
+
+	s.Events.publish(event)
+
+	if transition := event.transition(); transition != nil {
+		s.Events.publish(transition)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/julez-dev/go2todo/repo"
+)
+
+// notifier signals the watchers of a Storage about the changes made through it
+type notifier struct {
+	subscribers map[chan struct{}]struct{}
+	l           *sync.Mutex
+}
+
+func newNotifier() *notifier {
+	return &notifier{
+		subscribers: make(map[chan struct{}]struct{}),
+		l:           &sync.Mutex{},
+	}
+}
+
+func (n *notifier) subscribe(ctx context.Context) <-chan struct{} {
+	n.l.Lock()
+	defer n.l.Unlock()
+
+	changes := make(chan struct{}, 1)
+	n.subscribers[changes] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+
+		n.l.Lock()
+		defer n.l.Unlock()
+
+		delete(n.subscribers, changes)
+		close(changes)
+	}()
+
+	return changes
+}
+
+func (n *notifier) notify() {
+	n.l.Lock()
+	defer n.l.Unlock()
+
+	for changes := range n.subscribers {
+		select {
+		case changes <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Watch returns a channel receiving a value whenever the stored lists or tasks
+// may have changed, either through this Storage or, if the repos support it,
+// by another process. Multiple changes in a row may be reported only once
+func (s *Storage) Watch(ctx context.Context) (<-chan struct{}, error) {
+	sources := []<-chan struct{}{s.changes.subscribe(ctx)}
+
+	var repos []interface{} = []interface{}{s.TasksRepo}
+
+	// backends implementing both interfaces only need to be watched once
+	if interface{}(s.ListsRepo) != interface{}(s.TasksRepo) {
+		repos = append(repos, s.ListsRepo)
+	}
+
+	for _, r := range repos {
+		watcher, ok := r.(repo.Watcher)
+
+		if !ok {
+			continue
+		}
+
+		changes, err := watcher.Watch(ctx)
+
+		if err == repo.ErrWatchNotSupported {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, changes)
+	}
+
+	merged := make(chan struct{}, 1)
+	wg := &sync.WaitGroup{}
+
+	for _, source := range sources {
+		wg.Add(1)
+
+		go func(source <-chan struct{}) {
+			defer wg.Done()
+
+			for range source {
+				select {
+				case merged <- struct{}{}:
+				default:
+				}
+			}
+		}(source)
+	}
+
+	go func() {
+		wg.Wait()
+		close(merged)
+	}()
+
+	return merged, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/service"
+)
+
+// smartLists manages the smart lists, lists showing the tasks matching a stored query
+func smartLists(args []string) {
+	flags := flag.NewFlagSet("smartlist", flag.ExitOnError)
+	flags.Usage = func() {
+		out := flags.Output()
+		fmt.Fprintln(out, "usage: go2todo smartlist ls")
+		fmt.Fprintln(out, "       go2todo smartlist add NAME 'due:today !done'")
+		fmt.Fprintln(out, "       go2todo smartlist rm NAME")
+		fmt.Fprintln(out, "       go2todo smartlist defaults")
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	store := chooseStore()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	registerHooks(ctx, store, loadConfig(), log.Default())
+
+	all, err := store.GetLists(ctx)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	switch command := flags.Arg(0); {
+	case command == "ls" && flags.NArg() == 1:
+		for _, list := range all {
+			if list.Smart() {
+				fmt.Printf("%s\t%s\n", list.Name, list.Query)
+			}
+		}
+
+	case command == "add" && flags.NArg() >= 3:
+		q, err := query.Parse(strings.Join(flags.Args()[2:], " "))
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		addSmartList(ctx, store, all, &lists.List{Name: flags.Arg(1), Query: q.String()})
+
+	case command == "rm" && flags.NArg() == 2:
+		list := findSmartList(all, flags.Arg(1))
+
+		if list == nil {
+			log.Fatalf("there is no smart list %s\n", flags.Arg(1))
+		}
+
+		err := store.DeleteList(ctx, list.ID)
+
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+	case command == "defaults" && flags.NArg() == 1:
+		for _, list := range service.DefaultSmartLists() {
+			if findSmartList(all, list.Name) == nil {
+				addSmartList(ctx, store, all, list)
+			}
+		}
+
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	store.Events.Wait()
+}
+
+func addSmartList(ctx context.Context, store service.Interface, all []*lists.List, list *lists.List) {
+	if findSmartList(all, list.Name) != nil {
+		log.Fatalf("the smart list %s already exists\n", list.Name)
+	}
+
+	_, err := store.StoreList(ctx, list)
+
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	fmt.Printf("added %s\t%s\n", list.Name, list.Query)
+}
+
+// findSmartList returns the smart list with the name, ignoring case
+func findSmartList(all []*lists.List, name string) *lists.List {
+	for _, list := range all {
+		if list.Smart() && strings.EqualFold(list.Name, name) {
+			return list
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/julez-dev/go2todo/config"
+)
+
+// Actions whose confirmation can be turned off in the configuration file
+const (
+	ConfirmDeleteList  = "delete_list"
+	ConfirmDeleteTask  = "delete_task"
+	ConfirmDeleteTasks = "delete_tasks"
+)
+
+var confirmActions = []string{ConfirmDeleteList, ConfirmDeleteTask, ConfirmDeleteTasks}
+
+// confirmation is a modal question guarding a destructive action. It is sent as message
+// when the question needs data from the storage, like the number of tasks of a list
+type confirmation struct {
+	question string
+	// yes runs the action once it is confirmed
+	yes tea.Cmd
+}
+
+// confirms returns the actions to confirm from the configuration, all of them by default
+func confirms(cfg config.UI) (map[string]bool, error) {
+	enabled := map[string]bool{}
+
+	for _, action := range confirmActions {
+		enabled[action] = true
+	}
+
+	for action, on := range cfg.Confirm {
+		if _, ok := enabled[action]; !ok {
+			return nil, fmt.Errorf("config: unknown confirm action %q, expected one of %v", action, confirmActions)
+		}
+
+		enabled[action] = on
+	}
+
+	return enabled, nil
+}
+
+// ask shows the question before yes is run, or runs it right away if the action is not confirmed
+func (m *model) ask(action, question string, yes tea.Cmd) tea.Cmd {
+	if !m.confirms[action] {
+		return yes
+	}
+
+	m.confirmation = &confirmation{question: question, yes: yes}
+
+	return nil
+}
+
+// askDeleteList asks before the list and its tasks are deleted, smart lists own no tasks
+func (m *model) askDeleteList() tea.Cmd {
+	if m.cursorLists >= len(m.lists) {
+		return nil
+	}
+
+	list := m.lists[m.cursorLists]
+
+	if !m.confirms[ConfirmDeleteList] || list.Smart() {
+		return m.ask(ConfirmDeleteList, "delete smart list "+list.Name+"?", m.deleteList(list))
+	}
+
+	return func() tea.Msg {
+		_, total, err := m.storage.GetTasksPage(context.TODO(), list.ID, 0, 1)
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return &confirmation{
+			question: fmt.Sprintf("delete list %s and %s?", list.Name, plural(total, "task")),
+			yes:      m.deleteList(list),
+		}
+	}
+}
+
+// askDeleteTasks asks before the targets of the bulk actions are deleted
+func (m *model) askDeleteTasks() tea.Cmd {
+	targets := m.targets()
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	if len(targets) == 1 && !m.selecting() {
+		return m.ask(ConfirmDeleteTask, "delete task "+targets[0].Text+"?", m.deleteTask(targets[0]))
+	}
+
+	return m.ask(ConfirmDeleteTasks, "delete "+plural(len(targets), "task")+"?", m.bulkDelete())
+}
+
+// updateConfirmation handles the keys while the question is shown, only y runs the action
+func (m *model) updateConfirmation(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "y", "Y":
+		yes := m.confirmation.yes
+		m.confirmation = nil
+
+		return m, yes
+
+	case "n", "N", tea.KeyEsc.String():
+		m.confirmation = nil
+	}
+
+	return m, nil
+}
+
+// confirmationView renders the question as box below the items
+func (m *model) confirmationView() string {
+	return "\n" + m.theme.modal.Render(m.confirmation.question+"  y/n")
+}
+
+func plural(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+
+	return fmt.Sprintf("%d %ss", count, noun)
+}
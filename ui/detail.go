@@ -0,0 +1,229 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textarea"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// notesWidth is the width of the notes while the size of the terminal is unknown
+const notesWidth = 80
+
+type editTaskResponse struct {
+	task *tasks.Task
+}
+
+func newNotesInput() textarea.Model {
+	input := textarea.New()
+	input.Placeholder = "Notes as Markdown"
+	input.ShowLineNumbers = false
+	input.CharLimit = 0
+
+	return input
+}
+
+// detailTask returns the task shown on the detail page, nil if it vanished
+func (m *model) detailTask() *tasks.Task {
+	if m.cursorTasks >= len(m.tasks) {
+		return nil
+	}
+
+	return m.tasks[m.cursorTasks]
+}
+
+// openDetail shows the selected task with its notes
+func (m *model) openDetail() {
+	if m.detailTask() == nil || !m.matches(m.cursorTasks) {
+		return
+	}
+
+	m.page = detailPage
+	m.offsetDetail = 0
+}
+
+// closeDetail goes back to the tasks of the list, keeping the query and the loaded tasks
+func (m *model) closeDetail() {
+	m.page = viewTasksPage
+	m.mode = viewMode
+	m.notes.Blur()
+}
+
+// editNotes opens the editor with the notes of the shown task
+func (m *model) editNotes() tea.Cmd {
+	task := m.detailTask()
+
+	if task == nil {
+		return nil
+	}
+
+	m.mode = notesMode
+	m.notes.SetValue(task.Notes)
+	m.resizeNotes()
+
+	return m.notes.Focus()
+}
+
+// resizeNotes fits the editor below the header of the detail page
+func (m *model) resizeNotes() {
+	m.notes.SetWidth(m.wrapWidth())
+
+	if m.height == 0 {
+		m.notes.SetHeight(10)
+		return
+	}
+
+	// the blank line and the help below the editor
+	height := m.height - strings.Count(m.header(), "\n") - 3
+
+	if height < 3 {
+		height = 3
+	}
+
+	m.notes.SetHeight(height)
+}
+
+func (m *model) saveNotes() tea.Cmd {
+	task := m.detailTask()
+
+	if task == nil {
+		return nil
+	}
+
+	// the storage gets a copy, so the shown task stays untouched if the update fails
+	edited := *task
+	edited.Notes = strings.TrimRight(m.notes.Value(), "\n")
+
+	return func() tea.Msg {
+		saved, err := m.storage.UpdateTask(context.Background(), &edited)
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return &editTaskResponse{task: saved}
+	}
+}
+
+// updateNotes handles the keys while the notes are edited
+func (m *model) updateNotes(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "ctrl+s":
+		m.mode = viewMode
+		m.notes.Blur()
+
+		return m, m.saveNotes()
+
+	case tea.KeyEsc.String():
+		m.mode = viewMode
+		m.notes.Blur()
+
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.notes, cmd = m.notes.Update(msg)
+
+	return m, cmd
+}
+
+// scrollNotes moves the notes of the detail page by delta lines
+func (m *model) scrollNotes(delta int) {
+	m.offsetDetail += delta
+
+	if m.offsetDetail < 0 {
+		m.offsetDetail = 0
+	}
+}
+
+// wrapWidth returns the width the notes are wrapped at
+func (m *model) wrapWidth() int {
+	if m.width == 0 {
+		return notesWidth
+	}
+
+	return m.width - 2
+}
+
+// renderNotes renders the notes as Markdown, the result is kept until the notes or the width change
+func (m *model) renderNotes(notes string) string {
+	width := m.wrapWidth()
+
+	if m.rendered != "" && m.renderedNotes == notes && m.renderedWidth == width {
+		return m.rendered
+	}
+
+	renderer, err := glamour.NewTermRenderer(glamour.WithStandardStyle(m.theme.markdown), glamour.WithWordWrap(width))
+
+	if err != nil {
+		return notes
+	}
+
+	rendered, err := renderer.Render(notes)
+
+	if err != nil {
+		return notes
+	}
+
+	m.rendered = strings.Trim(rendered, "\n")
+	m.renderedNotes = notes
+	m.renderedWidth = width
+
+	return m.rendered
+}
+
+// detailHeader describes the shown task above its notes
+func (m *model) detailHeader() string {
+	task := m.detailTask()
+
+	if task == nil {
+		return ""
+	}
+
+	s := &strings.Builder{}
+	s.WriteString("  " + task.Text + " [" + m.theme.mark(task) + "]\n")
+
+	details := []string{m.theme.muted.Render("list " + m.listName(task.ListID))}
+
+	if task.Priority > 0 {
+		details = append(details, m.theme.priority(task.Priority).Render(fmt.Sprintf("priority %d", task.Priority)))
+	}
+
+	if task.Due != nil {
+		details = append(details, m.theme.due(task))
+	}
+
+	if len(task.Tags) > 0 {
+		details = append(details, m.theme.muted.Render("#"+strings.Join(task.Tags, " #")))
+	}
+
+	if task.CompletedAt != nil {
+		details = append(details, m.theme.muted.Render("completed "+task.CompletedAt.Format("2006-01-02")))
+	}
+
+	s.WriteString("  " + strings.Join(details, "  ") + "\n\n")
+
+	return s.String()
+}
+
+// detailBody renders the notes of the shown task, the editor replaces them while editing
+func (m *model) detailBody() []string {
+	task := m.detailTask()
+
+	if task == nil || m.mode == notesMode {
+		return []string{}
+	}
+
+	if task.Notes == "" {
+		return []string{m.theme.muted.Render("  No notes, press " + hint(m.keys.Edit) + " to write some")}
+	}
+
+	return strings.Split(m.renderNotes(task.Notes), "\n")
+}
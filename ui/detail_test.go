@@ -0,0 +1,61 @@
+package ui_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+func TestDetail(t *testing.T) {
+	storage, work := seed(t)
+	ctx := context.Background()
+	mail, _ := storage.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "write mail", Notes: "# Agenda\n\n- budget\n- hiring"})
+	d := newDriver(t, storage, config.UI{})
+
+	// the notes are rendered as Markdown
+	d.press("enter", "G", "enter")
+	d.assertView("write mail [-]", "list Work", "# Agenda", "• budget", "• hiring")
+	d.assertNotView("- budget")
+
+	// esc drops the edits
+	d.press("e", "x", "esc")
+	d.assertView("• hiring")
+
+	if stored, _ := storage.GetTask(ctx, mail.ID); stored.Notes != mail.Notes {
+		t.Errorf("notes = %q after esc, want them unchanged", stored.Notes)
+	}
+
+	d.press("e")
+	d.assertView("ctrl+s to save, esc to cancel")
+
+	for _, r := range " and travel" {
+		d.press(string(r))
+	}
+
+	d.press("ctrl+s")
+	d.assertView("• hiring and travel")
+
+	if stored, _ := storage.GetTask(ctx, mail.ID); stored.Notes != "# Agenda\n\n- budget\n- hiring and travel" {
+		t.Errorf("notes = %q, want the edit saved", stored.Notes)
+	}
+
+	d.press("esc")
+	d.assertView("Tasks for Work", "> write mail")
+}
+
+func TestDetailSaveError(t *testing.T) {
+	storage, _ := seed(t)
+	d := newDriver(t, storage, config.UI{})
+
+	d.press("enter", "enter")
+	d.assertView("write report [-]", "No notes, press e to write some")
+
+	// the shown task keeps its notes if they can't be saved
+	d.press("e", "x")
+	storage.Err = errors.New("disk on fire")
+	d.press("ctrl+s")
+	d.assertView("Current error: disk on fire", "No notes, press e to write some")
+}
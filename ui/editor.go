@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/julez-dev/go2todo/editor"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+type editorResponse struct {
+	path string
+	task *tasks.Task
+	err  error
+}
+
+// openEditor suspends the program while the selected task is edited in the editor of the user
+func (m *model) openEditor() tea.Cmd {
+	task := m.detailTask()
+
+	if task == nil || !m.matches(m.cursorTasks) {
+		return nil
+	}
+
+	path, err := editor.WriteTemp(task)
+
+	if err != nil {
+		m.currentError = err
+		return nil
+	}
+
+	return tea.ExecProcess(editor.Command(path), func(err error) tea.Msg {
+		return &editorResponse{path: path, task: task, err: err}
+	})
+}
+
+// applyEdit stores the task as it was edited, invalid files are reported as error. The file is
+// only removed once the task was saved, otherwise the error tells where the edits are kept
+func (m *model) applyEdit(msg *editorResponse) tea.Cmd {
+	return func() tea.Msg {
+		if msg.err != nil {
+			return &errorResponse{err: fmt.Errorf("editor failed: %w, the task is kept in %s", msg.err, msg.path)}
+		}
+
+		edited, err := editor.ReadFile(msg.path, msg.task)
+
+		if err != nil {
+			return &errorResponse{err: fmt.Errorf("%w, the edited task is kept in %s", err, msg.path)}
+		}
+
+		saved, err := m.storage.UpdateTask(context.Background(), edited)
+
+		if err != nil {
+			return &errorResponse{err: fmt.Errorf("%w, the edited task is kept in %s", err, msg.path)}
+		}
+
+		os.Remove(msg.path)
+
+		return &editTaskResponse{task: saved}
+	}
+}
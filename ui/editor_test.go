@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/editor"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service/fake"
+)
+
+// edit writes the task to a temporary file and replaces its content like the editor would
+func edit(t *testing.T, task *tasks.Task, content string) string {
+	t.Helper()
+
+	path, err := editor.WriteTemp(task)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { os.Remove(path) })
+
+	err = os.WriteFile(path, []byte(content), 0o600)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestApplyEdit(t *testing.T) {
+	storage := fake.New()
+	m, err := New(storage, config.UI{Theme: ThemeNoColor})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	task, _ := storage.StoreTask(context.Background(), &tasks.Task{ListID: "work", Text: "write report"})
+	m.tasks = []*tasks.Task{task}
+
+	// nothing is opened without a task
+	m.cursorTasks = 1
+
+	if m.openEditor() != nil {
+		t.Error("openEditor returned a command without a selected task")
+	}
+
+	m.cursorTasks = 0
+
+	path := edit(t, task, "---\ntext: write the report\ntags: [q1]\n---\n\nQuarterly numbers\n")
+	_, cmd := m.Update(&editorResponse{path: path, task: task})
+	m.Update(cmd())
+
+	if m.tasks[0].Text != "write the report" || m.tasks[0].Notes != "Quarterly numbers" {
+		t.Errorf("task = %+v, want the edited text and notes", m.tasks[0])
+	}
+
+	if stored, _ := storage.GetTask(context.Background(), task.ID); stored.Text != "write the report" || len(stored.Tags) != 1 {
+		t.Errorf("stored task = %+v, want the edits saved", stored)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the file of the saved task was not removed: %v", err)
+	}
+}
+
+func TestApplyEditKeepsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		editErr error
+		saveErr error
+		want    string
+	}{
+		{"editor", "---\ntext: call Bob\n---\n", errors.New("exit status 1"), nil, "editor failed: exit status 1"},
+		{"invalid", "call Bob\n", nil, nil, editor.ErrNoFrontMatter.Error()},
+		{"storage", "---\ntext: call Bob\n---\n", nil, errors.New("disk on fire"), "disk on fire"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			storage := fake.New()
+			m, err := New(storage, config.UI{Theme: ThemeNoColor})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			task, _ := storage.StoreTask(context.Background(), &tasks.Task{ListID: "work", Text: "write report"})
+			path := edit(t, task, test.content)
+			storage.Err = test.saveErr
+
+			msg, ok := m.applyEdit(&editorResponse{path: path, task: task, err: test.editErr})().(*errorResponse)
+
+			// the error names the file, so the edits can be recovered
+			if !ok || !strings.Contains(msg.Error(), test.want) || !strings.Contains(msg.Error(), path) {
+				t.Errorf("applyEdit = %v, want an error with %q and the path", msg, test.want)
+			}
+
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("the edited file was removed: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"context"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+func newFilterInput() textinput.Model {
+	input := textinput.NewModel()
+	input.Focus()
+	input.Prompt = "filter: "
+	input.Placeholder = "list:Work tag:backend !done due<7d sort:priority"
+	input.Width = 60
+
+	return input
+}
+
+// queryTasks runs the filter, it replaces getAllTasks while the filter is set
+func (m *model) queryTasks(filter *query.Query) tea.Cmd {
+	return func() tea.Msg {
+		tasks, err := m.storage.QueryTasks(context.TODO(), filter)
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return &getAllTasksResponse{tasks: tasks}
+	}
+}
+
+// setAllTasks shows the tasks on the search page, split into the groups of the filter
+func (m *model) setAllTasks(all []*tasks.Task) {
+	m.allTasks = all
+	m.groups = nil
+
+	if m.filter == nil || m.filter.Group == "" {
+		return
+	}
+
+	// tasks with several tags show up in the group of every tag
+	m.allTasks = nil
+
+	for _, group := range m.filter.GroupTasks(all, m.lists) {
+		for _, task := range group.Tasks {
+			m.allTasks = append(m.allTasks, task)
+			m.groups = append(m.groups, group.Name)
+		}
+	}
+}
+
+// updateFilter handles the keys while the filter is typed
+func (m *model) updateFilter(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "enter":
+		q, err := query.Parse(m.filterInput.Value())
+
+		if err != nil {
+			m.currentError = err
+			return m, nil
+		}
+
+		m.mode = viewMode
+		m.openPage(searchPage)
+		m.filter = q
+		m.cursorSearch = 0
+
+		return m, tea.Batch(m.getLists, m.queryTasks(q))
+
+	case tea.KeyEsc.String():
+		m.mode = viewMode
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+
+	return m, cmd
+}
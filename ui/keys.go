@@ -0,0 +1,333 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/julez-dev/go2todo/config"
+)
+
+// Names of the keymap presets, the keys of single actions can be changed on top of them
+const (
+	KeymapDefault = "default"
+	KeymapVim     = "vim"
+	KeymapEmacs   = "emacs"
+)
+
+// keyMap contains the bindings of the view mode. The inputs keep their fixed keys,
+// enter submits, esc cancels and ctrl+c always quits
+type keyMap struct {
+	Up       key.Binding
+	Down     key.Binding
+	PageUp   key.Binding
+	PageDown key.Binding
+	Top      key.Binding
+	Bottom   key.Binding
+	Open     key.Binding
+	Back     key.Binding
+	Cancel   key.Binding
+
+	Search        key.Binding
+	NextMatch     key.Binding
+	PreviousMatch key.Binding
+	Filter        key.Binding
+	SearchAll     key.Binding
+
+	New          key.Binding
+	NewSmartList key.Binding
+	Delete       key.Binding
+	Toggle       key.Binding
+	Edit         key.Binding
+	EditExternal key.Binding
+
+	Visual     key.Binding
+	SelectUp   key.Binding
+	SelectDown key.Binding
+	SelectAll  key.Binding
+	Move       key.Binding
+	Tag        key.Binding
+
+	Help key.Binding
+	Quit key.Binding
+}
+
+// action describes a binding of the keyMap, name is used in the configuration file
+type action struct {
+	name string
+	help string
+	keys []string
+}
+
+// actions lists the bindings with their keys in the default preset
+var actions = []action{
+	{"up", "up", []string{"up", "k"}},
+	{"down", "down", []string{"down", "j"}},
+	{"page_up", "page up", []string{"pgup"}},
+	{"page_down", "page down", []string{"pgdown"}},
+	{"top", "first item", []string{"g", "home"}},
+	{"bottom", "last item", []string{"G", "end"}},
+	{"open", "open", []string{"enter", "right", "l"}},
+	{"back", "go back", []string{"left", "h"}},
+	{"cancel", "clear, close", []string{"esc"}},
+
+	{"search", "search the page", []string{"/"}},
+	{"next_match", "next match", []string{"n"}},
+	{"previous_match", "previous match", []string{"N"}},
+	{"filter", "filter all tasks", []string{"f"}},
+	{"search_all", "search all tasks", []string{"F"}},
+
+	{"new", "new item", []string{"i"}},
+	{"new_smart_list", "new smart list", []string{"S"}},
+	{"delete", "delete", []string{"d", "delete"}},
+	{"toggle", "toggle done", []string{" "}},
+	{"edit", "edit", []string{"e"}},
+	{"edit_external", "edit in $EDITOR", []string{"E"}},
+
+	{"visual", "visual selection", []string{"v"}},
+	{"select_up", "select up", []string{"shift+up"}},
+	{"select_down", "select down", []string{"shift+down"}},
+	{"select_all", "select all", []string{"ctrl+a"}},
+	{"move", "move to list", []string{"m"}},
+	{"tag", "change tags", []string{"t"}},
+
+	{"help", "toggle help", []string{"?"}},
+	{"quit", "quit", []string{"q"}},
+}
+
+// presets contain the keys differing from the default preset
+var presets = map[string]map[string][]string{
+	KeymapDefault: {},
+	KeymapVim: {
+		"page_up":     {"ctrl+b", "ctrl+u", "pgup"},
+		"page_down":   {"ctrl+f", "ctrl+d", "pgdown"},
+		"open":        {"l", "enter", "right"},
+		"back":        {"h", "left"},
+		"new":         {"i", "o"},
+		"delete":      {"d", "x", "delete"},
+		"visual":      {"v", "V"},
+		"select_up":   {"K", "shift+up"},
+		"select_down": {"J", "shift+down"},
+	},
+	KeymapEmacs: {
+		"up":          {"ctrl+p", "up"},
+		"down":        {"ctrl+n", "down"},
+		"page_up":     {"alt+v", "pgup"},
+		"page_down":   {"ctrl+v", "pgdown"},
+		"top":         {"alt+<", "home"},
+		"bottom":      {"alt+>", "end"},
+		"open":        {"ctrl+f", "enter", "right"},
+		"back":        {"ctrl+b", "left"},
+		"cancel":      {"ctrl+g", "esc"},
+		"search":      {"ctrl+s", "/"},
+		"delete":      {"ctrl+d", "delete"},
+		"select_up":   {"alt+p", "shift+up"},
+		"select_down": {"alt+n", "shift+down"},
+	},
+}
+
+// newKeyMap builds the keymap of the preset in cfg with the keys of single actions replaced.
+// An action without keys is turned off, a key bound to two actions is an error
+func newKeyMap(cfg config.UI) (*keyMap, error) {
+	preset := cfg.Keymap
+
+	if preset == "" {
+		preset = KeymapDefault
+	}
+
+	changed, ok := presets[preset]
+
+	if !ok {
+		return nil, fmt.Errorf("config: unknown keymap %q, expected one of %v", preset, []string{KeymapDefault, KeymapVim, KeymapEmacs})
+	}
+
+	k := &keyMap{}
+	bindings := k.bindings()
+
+	for name := range cfg.Keys {
+		if _, ok := bindings[name]; !ok {
+			return nil, fmt.Errorf("config: unknown key action %q", name)
+		}
+	}
+
+	boundTo := map[string]string{}
+
+	for _, a := range actions {
+		keys := a.keys
+
+		if preset, ok := changed[a.name]; ok {
+			keys = preset
+		}
+
+		if custom, ok := cfg.Keys[a.name]; ok {
+			keys = []string{}
+
+			// a space is easily lost in the file, so it may be written as word
+			for _, custom := range custom {
+				if custom == "space" {
+					custom = " "
+				}
+
+				keys = append(keys, custom)
+			}
+		}
+
+		for _, bound := range keys {
+			if other, ok := boundTo[bound]; ok && other == a.name {
+				return nil, fmt.Errorf("config: key %q is listed twice for %s", keyName(bound), a.name)
+			}
+
+			if other, ok := boundTo[bound]; ok {
+				return nil, fmt.Errorf("config: key %q is bound to %s and %s", keyName(bound), other, a.name)
+			}
+
+			boundTo[bound] = a.name
+		}
+
+		*bindings[a.name] = key.NewBinding(key.WithKeys(keys...), key.WithHelp(helpKeys(keys), a.help))
+
+		// the help leaves out disabled bindings
+		bindings[a.name].SetEnabled(len(keys) > 0)
+	}
+
+	return k, nil
+}
+
+// bindings maps the names of the actions to the bindings
+func (k *keyMap) bindings() map[string]*key.Binding {
+	return map[string]*key.Binding{
+		"up":             &k.Up,
+		"down":           &k.Down,
+		"page_up":        &k.PageUp,
+		"page_down":      &k.PageDown,
+		"top":            &k.Top,
+		"bottom":         &k.Bottom,
+		"open":           &k.Open,
+		"back":           &k.Back,
+		"cancel":         &k.Cancel,
+		"search":         &k.Search,
+		"next_match":     &k.NextMatch,
+		"previous_match": &k.PreviousMatch,
+		"filter":         &k.Filter,
+		"search_all":     &k.SearchAll,
+		"new":            &k.New,
+		"new_smart_list": &k.NewSmartList,
+		"delete":         &k.Delete,
+		"toggle":         &k.Toggle,
+		"edit":           &k.Edit,
+		"edit_external":  &k.EditExternal,
+		"visual":         &k.Visual,
+		"select_up":      &k.SelectUp,
+		"select_down":    &k.SelectDown,
+		"select_all":     &k.SelectAll,
+		"move":           &k.Move,
+		"tag":            &k.Tag,
+		"help":           &k.Help,
+		"quit":           &k.Quit,
+	}
+}
+
+// ShortHelp implements help.KeyMap
+func (k *keyMap) ShortHelp() []key.Binding {
+	return []key.Binding{k.Help, k.Quit}
+}
+
+// FullHelp implements help.KeyMap, every group is a column of the help overlay
+func (k *keyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown, k.Top, k.Bottom, k.Open, k.Back, k.Cancel},
+		{k.Search, k.NextMatch, k.PreviousMatch, k.Filter, k.SearchAll, k.Help, k.Quit},
+		{k.New, k.NewSmartList, k.Delete, k.Toggle, k.Edit, k.EditExternal},
+		{k.Visual, k.SelectUp, k.SelectDown, k.SelectAll, k.Move, k.Tag},
+	}
+}
+
+// helpKeys joins the keys as they are shown in the help
+func helpKeys(keys []string) string {
+	names := make([]string, len(keys))
+
+	for i, bound := range keys {
+		names[i] = keyName(bound)
+	}
+
+	return strings.Join(names, "/")
+}
+
+func keyName(bound string) string {
+	if bound == " " {
+		return "space"
+	}
+
+	return bound
+}
+
+// hint names the first key of the binding in the status lines, the help overlay lists all of them
+func hint(b key.Binding) string {
+	if len(b.Keys()) == 0 {
+		return "(unbound)"
+	}
+
+	return keyName(b.Keys()[0])
+}
+
+// helpView renders the columns of the full help, wrapped into rows fitting the terminal
+func (m *model) helpView() string {
+	width := m.width
+
+	if width == 0 {
+		width = notesWidth
+	}
+
+	h := help.New()
+	h.Styles.FullKey = m.theme.cursor
+	h.Styles.FullDesc = lipgloss.NewStyle()
+	h.Styles.FullSeparator = m.theme.muted
+	rows := []string{}
+	row := [][]key.Binding{}
+
+	for _, group := range m.keys.FullHelp() {
+		wider := append(append([][]key.Binding{}, row...), group)
+
+		if len(row) > 0 && lipgloss.Width(h.FullHelpView(wider)) > width-2 {
+			rows = append(rows, h.FullHelpView(row))
+			wider = [][]key.Binding{group}
+		}
+
+		row = wider
+	}
+
+	rows = append(rows, h.FullHelpView(row))
+
+	s := &strings.Builder{}
+	s.WriteString("  Keys\n\n")
+
+	for i, row := range rows {
+		if i > 0 {
+			s.WriteString("\n")
+		}
+
+		for _, line := range strings.Split(row, "\n") {
+			s.WriteString("  " + line + "\n")
+		}
+	}
+
+	s.WriteString("\n" + m.theme.status.Render("  "+hint(m.keys.Help)+" or esc to close") + "\n")
+
+	return s.String()
+}
+
+// updateHelp closes the help overlay, the other keys are ignored while it is shown
+func (m *model) updateHelp(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	if key.Matches(msg, m.keys.Help, m.keys.Cancel, m.keys.Quit) || msg.Type == tea.KeyEsc {
+		m.showHelp = false
+	}
+
+	return m, nil
+}
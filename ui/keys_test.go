@@ -0,0 +1,101 @@
+package ui
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/julez-dev/go2todo/config"
+)
+
+func TestPresets(t *testing.T) {
+	tests := []struct {
+		preset string
+		action string
+		want   []string
+	}{
+		{KeymapDefault, "back", []string{"left", "h"}},
+		{KeymapDefault, "open", []string{"enter", "right", "l"}},
+		{KeymapVim, "back", []string{"h", "left"}},
+		{KeymapVim, "page_down", []string{"ctrl+f", "ctrl+d", "pgdown"}},
+		{KeymapVim, "up", []string{"up", "k"}},
+		{KeymapEmacs, "up", []string{"ctrl+p", "up"}},
+		{KeymapEmacs, "back", []string{"ctrl+b", "left"}},
+		{KeymapEmacs, "toggle", []string{" "}},
+	}
+
+	for _, test := range tests {
+		k, err := newKeyMap(config.UI{Keymap: test.preset})
+
+		if err != nil {
+			t.Fatalf("preset %s: %v", test.preset, err)
+		}
+
+		if got := k.bindings()[test.action].Keys(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("preset %s binds %s to %q, want %q", test.preset, test.action, got, test.want)
+		}
+	}
+
+	// no preset binds a key twice, and backspace is left to the inputs
+	backspace := tea.KeyMsg{Type: tea.KeyBackspace}
+
+	for preset := range presets {
+		k, err := newKeyMap(config.UI{Keymap: preset})
+
+		if err != nil {
+			t.Errorf("preset %s: %v", preset, err)
+			continue
+		}
+
+		for name, binding := range k.bindings() {
+			if key.Matches(backspace, *binding) {
+				t.Errorf("preset %s binds backspace to %s", preset, name)
+			}
+		}
+	}
+}
+
+func TestKeyMapConfig(t *testing.T) {
+	k, err := newKeyMap(config.UI{Keymap: KeymapVim, Keys: map[string][]string{"toggle": {"space", "x"}, "delete": {"D"}, "quit": {}}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := k.Toggle.Keys(); !reflect.DeepEqual(got, []string{" ", "x"}) {
+		t.Errorf("toggle = %q, want space and x", got)
+	}
+
+	// an action without keys is turned off
+	if k.Quit.Enabled() || hint(k.Quit) != "(unbound)" {
+		t.Errorf("quit is enabled with %q", k.Quit.Keys())
+	}
+}
+
+func TestKeyMapErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.UI
+		want string
+	}{
+		{"preset", config.UI{Keymap: "nano"}, `unknown keymap "nano"`},
+		{"action", config.UI{Keys: map[string][]string{"jump": {"J"}}}, `unknown key action "jump"`},
+		{"default", config.UI{Keys: map[string][]string{"new": {"n"}}}, `key "n" is bound to next_match and new`},
+		{"space", config.UI{Keys: map[string][]string{"quit": {"space"}}}, `key "space" is bound to toggle and quit`},
+		{"vim", config.UI{Keymap: KeymapVim, Keys: map[string][]string{"tag": {"x"}}}, `key "x" is bound to delete and tag`},
+		{"emacs", config.UI{Keymap: KeymapEmacs, Keys: map[string][]string{"help": {"ctrl+g"}}}, `key "ctrl+g" is bound to cancel and help`},
+		{"same action", config.UI{Keys: map[string][]string{"move": {"m", "m"}}}, `key "m" is listed twice for move`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := newKeyMap(test.cfg)
+
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("err = %v, want it to contain %q", err, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package ui
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+type getAllTasksResponse struct {
+	tasks []*tasks.Task
+}
+
+func (m *model) getAllTasks() tea.Cmd {
+	if m.filter != nil {
+		return m.queryTasks(m.filter)
+	}
+
+	return func() tea.Msg {
+		tasks, err := m.storage.GetAllTasks(context.TODO())
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return &getAllTasksResponse{tasks: tasks}
+	}
+}
+
+// fuzzyMatch reports whether the runes of pattern appear in text in the same order,
+// ignoring case, and returns the indexes of the matched runes of text
+func fuzzyMatch(pattern, text string) ([]int, bool) {
+	wanted := []rune(pattern)
+	positions := []int{}
+
+	if len(wanted) == 0 {
+		return positions, true
+	}
+
+	for i, r := range []rune(text) {
+		if unicode.ToLower(r) == unicode.ToLower(wanted[len(positions)]) {
+			positions = append(positions, i)
+
+			if len(positions) == len(wanted) {
+				return positions, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// listName returns the name of the list with the ID
+func (m *model) listName(id string) string {
+	if index := indexOfList(m.lists, id); index >= 0 {
+		return m.lists[index].Name
+	}
+
+	return ""
+}
+
+// itemCount returns the number of items on the current page, ignoring the query
+func (m *model) itemCount() int {
+	switch m.page {
+	case viewListsPage:
+		return len(m.lists)
+	case viewTasksPage:
+		return len(m.tasks)
+	case searchPage:
+		return len(m.allTasks)
+	}
+
+	return 0
+}
+
+// cursor returns the cursor of the current page
+func (m *model) cursor() *int {
+	switch m.page {
+	case viewTasksPage:
+		return &m.cursorTasks
+	case searchPage:
+		return &m.cursorSearch
+	}
+
+	return &m.cursorLists
+}
+
+// matches reports whether the item at index i of the current page matches the query.
+// Tasks of the global search match by their text or the name of their list
+func (m *model) matches(i int) bool {
+	if m.query == "" {
+		return true
+	}
+
+	switch m.page {
+	case viewListsPage:
+		_, ok := fuzzyMatch(m.query, m.lists[i].Name)
+		return ok
+	case viewTasksPage:
+		_, ok := fuzzyMatch(m.query, m.tasks[i].Text)
+		return ok
+	case searchPage:
+		if _, ok := fuzzyMatch(m.query, m.allTasks[i].Text); ok {
+			return true
+		}
+
+		_, ok := fuzzyMatch(m.query, m.listName(m.allTasks[i].ListID))
+		return ok
+	}
+
+	return false
+}
+
+// matchCount returns the number of matching items and the position of the cursor among them
+func (m *model) matchCount() (int, int) {
+	count, current := 0, 0
+
+	for i := 0; i < m.itemCount(); i++ {
+		if m.matches(i) {
+			count++
+
+			if i == *m.cursor() {
+				current = count
+			}
+		}
+	}
+
+	return count, current
+}
+
+// move moves the cursor to the next matching item in the direction of delta,
+// wrapping around at the ends if wrap is set
+func (m *model) move(delta int, wrap bool) {
+	count := m.itemCount()
+	cursor := m.cursor()
+
+	for i, step := *cursor, 1; step <= count; step++ {
+		i += delta
+
+		if i < 0 || i >= count {
+			if !wrap {
+				return
+			}
+
+			i = (i + count) % count
+		}
+
+		if m.matches(i) {
+			*cursor = i
+			return
+		}
+	}
+}
+
+// keepCursorOnMatch moves the cursor to the closest match if its item is filtered out
+func (m *model) keepCursorOnMatch() {
+	cursor := m.cursor()
+
+	if *cursor >= m.itemCount() || m.matches(*cursor) {
+		return
+	}
+
+	before := *cursor
+	m.move(1, false)
+
+	if *cursor == before {
+		m.move(-1, false)
+	}
+}
+
+// openPage switches to a page, the query and the filter only apply to the page they were typed on
+func (m *model) openPage(p page) {
+	m.page = p
+	m.query = ""
+	m.search.Reset()
+	m.filter = nil
+	m.groups = nil
+	*m.offset() = 0
+	m.clearSelection()
+
+	// the tasks of the previously opened list are dropped
+	if p == viewTasksPage {
+		m.tasks = nil
+		m.totalTasks = 0
+		m.toEnd = false
+	}
+}
+
+// updateSearch handles the keys while the query is typed
+func (m *model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
+
+	case "enter":
+		m.mode = viewMode
+		return m, nil
+
+	case tea.KeyEsc.String():
+		m.mode = viewMode
+		m.query = ""
+		m.search.Reset()
+
+		return m, nil
+
+	case "up", "ctrl+p":
+		m.move(-1, false)
+		return m, nil
+
+	case "down", "ctrl+n":
+		m.move(1, false)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.search, cmd = m.search.Update(msg)
+	m.query = m.search.Value()
+
+	return m, cmd
+}
+
+// jump opens the list of the selected task of the global search and selects the task
+func (m *model) jump() (tea.Model, tea.Cmd) {
+	if m.cursorSearch >= len(m.allTasks) || !m.matches(m.cursorSearch) {
+		return m, nil
+	}
+
+	task := m.allTasks[m.cursorSearch]
+	index := indexOfList(m.lists, task.ListID)
+
+	if index < 0 {
+		return m, nil
+	}
+
+	m.cursorLists = index
+	m.cursorTasks = 0
+	m.jumpTo = task.ID
+	m.openPage(viewTasksPage)
+
+	return m, m.getTasks()
+}
+
+// highlight styles the matched runes of text and pads it to width runes
+func (m *model) highlight(text string, positions []int, width int) string {
+	s := &strings.Builder{}
+	next := 0
+
+	for i, r := range []rune(text) {
+		if next < len(positions) && positions[next] == i {
+			s.WriteString(m.theme.match.Render(string(r)))
+			next++
+			continue
+		}
+
+		s.WriteRune(r)
+	}
+
+	if padding := width - utf8.RuneCountInString(text); padding > 0 {
+		s.WriteString(strings.Repeat(" ", padding))
+	}
+
+	return s.String()
+}
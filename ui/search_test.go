@@ -0,0 +1,68 @@
+package ui_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+func TestSearch(t *testing.T) {
+	storage, work := seed(t)
+	storage.StoreTask(context.Background(), &tasks.Task{ListID: work.ID, Text: "write mail"})
+	d := newDriver(t, storage, config.UI{})
+
+	// the page is filtered while the query is typed
+	d.press("enter", "/", "w", "r")
+	d.assertView("> write report", "  write mail")
+	d.assertNotView("call Bob")
+
+	d.press("enter")
+	d.assertView("1/2, n/N for the next/previous match")
+
+	d.press("n")
+	d.assertView("> write mail", "2/2")
+
+	// n wraps around at the last match
+	d.press("n")
+	d.assertView("> write report", "1/2")
+
+	d.press("N")
+	d.assertView("> write mail", "2/2")
+
+	d.press("esc")
+	d.assertView("  call Bob", "> write mail")
+	d.assertNotView("n/N for the next")
+
+	// the cursor moves to a match if its list is filtered out
+	d.press("h", "/", "h", "o")
+	d.assertView("> Home")
+	d.assertNotView("Work")
+}
+
+func TestGlobalSearch(t *testing.T) {
+	storage, work := seed(t)
+	ctx := context.Background()
+	all, _ := storage.GetLists(ctx)
+	storage.StoreTask(ctx, &tasks.Task{ListID: all[1].ID, Text: "buy milk"})
+	storage.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: "write mail"})
+	d := newDriver(t, storage, config.UI{})
+
+	d.press("F")
+	d.assertView("Tasks of all lists", "> write report [-] Work", "  buy milk     [-] Home", "  write mail   [-] Work")
+
+	// tasks match by their text or the name of their list
+	d.typ("home")
+	d.assertView("> buy milk     [-] Home", "1/1")
+	d.assertNotView("write report")
+
+	d.press("esc", "/")
+	d.typ("mal")
+	d.assertView("> write mail   [-] Work")
+
+	// enter opens the list of the task with the task selected
+	d.press("enter")
+	d.assertView("Tasks for Work", "  call Bob     [-]", "> write mail   [-]")
+	d.assertNotView("buy milk")
+}
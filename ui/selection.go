@@ -0,0 +1,269 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+// bulkAction is set while the input is the argument of an action on the selected tasks
+type bulkAction int
+
+const (
+	noBulkAction bulkAction = 0
+	moveAction   bulkAction = 1
+	tagAction    bulkAction = 2
+)
+
+type bulkResponse struct{}
+
+// selecting reports whether tasks are selected or the visual mode is on
+func (m *model) selecting() bool {
+	return len(m.selected) > 0 || m.visual
+}
+
+// isSelected reports whether the task at index i of the open list is selected, either
+// on its own or as part of the range between the anchor and the cursor of the visual mode
+func (m *model) isSelected(i int) bool {
+	if m.selected[m.tasks[i].ID] {
+		return true
+	}
+
+	if !m.visual || !m.matches(i) {
+		return false
+	}
+
+	return (m.anchor <= i && i <= m.cursorTasks) || (m.cursorTasks <= i && i <= m.anchor)
+}
+
+// toggleVisual starts the visual mode at the cursor, or ends it keeping the range selected
+func (m *model) toggleVisual() {
+	if !m.visual {
+		m.visual = true
+		m.anchor = m.cursorTasks
+
+		return
+	}
+
+	for i := range m.tasks {
+		if m.isSelected(i) {
+			m.selected[m.tasks[i].ID] = true
+		}
+	}
+
+	m.visual = false
+}
+
+// extendSelection selects the task under the cursor and the one delta items away
+func (m *model) extendSelection(delta int) {
+	if m.cursorTasks >= len(m.tasks) {
+		return
+	}
+
+	m.selected[m.tasks[m.cursorTasks].ID] = true
+	m.move(delta, false)
+	m.selected[m.tasks[m.cursorTasks].ID] = true
+}
+
+// toggleSelectAll selects all matching tasks of the list, including the ones loaded later,
+// or clears the selection if everything is selected already
+func (m *model) toggleSelectAll() tea.Cmd {
+	if m.selectAll {
+		m.clearSelection()
+		return nil
+	}
+
+	m.selectAll = true
+	m.selectLoaded()
+
+	return m.loadMoreTasks(m.totalTasks - len(m.tasks))
+}
+
+// selectLoaded selects the loaded tasks matching the query
+func (m *model) selectLoaded() {
+	for i, task := range m.tasks {
+		if m.matches(i) {
+			m.selected[task.ID] = true
+		}
+	}
+}
+
+func (m *model) clearSelection() {
+	m.selected = map[string]bool{}
+	m.visual = false
+	m.selectAll = false
+}
+
+// targets returns the tasks the bulk actions apply to, the selected tasks or the one under the cursor
+func (m *model) targets() []*tasks.Task {
+	targets := []*tasks.Task{}
+
+	for i, task := range m.tasks {
+		if m.isSelected(i) {
+			targets = append(targets, task)
+		}
+	}
+
+	if len(targets) == 0 && m.cursorTasks < len(m.tasks) && m.matches(m.cursorTasks) {
+		targets = append(targets, m.tasks[m.cursorTasks])
+	}
+
+	return targets
+}
+
+// bulkUpdate applies change to copies of the targets and stores them at once
+func (m *model) bulkUpdate(change func(*tasks.Task)) tea.Cmd {
+	targets := m.targets()
+	updated := make([]*tasks.Task, len(targets))
+
+	for i, task := range targets {
+		edited := *task
+		change(&edited)
+		updated[i] = &edited
+	}
+
+	return func() tea.Msg {
+		_, err := m.storage.UpdateTasks(context.Background(), updated)
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return &bulkResponse{}
+	}
+}
+
+// bulkToggle completes the targets, or reopens them if all of them are completed
+func (m *model) bulkToggle() tea.Cmd {
+	completed := true
+
+	for _, task := range m.targets() {
+		if !task.Completed {
+			completed = false
+		}
+	}
+
+	return m.bulkUpdate(func(task *tasks.Task) {
+		task.Completed = !completed
+	})
+}
+
+func (m *model) bulkDelete() tea.Cmd {
+	ids := []string{}
+
+	for _, task := range m.targets() {
+		ids = append(ids, task.ID)
+	}
+
+	return func() tea.Msg {
+		err := m.storage.DeleteTasksByID(context.Background(), ids)
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return &bulkResponse{}
+	}
+}
+
+// askBulkInput asks for the list to move the targets to or the tags to change
+func (m *model) askBulkInput(action bulkAction) {
+	m.mode = inputMode
+	m.bulkAction = action
+	m.textInput.Reset()
+
+	if action == moveAction {
+		m.textInput.Placeholder = "Move to list"
+	} else {
+		m.textInput.Placeholder = "Tags to add, -tag to remove"
+	}
+}
+
+// applyBulkInput runs the bulk action the input was asked for
+func (m *model) applyBulkInput() tea.Cmd {
+	value := strings.TrimSpace(m.textInput.Value())
+	action := m.bulkAction
+
+	m.textInput.Reset()
+	m.mode = viewMode
+	m.bulkAction = noBulkAction
+
+	if value == "" {
+		return nil
+	}
+
+	if action == moveAction {
+		list := findList(m.lists, value)
+
+		if list == nil {
+			m.currentError = fmt.Errorf("there is no list named %q", value)
+			return nil
+		}
+
+		return m.bulkUpdate(func(task *tasks.Task) {
+			task.ListID = list.ID
+		})
+	}
+
+	fields := strings.Fields(value)
+
+	return m.bulkUpdate(func(task *tasks.Task) {
+		task.Tags = changeTags(task.Tags, fields)
+	})
+}
+
+// findList returns the list, not a smart one, with the name ignoring case
+func findList(all []*lists.List, name string) *lists.List {
+	for _, list := range all {
+		if !list.Smart() && strings.EqualFold(list.Name, name) {
+			return list
+		}
+	}
+
+	return nil
+}
+
+// changeTags adds the tags of fields to a copy of tags, fields starting with - are removed
+func changeTags(tags []string, fields []string) []string {
+	changed := append([]string{}, tags...)
+
+	for _, field := range fields {
+		tag := strings.TrimPrefix(strings.TrimPrefix(field, "-"), "#")
+		index := -1
+
+		for i, existing := range changed {
+			if existing == tag {
+				index = i
+			}
+		}
+
+		if strings.HasPrefix(field, "-") && index >= 0 {
+			changed = append(changed[:index], changed[index+1:]...)
+		}
+
+		if !strings.HasPrefix(field, "-") && index < 0 && tag != "" {
+			changed = append(changed, tag)
+		}
+	}
+
+	return changed
+}
+
+// selectionStatus describes the selection and the bulk actions below the tasks
+func (m *model) selectionStatus() string {
+	count := len(m.targets())
+	status := fmt.Sprintf("%d selected", count)
+
+	if m.visual {
+		status = "-- VISUAL -- " + status
+	}
+
+	status += fmt.Sprintf(", %s toggle done, %s delete, %s move, %s tag, %s clear", hint(m.keys.Toggle),
+		hint(m.keys.Delete), hint(m.keys.Move), hint(m.keys.Tag), hint(m.keys.Cancel))
+
+	return "\n" + m.theme.status.Render("  "+status)
+}
@@ -0,0 +1,41 @@
+package ui
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/julez-dev/go2todo/query"
+	"github.com/julez-dev/go2todo/repo/lists"
+)
+
+var errSmartListInput = errors.New(`a smart list is entered as "Name = query"`)
+
+// parseSmartList creates a smart list from the input "Name = query", the name
+// ends at the first "="
+func parseSmartList(input string) (*lists.List, error) {
+	index := strings.Index(input, "=")
+
+	if index < 0 {
+		return nil, errSmartListInput
+	}
+
+	name := strings.TrimSpace(input[:index])
+	value := strings.TrimSpace(input[index+1:])
+
+	if name == "" || value == "" {
+		return nil, errSmartListInput
+	}
+
+	q, err := query.Parse(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &lists.List{Name: name, Query: q.String()}, nil
+}
+
+// smartList returns whether the tasks page shows a smart list
+func (m *model) smartList() bool {
+	return m.page == viewTasksPage && m.cursorLists < len(m.lists) && m.lists[m.cursorLists].Smart()
+}
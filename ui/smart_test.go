@@ -0,0 +1,52 @@
+package ui_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julez-dev/go2todo/config"
+)
+
+func TestSmartList(t *testing.T) {
+	storage, _ := seed(t)
+	d := newDriver(t, storage, config.UI{})
+
+	d.press("S")
+	d.typ("Open = !done sort:text")
+	d.assertView("  Open !is:done sort:text")
+
+	all, _ := storage.GetLists(context.Background())
+
+	if len(all) != 3 || all[2].Name != "Open" || all[2].Query != "!is:done sort:text" {
+		t.Fatalf("lists = %v, want the smart list Open", all)
+	}
+
+	// the tasks of other lists are shown with the name of their list
+	d.press("G", "enter")
+	d.assertView("Tasks matching Open (!is:done sort:text)", "> call Bob     [-] Work", "  write report [-] Work")
+
+	d.press("i")
+	d.assertView("Current error: smart lists show the tasks of other lists")
+
+	// completed tasks leave the list once it is reloaded
+	d.press(" ", "h", "G", "enter")
+	d.assertNotView("call Bob")
+	d.assertView("> write report [-] Work")
+}
+
+func TestSmartListInput(t *testing.T) {
+	storage, _ := seed(t)
+	d := newDriver(t, storage, config.UI{})
+
+	d.press("S")
+	d.typ("no query")
+	d.assertView(`Current error: a smart list is entered as "Name = query"`)
+
+	d.press("esc", "S")
+	d.typ("Broken = (done")
+	d.assertView("Current error: query: unclosed parenthesis at offset 0")
+
+	if all, _ := storage.GetLists(context.Background()); len(all) != 2 {
+		t.Errorf("lists = %v, want no new list", all)
+	}
+}
@@ -0,0 +1,318 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/muesli/termenv"
+)
+
+// Names of the built in themes
+const (
+	ThemeDark         = "dark"
+	ThemeLight        = "light"
+	ThemeHighContrast = "high-contrast"
+	ThemeNoColor      = "no-color"
+)
+
+// themes contains the colours of the built in themes, no-color leaves all of them out
+var themes = map[string]config.Theme{
+	ThemeDark: {
+		Cursor:     "10",
+		Selected:   "11",
+		Done:       "10",
+		Undone:     "9",
+		Overdue:    "203",
+		Match:      "11",
+		Error:      "9",
+		Border:     "240",
+		Priorities: []string{"9", "214", "11"},
+	},
+	ThemeLight: {
+		Cursor:     "28",
+		Selected:   "130",
+		Done:       "28",
+		Undone:     "160",
+		Overdue:    "160",
+		Match:      "166",
+		Error:      "160",
+		Border:     "245",
+		Priorities: []string{"160", "166", "136"},
+	},
+	ThemeHighContrast: {
+		Cursor:     "14",
+		Selected:   "11",
+		Done:       "10",
+		Undone:     "9",
+		Overdue:    "9",
+		Match:      "11",
+		Muted:      "15",
+		Status:     "15",
+		Error:      "9",
+		Border:     "15",
+		Priorities: []string{"9", "11", "14"},
+	},
+	ThemeNoColor: {},
+}
+
+var hexPattern = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// theme contains the styles the TUI is drawn with
+type theme struct {
+	cursor   lipgloss.Style
+	selected lipgloss.Style
+	done     lipgloss.Style
+	undone   lipgloss.Style
+	overdue  lipgloss.Style
+	match    lipgloss.Style
+	muted    lipgloss.Style
+	status   lipgloss.Style
+	err      lipgloss.Style
+	modal    lipgloss.Style
+	// priorities contains the styles of priority 1, 2 and so on
+	priorities []lipgloss.Style
+	// markdown is the glamour style the notes are rendered with
+	markdown string
+}
+
+// newTheme builds the theme selected in cfg. The background of the terminal is asked for
+// before the program takes over the terminal, later the answer would be swallowed as keys
+func newTheme(cfg config.UI) (*theme, error) {
+	name := cfg.Theme
+
+	if os.Getenv("NO_COLOR") != "" {
+		name = ThemeNoColor
+	}
+
+	if name == "" {
+		name = backgroundTheme()
+	}
+
+	colours, ok := cfg.Themes[name]
+
+	if !ok {
+		colours, ok = themes[name]
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("config: unknown theme %q, expected one of %v", name, themeNames(cfg))
+	}
+
+	base := colours.Base
+
+	// a built in theme which is changed in the configuration keeps its own colours
+	if _, builtIn := themes[name]; base == "" && builtIn {
+		base = name
+	}
+
+	if base == "" {
+		base = backgroundTheme()
+	}
+
+	builtIn, ok := themes[base]
+
+	if !ok {
+		return nil, fmt.Errorf("config: theme %s: unknown base %q, expected one of %v", name, base, themeNames(config.UI{}))
+	}
+
+	colours = merge(builtIn, colours)
+
+	err := validate(colours)
+
+	if err != nil {
+		return nil, fmt.Errorf("config: theme %s: %w", name, err)
+	}
+
+	// no-color leaves out bold and faint text as well, the output is plain text
+	styled := base != ThemeNoColor
+
+	t := &theme{
+		cursor:   foreground(lipgloss.NewStyle(), colours.Cursor),
+		selected: foreground(lipgloss.NewStyle(), colours.Selected),
+		done:     foreground(lipgloss.NewStyle(), colours.Done),
+		undone:   foreground(lipgloss.NewStyle(), colours.Undone),
+		overdue:  foreground(lipgloss.NewStyle().Bold(styled), colours.Overdue),
+		match:    foreground(lipgloss.NewStyle().Bold(styled), colours.Match),
+		muted:    foreground(lipgloss.NewStyle().Faint(styled && colours.Muted == ""), colours.Muted),
+		status:   foreground(lipgloss.NewStyle().Faint(styled && colours.Status == ""), colours.Status),
+		err:      foreground(lipgloss.NewStyle().Bold(styled), colours.Error),
+		modal:    lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).Padding(0, 1).MarginLeft(2),
+		markdown: "dark",
+	}
+
+	if colours.Border != "" {
+		t.modal = t.modal.BorderForeground(lipgloss.Color(colours.Border))
+	}
+
+	for _, priority := range colours.Priorities {
+		t.priorities = append(t.priorities, foreground(lipgloss.NewStyle(), priority))
+	}
+
+	if base == ThemeLight {
+		t.markdown = "light"
+	}
+
+	if base == ThemeNoColor || termenv.EnvColorProfile() == termenv.Ascii {
+		t.markdown = "notty"
+	}
+
+	return t, nil
+}
+
+func backgroundTheme() string {
+	if termenv.HasDarkBackground() {
+		return ThemeDark
+	}
+
+	return ThemeLight
+}
+
+// themeNames returns the names of the built in themes and the ones of cfg
+func themeNames(cfg config.UI) []string {
+	names := []string{}
+
+	for name := range themes {
+		names = append(names, name)
+	}
+
+	for name := range cfg.Themes {
+		if _, ok := themes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// merge fills the colours left out of theme with the ones of base
+func merge(base, theme config.Theme) config.Theme {
+	fields := []struct{ value, fallback *string }{
+		{&theme.Cursor, &base.Cursor},
+		{&theme.Selected, &base.Selected},
+		{&theme.Done, &base.Done},
+		{&theme.Undone, &base.Undone},
+		{&theme.Overdue, &base.Overdue},
+		{&theme.Match, &base.Match},
+		{&theme.Muted, &base.Muted},
+		{&theme.Status, &base.Status},
+		{&theme.Error, &base.Error},
+		{&theme.Border, &base.Border},
+	}
+
+	for _, field := range fields {
+		if *field.value == "" {
+			*field.value = *field.fallback
+		}
+	}
+
+	if len(theme.Priorities) == 0 {
+		theme.Priorities = base.Priorities
+	}
+
+	return theme
+}
+
+func validate(theme config.Theme) error {
+	colours := []string{theme.Cursor, theme.Selected, theme.Done, theme.Undone, theme.Overdue,
+		theme.Match, theme.Muted, theme.Status, theme.Error, theme.Border}
+
+	for _, colour := range append(colours, theme.Priorities...) {
+		if colour != "" && !validColour(colour) {
+			return fmt.Errorf("invalid colour %q, expected an ANSI number from 0 to 255 like \"9\" or a hex value like \"#ff5f5f\"", colour)
+		}
+	}
+
+	return nil
+}
+
+func validColour(colour string) bool {
+	if hexPattern.MatchString(colour) {
+		return true
+	}
+
+	// Atoi accepts signs, which are no part of an ANSI number
+	n, err := strconv.Atoi(colour)
+
+	return err == nil && colour[0] != '+' && colour[0] != '-' && n <= 255
+}
+
+func foreground(style lipgloss.Style, colour string) lipgloss.Style {
+	if colour == "" {
+		return style
+	}
+
+	return style.Foreground(lipgloss.Color(colour))
+}
+
+// mark renders the completion state of the task
+func (t *theme) mark(task *tasks.Task) string {
+	if task.Completed {
+		return t.done.Render("X")
+	}
+
+	return t.undone.Render("-")
+}
+
+// priority returns the style of the priority, the last style is used for all lower priorities
+func (t *theme) priority(priority int) lipgloss.Style {
+	if len(t.priorities) == 0 {
+		return lipgloss.NewStyle()
+	}
+
+	if priority > len(t.priorities) {
+		priority = len(t.priorities)
+	}
+
+	return t.priorities[priority-1]
+}
+
+// details renders the priority and the due date shown behind the task, if it has any
+func (t *theme) details(task *tasks.Task) string {
+	details := []string{}
+
+	if task.Priority > 0 {
+		details = append(details, t.priority(task.Priority).Render(fmt.Sprintf("!%d", task.Priority)))
+	}
+
+	if task.Due != nil {
+		details = append(details, t.due(task))
+	}
+
+	if len(details) == 0 {
+		return ""
+	}
+
+	return " " + strings.Join(details, " ")
+}
+
+// due renders the due date of the task, highlighted once the task is overdue
+func (t *theme) due(task *tasks.Task) string {
+	style := t.muted
+
+	if overdue(task, time.Now()) {
+		style = t.overdue
+	}
+
+	return style.Render("due " + task.Due.Format("2006-01-02"))
+}
+
+// overdue reports whether the task is still open after the day it was due, like due<today !done
+func overdue(task *tasks.Task, now time.Time) bool {
+	if task.Completed || task.Due == nil {
+		return false
+	}
+
+	year, month, day := now.Date()
+
+	return task.Due.Before(time.Date(year, month, day, 0, 0, 0, 0, now.Location()))
+}
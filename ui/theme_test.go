@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/muesli/termenv"
+)
+
+// withColours renders with 256 colours for the test, the test binary has no terminal
+func withColours(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	t.Cleanup(func() { lipgloss.SetColorProfile(profile) })
+}
+
+func TestThemeColours(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	tests := []struct {
+		colour string
+		valid  bool
+	}{
+		{"0", true},
+		{"9", true},
+		{"255", true},
+		{"#fff", true},
+		{"#FF5F5F", true},
+		{"256", false},
+		{"999", false},
+		{"-1", false},
+		{"+9", false},
+		{"#ff", false},
+		{"#gggggg", false},
+		{"red", false},
+	}
+
+	for _, test := range tests {
+		cfg := config.UI{Theme: "mine", Themes: map[string]config.Theme{"mine": {Base: ThemeDark, Cursor: test.colour}}}
+		_, err := newTheme(cfg)
+
+		if (err == nil) != test.valid {
+			t.Errorf("colour %q: err = %v, want valid %v", test.colour, err, test.valid)
+		}
+	}
+}
+
+func TestNoColor(t *testing.T) {
+	withColours(t)
+
+	plain, err := newTheme(config.UI{Theme: ThemeNoColor})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// NO_COLOR wins over the configured theme
+	t.Setenv("NO_COLOR", "1")
+	forced, err := newTheme(config.UI{Theme: ThemeDark})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, th := range []*theme{plain, forced} {
+		styles := []lipgloss.Style{th.cursor, th.selected, th.done, th.undone, th.overdue, th.match, th.muted, th.status, th.err, th.priority(1)}
+
+		for _, style := range styles {
+			if got := style.Render("x"); got != "x" {
+				t.Errorf("no-color renders %q, want plain text", got)
+			}
+		}
+
+		if th.markdown != "notty" {
+			t.Errorf("markdown style = %s, want notty", th.markdown)
+		}
+	}
+}
+
+func TestCustomTheme(t *testing.T) {
+	withColours(t)
+
+	// a changed built in theme keeps the colours it doesn't change
+	th, err := newTheme(config.UI{Theme: ThemeLight, Themes: map[string]config.Theme{ThemeLight: {Cursor: "#ff0000"}}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cursor := th.cursor.Render(">"); !strings.Contains(cursor, "38;5;196") {
+		t.Errorf("cursor = %q, want the configured colour", cursor)
+	}
+
+	if done := th.done.Render("X"); !strings.Contains(done, "38;5;28") {
+		t.Errorf("done = %q, want the colour of the light theme", done)
+	}
+
+	_, err = newTheme(config.UI{Theme: "solarized"})
+
+	if err == nil || !strings.Contains(err.Error(), `unknown theme "solarized"`) {
+		t.Errorf("unknown theme: err = %v", err)
+	}
+
+	_, err = newTheme(config.UI{Theme: "mine", Themes: map[string]config.Theme{"mine": {Base: "solarized"}}})
+
+	if err == nil || !strings.Contains(err.Error(), `unknown base "solarized"`) {
+		t.Errorf("unknown base: err = %v", err)
+	}
+}
+
+func TestDetails(t *testing.T) {
+	th, err := newTheme(config.UI{Theme: ThemeNoColor})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	tomorrow := now.AddDate(0, 0, 1)
+
+	tests := []struct {
+		task    *tasks.Task
+		details string
+		overdue bool
+	}{
+		{&tasks.Task{}, "", false},
+		{&tasks.Task{Priority: 5}, " !5", false},
+		{&tasks.Task{Priority: 1, Due: &tomorrow}, " !1 due " + tomorrow.Format("2006-01-02"), false},
+		{&tasks.Task{Due: &yesterday}, " due " + yesterday.Format("2006-01-02"), true},
+		{&tasks.Task{Due: &yesterday, Completed: true}, " due " + yesterday.Format("2006-01-02"), false},
+		// the day it is due the task is not overdue yet
+		{&tasks.Task{Due: &now}, " due " + now.Format("2006-01-02"), false},
+	}
+
+	for _, test := range tests {
+		if got := th.details(test.task); got != test.details {
+			t.Errorf("details = %q, want %q", got, test.details)
+		}
+
+		if got := overdue(test.task, now); got != test.overdue {
+			t.Errorf("overdue(%+v) = %v, want %v", test.task, got, test.overdue)
+		}
+	}
+}
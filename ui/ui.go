@@ -7,9 +7,12 @@ import (
 	"strings"
 	"unicode/utf8"
 
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/fatih/color"
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/query"
 	"github.com/julez-dev/go2todo/repo/lists"
 	"github.com/julez-dev/go2todo/repo/tasks"
 	"github.com/julez-dev/go2todo/service"
@@ -18,8 +21,12 @@ import (
 type mode int
 
 const (
-	inputMode mode = 0
-	viewMode  mode = 1
+	inputMode  mode = 0
+	viewMode   mode = 1
+	searchMode mode = 2
+	filterMode mode = 3
+	// notesMode edits the notes of the task on the detail page
+	notesMode mode = 4
 )
 
 type page int
@@ -27,25 +34,88 @@ type page int
 const (
 	viewListsPage page = 0
 	viewTasksPage page = 1
+	// searchPage lists the tasks of all lists for the global search
+	searchPage page = 2
+	// detailPage shows a single task of the open list with its notes
+	detailPage page = 3
 )
 
 type model struct {
-	storage *service.Storage
+	storage service.Interface
 	mode    mode
 	page    page
 
 	currentError error
 
-	cursorLists int
-	cursorTasks int
+	cursorLists  int
+	cursorTasks  int
+	cursorSearch int
 
 	textInput textinput.Model
 
-	lists   []*lists.List
-	newList *lists.List
+	// query filters the items of the current page, search is the input it is typed in
+	search textinput.Model
+	query  string
+	// jumpTo is the ID of the task to select once the tasks of its list arrived
+	jumpTo string
 
-	tasks   []*tasks.Task
-	newTask *tasks.Task
+	// filter selects the tasks of the search page, it is typed in filterInput
+	filter      *query.Query
+	filterInput textinput.Model
+	// groups contains the group name of every task of the search page when the filter groups them
+	groups []string
+
+	lists []*lists.List
+	// newSmart is set while the input is the definition of a smart list
+	newSmart bool
+
+	tasks []*tasks.Task
+	// totalTasks is the number of tasks of the open list, tasks only contains the pages loaded so far
+	totalTasks int
+	// loading is set while a page of tasks is fetched
+	loading bool
+	// toEnd moves the cursor to the last task once the remaining tasks are loaded
+	toEnd bool
+
+	// selected contains the IDs of the selected tasks of the open list. In the visual mode
+	// the tasks between anchor and the cursor are selected too
+	selected map[string]bool
+	visual   bool
+	anchor   int
+	// selectAll selects the tasks of the open list as they are loaded
+	selectAll bool
+	// bulkAction is the action on the selected tasks the input is asked for
+	bulkAction bulkAction
+
+	allTasks []*tasks.Task
+
+	// notes is the editor of the notes on the detail page
+	notes textarea.Model
+	// rendered caches the notes renderedNotes, wrapped at renderedWidth
+	rendered      string
+	renderedNotes string
+	renderedWidth int
+
+	// height and width are the size of the terminal, 0 until it is known
+	height int
+	width  int
+	// offsetLists, offsetTasks, offsetSearch and offsetDetail are the first visible lines of the pages
+	offsetLists  int
+	offsetTasks  int
+	offsetSearch int
+	offsetDetail int
+
+	// confirms contains the actions which are confirmed before they run, confirmation is the open question
+	confirms     map[string]bool
+	confirmation *confirmation
+
+	// keys are the bindings of the view mode, showHelp opens the overlay listing them
+	keys     *keyMap
+	showHelp bool
+
+	theme *theme
+
+	changes <-chan struct{}
 }
 
 // Event responses
@@ -66,7 +136,10 @@ func (e *errorResponse) Error() string {
 
 type createListResponse struct{}
 
-type getTasksResponse struct{ tasks []*tasks.Task }
+type getTasksResponse struct {
+	tasks []*tasks.Task
+	total int
+}
 
 type createTaskResponse struct{}
 
@@ -76,13 +149,24 @@ type updateTaskResponse struct {
 	task *tasks.Task
 }
 
-// Messages
+type watchResponse struct {
+	changes <-chan struct{}
+}
 
-func (m *model) deleteList() tea.Msg {
-	if len(m.lists) > 0 && m.cursorLists <= len(m.lists) {
-		item := m.lists[m.cursorLists]
+type changeResponse struct{}
 
-		err := m.storage.DeleteList(context.Background(), item.ID)
+type refreshResponse struct {
+	lists  []*lists.List
+	listID string
+	tasks  []*tasks.Task
+	total  int
+}
+
+// Messages
+
+func (m *model) deleteList(list *lists.List) tea.Cmd {
+	return func() tea.Msg {
+		err := m.storage.DeleteList(context.Background(), list.ID)
 
 		if err != nil {
 			return &errorResponse{err: err}
@@ -90,8 +174,6 @@ func (m *model) deleteList() tea.Msg {
 
 		return &deleteListResponse{}
 	}
-
-	return nil
 }
 
 func (m *model) getLists() tea.Msg {
@@ -106,85 +188,236 @@ func (m *model) getLists() tea.Msg {
 	}
 }
 
-func (m *model) createList() tea.Msg {
-	_, err := m.storage.StoreList(context.Background(), m.newList)
+func (m *model) createList(list *lists.List) tea.Cmd {
+	return func() tea.Msg {
+		_, err := m.storage.StoreList(context.Background(), list)
 
-	if err != nil {
-		return &errorResponse{err: err}
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return &createListResponse{}
 	}
+}
 
-	return &createListResponse{}
+// getTasks fetches the first page of tasks of the open list, at least as many as are loaded.
+// The list and the limit are read before the command runs, the model may change meanwhile
+func (m *model) getTasks() tea.Cmd {
+	if m.cursorLists < 0 || m.cursorLists >= len(m.lists) {
+		return nil
+	}
+
+	listID := m.lists[m.cursorLists].ID
+	limit := m.loadedTasks()
+
+	return func() tea.Msg {
+		tasks, total, err := m.storage.GetTasksPage(context.TODO(), listID, 0, limit)
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return &getTasksResponse{tasks: tasks, total: total}
+	}
 }
 
-func (m *model) getTasks() tea.Msg {
-	if len(m.lists) > 0 && m.cursorLists <= len(m.lists) {
-		tasks, err := m.storage.GetTasks(context.TODO(), m.lists[m.cursorLists].ID)
+func (m *model) createTask(task *tasks.Task) tea.Cmd {
+	return func() tea.Msg {
+		_, err := m.storage.StoreTask(context.Background(), task)
 
 		if err != nil {
 			return &errorResponse{err: err}
 		}
 
-		return &getTasksResponse{tasks: tasks}
+		return &createTaskResponse{}
 	}
+}
+
+func (m *model) deleteTask(task *tasks.Task) tea.Cmd {
+	return func() tea.Msg {
+		err := m.storage.DeleteTask(context.Background(), task.ID)
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return &delteTaskResponse{}
+	}
+}
+
+// toggleTask completes or reopens the task under the cursor
+func (m *model) toggleTask() tea.Cmd {
+	if m.cursorTasks < 0 || m.cursorTasks >= len(m.tasks) {
+		return nil
+	}
+
+	// the storage gets a copy, so the displayed task stays untouched if the update fails
+	toggled := *m.tasks[m.cursorTasks]
+	toggled.Completed = !toggled.Completed
+
+	return func() tea.Msg {
+		task, err := m.storage.UpdateTask(context.Background(), &toggled)
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
 
-	return nil
+		return &updateTaskResponse{task: task}
+	}
 }
 
-func (m *model) createTask() tea.Msg {
-	_, err := m.storage.StoreTask(context.Background(), m.newTask)
+func (m *model) watch() tea.Msg {
+	changes, err := m.storage.Watch(context.Background())
 
 	if err != nil {
 		return &errorResponse{err: err}
 	}
 
-	return &createTaskResponse{}
+	return &watchResponse{changes: changes}
 }
 
-func (m *model) deleteTask() tea.Msg {
-	if len(m.tasks) > 0 && m.cursorTasks <= len(m.tasks) {
-		err := m.storage.DeleteTask(context.Background(), m.tasks[m.cursorTasks].ID)
+func (m *model) waitForChange() tea.Msg {
+	_, ok := <-m.changes
+
+	if !ok {
+		return nil
+	}
+
+	return &changeResponse{}
+}
+
+// refresh fetches the lists and, if listID is set and the list still exists, its loaded tasks
+func (m *model) refresh(listID string) tea.Cmd {
+	limit := m.loadedTasks()
+
+	return func() tea.Msg {
+		lists, err := m.storage.GetLists(context.TODO())
 
 		if err != nil {
 			return &errorResponse{err: err}
 		}
 
-		return &delteTaskResponse{}
+		resp := &refreshResponse{lists: lists, listID: listID}
+
+		if listID == "" || indexOfList(lists, listID) < 0 {
+			return resp
+		}
+
+		resp.tasks, resp.total, err = m.storage.GetTasksPage(context.TODO(), listID, 0, limit)
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return resp
+	}
+}
+
+// loadedTasks returns the number of tasks to fetch when the open list is loaded again
+func (m *model) loadedTasks() int {
+	if len(m.tasks) > taskPageSize {
+		return len(m.tasks)
 	}
 
-	return nil
+	return taskPageSize
 }
 
-func (m *model) updateTask() tea.Msg {
-	task := m.tasks[m.cursorTasks]
-	task.Completed = !task.Completed
-	task, err := m.storage.UpdateTask(context.Background(), task)
+func indexOfList(all []*lists.List, id string) int {
+	for i, list := range all {
+		if list.ID == id {
+			return i
+		}
+	}
 
-	if err != nil {
-		return &errorResponse{err: err}
+	return -1
+}
+
+func indexOfTask(all []*tasks.Task, id string) int {
+	for i, task := range all {
+		if task.ID == id {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// clampCursor keeps a cursor inside a collection of the given length
+func clampCursor(cursor, length int) int {
+	if cursor >= length {
+		cursor = length - 1
+	}
+
+	if cursor < 0 {
+		cursor = 0
 	}
 
-	return &updateTaskResponse{task: task}
+	return cursor
 }
 
-func New(storage *service.Storage) *model {
+// New creates the model of the TUI, cfg is the ui section of the configuration file
+func New(storage service.Interface, cfg config.UI) (*model, error) {
+	confirms, err := confirms(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := newKeyMap(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	theme, err := newTheme(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
 	ti := textinput.NewModel()
 	ti.Focus()
 	ti.CharLimit = 156
 	ti.Width = 40
 
+	search := textinput.NewModel()
+	search.Focus()
+	search.Prompt = "/"
+	search.Placeholder = "Search"
+	search.Width = 40
+
 	return &model{
-		storage:   storage,
-		textInput: ti,
-		mode:      viewMode,
-		page:      viewListsPage,
-	}
+		storage:     storage,
+		textInput:   ti,
+		search:      search,
+		filterInput: newFilterInput(),
+		notes:       newNotesInput(),
+		selected:    map[string]bool{},
+		confirms:    confirms,
+		keys:        keys,
+		theme:       theme,
+		mode:        viewMode,
+		page:        viewListsPage,
+	}, nil
 }
 
 func (m *model) Init() tea.Cmd {
-	return m.getLists
+	return tea.Batch(m.getLists, m.watch)
 }
 
 func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	m.keepCursorOnMatch()
+	m.scroll()
+
+	if next := m.loadNextPage(); next != nil {
+		cmd = tea.Batch(cmd, next)
+	}
+
+	return model, cmd
+}
+
+func (m *model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.currentError = nil
 
 	switch msg := msg.(type) {
@@ -200,163 +433,524 @@ func (m *model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case *errorResponse:
 		m.currentError = msg
+		m.loading = false
+		m.toEnd = false
+		return m, nil
+
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		m.width = msg.Width
+		m.resizeNotes()
+
 		return m, nil
 
 	case *createListResponse:
 		return m, m.getLists
 
 	case *createTaskResponse:
-		return m, m.getTasks
+		return m, m.getTasks()
 
 	case *getTasksResponse:
 		m.tasks = msg.tasks
+		m.totalTasks = msg.total
+		m.cursorTasks = clampCursor(m.cursorTasks, len(m.tasks))
+
+		return m, m.selectJumpTarget()
+
+	case *moreTasksResponse:
+		return m, m.addTasks(msg)
+
+	case *getAllTasksResponse:
+		m.setAllTasks(msg.tasks)
+		m.cursorSearch = clampCursor(m.cursorSearch, len(m.allTasks))
 		return m, nil
 
 	case *delteTaskResponse:
 		if m.cursorTasks > 0 {
 			m.cursorTasks--
 		}
-		return m, m.getTasks
+		return m, m.getTasks()
 
 	case *updateTaskResponse:
-		m.tasks[m.cursorTasks] = msg.task
+		// the tasks may have been reloaded or the cursor moved since the update was sent
+		if i := indexOfTask(m.tasks, msg.task.ID); i >= 0 {
+			m.tasks[i] = msg.task
+		}
+
 		return m, nil
 
-	case tea.KeyMsg:
+	case *confirmation:
+		m.confirmation = msg
+		return m, nil
 
-		switch msg.String() {
+	case *bulkResponse:
+		m.clearSelection()
+		return m, m.getTasks()
 
-		case "ctrl+c", "q":
-			return m, tea.Quit
+	case *editorResponse:
+		return m, m.applyEdit(msg)
 
-		case "up", "k":
-			if m.mode != viewMode {
-				break
-			}
+	case *editTaskResponse:
+		if index := indexOfTask(m.tasks, msg.task.ID); index >= 0 {
+			m.tasks[index] = msg.task
+		}
 
-			if m.page == viewListsPage {
-				if m.cursorLists > 0 {
-					m.cursorLists--
-				}
-			}
+		return m, nil
+
+	case *watchResponse:
+		m.changes = msg.changes
+		return m, m.waitForChange
+
+	case *changeResponse:
+		listID := ""
+
+		if m.showsList() && m.cursorLists < len(m.lists) {
+			listID = m.lists[m.cursorLists].ID
+		}
 
-			if m.page == viewTasksPage {
-				if m.cursorTasks > 0 {
-					m.cursorTasks--
+		if m.page == searchPage {
+			return m, tea.Batch(m.refresh(listID), m.getAllTasks(), m.waitForChange)
+		}
+
+		return m, tea.Batch(m.refresh(listID), m.waitForChange)
+
+	case *refreshResponse:
+		// keep the cursors on the selected items, they may have moved or vanished
+		if m.cursorLists < len(m.lists) {
+			if index := indexOfList(msg.lists, m.lists[m.cursorLists].ID); index >= 0 {
+				m.cursorLists = index
+			} else if m.showsList() {
+				if m.page == detailPage {
+					m.closeDetail()
 				}
-			}
 
-		case "down", "j":
-			if m.mode != viewMode {
-				break
+				m.clearSelection()
+				m.page = viewListsPage
 			}
+		}
 
-			if m.page == viewListsPage && m.cursorLists < len(m.lists)-1 {
-				m.cursorLists++
+		if m.showsList() && m.cursorTasks < len(m.tasks) {
+			if index := indexOfTask(msg.tasks, m.tasks[m.cursorTasks].ID); index >= 0 {
+				m.cursorTasks = index
+			} else if m.page == detailPage {
+				m.closeDetail()
 			}
+		}
 
-			if m.page == viewTasksPage && m.cursorTasks < len(m.tasks)-1 {
-				m.cursorTasks++
-			}
+		m.lists = msg.lists
+		m.cursorLists = clampCursor(m.cursorLists, len(m.lists))
+
+		// the tasks page may have been opened for another list in the meantime
+		if m.showsList() && len(m.lists) > 0 && m.lists[m.cursorLists].ID == msg.listID {
+			m.tasks = msg.tasks
+			m.totalTasks = msg.total
+			m.cursorTasks = clampCursor(m.cursorTasks, len(m.tasks))
+		}
 
-		case "enter":
-			if m.mode == inputMode {
-				if m.page == viewListsPage {
-					m.newList = &lists.List{
-						Name: m.textInput.Value(),
-					}
+		return m, nil
 
-					m.textInput.Reset()
-					m.mode = viewMode
+	case tea.KeyMsg:
+		if m.confirmation != nil {
+			return m.updateConfirmation(msg)
+		}
 
-					return m, m.createList
-				}
+		if m.showHelp {
+			return m.updateHelp(msg)
+		}
 
-				if m.page == viewTasksPage {
-					m.newTask = &tasks.Task{
-						Text:   m.textInput.Value(),
-						ListID: m.lists[m.cursorLists].ID,
-					}
+		if m.mode == searchMode {
+			return m.updateSearch(msg)
+		}
 
-					m.textInput.Reset()
-					m.mode = viewMode
+		if m.mode == filterMode {
+			return m.updateFilter(msg)
+		}
 
-					return m, m.createTask
-				}
-			}
+		if m.mode == notesMode {
+			return m.updateNotes(msg)
+		}
 
-			if m.page != viewTasksPage && m.mode == viewMode && len(m.lists) > 0 {
-				m.page = viewTasksPage
-				m.cursorTasks = 0
-				return m, m.getTasks
-			}
+		if m.mode == inputMode {
+			return m.updateInput(msg)
+		}
 
-		case tea.KeyEsc.String():
-			if m.mode == inputMode {
-				m.textInput.Reset()
-				m.mode = viewMode
+		return m.updateView(msg)
+	}
 
-				return m, nil
-			}
+	if m.mode == inputMode {
+		var cmd tea.Cmd
+		m.textInput, cmd = m.textInput.Update(msg)
+		return m, cmd
+	}
 
-		case "i":
-			if m.mode != inputMode {
-				m.mode = inputMode
+	// the editor keeps its cursor blinking
+	if m.mode == notesMode {
+		var cmd tea.Cmd
+		m.notes, cmd = m.notes.Update(msg)
+		return m, cmd
+	}
 
-				if m.page == viewListsPage {
-					m.textInput.Placeholder = "New list name"
-				}
+	return m, nil
+}
 
-				if m.page == viewTasksPage {
-					m.textInput.Placeholder = "New task name"
-				}
+// updateInput handles the keys while the name of a new item or the argument of a bulk action is typed
+func (m *model) updateInput(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m, tea.Quit
 
-				return m, nil
+	case "enter":
+		if m.page == viewListsPage {
+			list := &lists.List{
+				Name: m.textInput.Value(),
 			}
 
-		case tea.KeyDelete.String(), "d":
-			if m.page == viewListsPage && m.mode == viewMode {
-				return m, m.deleteList
-			}
+			if m.newSmart {
+				smart, err := parseSmartList(m.textInput.Value())
 
-			if m.page == viewTasksPage && m.mode == viewMode {
-				return m, m.deleteTask
-			}
+				if err != nil {
+					m.currentError = err
+					return m, nil
+				}
 
-		case tea.KeyRight.String(), "l":
-			if m.page != viewTasksPage && m.mode == viewMode && len(m.lists) > 0 {
-				m.page = viewTasksPage
-				m.cursorTasks = 0
-				return m, m.getTasks
+				list = smart
 			}
 
-		case tea.KeyLeft.String(), tea.KeyBackspace.String(), "h":
-			if m.page == viewTasksPage && m.mode == viewMode {
-				m.page = viewListsPage
-				return m, m.getLists
-			}
+			m.textInput.Reset()
+			m.mode = viewMode
 
-		case " ":
-			if m.page == viewTasksPage && m.mode == viewMode {
-				return m, m.updateTask
+			return m, m.createList(list)
+		}
+
+		if m.page == viewTasksPage && m.bulkAction != noBulkAction {
+			return m, m.applyBulkInput()
+		}
+
+		if m.page == viewTasksPage && m.cursorLists < len(m.lists) {
+			task := &tasks.Task{
+				Text:   m.textInput.Value(),
+				ListID: m.lists[m.cursorLists].ID,
 			}
+
+			m.textInput.Reset()
+			m.mode = viewMode
+
+			return m, m.createTask(task)
 		}
+
+	case tea.KeyEsc.String():
+		m.textInput.Reset()
+		m.mode = viewMode
+		m.bulkAction = noBulkAction
+
+		return m, nil
 	}
 
-	if m.mode == inputMode {
-		var cmd tea.Cmd
-		m.textInput, cmd = m.textInput.Update(msg)
-		return m, cmd
+	var cmd tea.Cmd
+	m.textInput, cmd = m.textInput.Update(msg)
+	return m, cmd
+}
+
+// updateView handles the keys of the view mode as bound in the keymap
+func (m *model) updateView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	// ctrl+c quits whatever the keymap says, so there is always a way out
+	if msg.String() == "ctrl+c" {
+		return m, tea.Quit
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Help):
+		m.showHelp = true
+
+	case key.Matches(msg, m.keys.Up, m.keys.Down):
+		delta := 1
+
+		if key.Matches(msg, m.keys.Up) {
+			delta = -1
+		}
+
+		if m.page == detailPage {
+			m.scrollNotes(delta)
+		} else {
+			m.move(delta, false)
+		}
+
+	case key.Matches(msg, m.keys.PageDown):
+		m.movePage(1)
+
+	case key.Matches(msg, m.keys.PageUp):
+		m.movePage(-1)
+
+	case key.Matches(msg, m.keys.Top):
+		return m, m.moveToEdge(false)
+
+	case key.Matches(msg, m.keys.Bottom):
+		return m, m.moveToEdge(true)
+
+	case key.Matches(msg, m.keys.NextMatch):
+		if m.query != "" {
+			m.move(1, true)
+		}
+
+	case key.Matches(msg, m.keys.PreviousMatch):
+		if m.query != "" {
+			m.move(-1, true)
+		}
+
+	case key.Matches(msg, m.keys.Search):
+		if m.page == detailPage {
+			break
+		}
+
+		m.mode = searchMode
+		m.search.Reset()
+
+		// the search covers all tasks of the list, not only the loaded ones
+		if m.page == viewTasksPage {
+			return m, m.loadMoreTasks(m.totalTasks - len(m.tasks))
+		}
+
+	case key.Matches(msg, m.keys.Filter):
+		m.mode = filterMode
+
+		if m.filter != nil {
+			m.filterInput.SetValue(m.filter.String())
+			m.filterInput.CursorEnd()
+		}
+
+	case key.Matches(msg, m.keys.SearchAll):
+		m.openPage(searchPage)
+		m.mode = searchMode
+		m.cursorSearch = 0
+		return m, tea.Batch(m.getLists, m.getAllTasks())
+
+	case key.Matches(msg, m.keys.Open):
+		if m.page == searchPage {
+			return m.jump()
+		}
+
+		if m.page == viewListsPage && len(m.lists) > 0 {
+			m.openPage(viewTasksPage)
+			m.cursorTasks = 0
+			return m, m.getTasks()
+		}
+
+		if m.page == viewTasksPage {
+			m.openDetail()
+		}
+
+	case key.Matches(msg, m.keys.Back):
+		if m.page == detailPage {
+			m.closeDetail()
+			return m, nil
+		}
+
+		if m.page != viewListsPage {
+			m.openPage(viewListsPage)
+			return m, m.getLists
+		}
+
+	case key.Matches(msg, m.keys.Cancel):
+		if m.page == viewTasksPage && m.selecting() {
+			m.clearSelection()
+			return m, nil
+		}
+
+		if m.query != "" {
+			m.query = ""
+			m.search.Reset()
+
+			return m, nil
+		}
+
+		if m.page == searchPage {
+			m.openPage(viewListsPage)
+			return m, m.getLists
+		}
+
+		if m.page == detailPage {
+			m.closeDetail()
+		}
+
+	case key.Matches(msg, m.keys.New):
+		if m.smartList() {
+			m.currentError = service.ErrSmartList
+			return m, nil
+		}
+
+		if m.page != viewListsPage && m.page != viewTasksPage {
+			break
+		}
+
+		m.mode = inputMode
+		m.newSmart = false
+		m.bulkAction = noBulkAction
+
+		if m.page == viewListsPage {
+			m.textInput.Placeholder = "New list name"
+		}
+
+		if m.page == viewTasksPage {
+			m.textInput.Placeholder = "New task name"
+		}
+
+	case key.Matches(msg, m.keys.NewSmartList):
+		if m.page == viewListsPage {
+			m.mode = inputMode
+			m.newSmart = true
+			m.textInput.Placeholder = "Name = query, e.g. Today = due:today !done"
+		}
+
+	case key.Matches(msg, m.keys.Delete):
+		if m.page == viewListsPage {
+			return m, m.askDeleteList()
+		}
+
+		if m.page == viewTasksPage {
+			return m, m.askDeleteTasks()
+		}
+
+	case key.Matches(msg, m.keys.Toggle):
+		if m.page == viewTasksPage && m.selecting() {
+			return m, m.bulkToggle()
+		}
+
+		if m.showsList() {
+			return m, m.toggleTask()
+		}
+
+	case key.Matches(msg, m.keys.Edit):
+		if m.page == detailPage {
+			return m, m.editNotes()
+		}
+
+		if m.page == viewTasksPage {
+			return m, m.openEditor()
+		}
+
+	case key.Matches(msg, m.keys.EditExternal):
+		if m.showsList() {
+			return m, m.openEditor()
+		}
+
+	case key.Matches(msg, m.keys.Visual):
+		if m.page == viewTasksPage {
+			m.toggleVisual()
+		}
+
+	case key.Matches(msg, m.keys.SelectUp):
+		if m.page == viewTasksPage {
+			m.extendSelection(-1)
+		}
+
+	case key.Matches(msg, m.keys.SelectDown):
+		if m.page == viewTasksPage {
+			m.extendSelection(1)
+		}
+
+	case key.Matches(msg, m.keys.SelectAll):
+		if m.page == viewTasksPage {
+			return m, m.toggleSelectAll()
+		}
+
+	case key.Matches(msg, m.keys.Move):
+		if m.page == viewTasksPage && len(m.targets()) > 0 {
+			m.askBulkInput(moveAction)
+		}
+
+	case key.Matches(msg, m.keys.Tag):
+		if m.page == viewTasksPage && len(m.targets()) > 0 {
+			m.askBulkInput(tagAction)
+		}
 	}
 
 	return m, nil
 }
 
+// showsList reports whether the current page shows the tasks of the open list
+func (m *model) showsList() bool {
+	return m.page == viewTasksPage || m.page == detailPage
+}
+
 func (m *model) View() string {
+	if m.showHelp {
+		return m.helpView()
+	}
+
+	s := &strings.Builder{}
+	s.WriteString(m.header())
+
+	lines, itemLines := m.body()
+	height := m.bodyHeight(len(lines))
+	offset := *m.offset()
+
+	if height > 0 && len(lines) > height {
+		end := offset + height
+
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		for _, line := range lines[offset:end] {
+			s.WriteString(line + "\n")
+		}
+
+		s.WriteString(m.indicator(len(lines), itemLines, offset, height) + "\n")
+	} else {
+		for _, line := range lines {
+			s.WriteString(line + "\n")
+		}
+	}
+
+	s.WriteString(m.footer())
+
+	return s.String()
+}
+
+// header renders the lines above the items of the current page
+func (m *model) header() string {
 	s := &strings.Builder{}
 
 	if m.currentError != nil {
-		s.WriteString("Current error: " + m.currentError.Error() + "\n\n")
+		s.WriteString(m.theme.err.Render("Current error: "+m.currentError.Error()) + "\n\n")
+	}
+
+	if m.page == viewTasksPage {
+		list := m.lists[m.cursorLists]
+
+		if list.Smart() {
+			s.WriteString("  Tasks matching " + list.Name + " (" + list.Query + ")\n\n")
+		} else {
+			s.WriteString("  Tasks for " + list.Name + "\n\n")
+		}
+	}
+
+	if m.page == detailPage {
+		s.WriteString(m.detailHeader())
+	}
+
+	if m.page == searchPage {
+		if m.filter != nil {
+			s.WriteString("  Tasks matching " + m.filter.String() + "\n\n")
+		} else {
+			s.WriteString("  Tasks of all lists\n\n")
+		}
+	}
+
+	return s.String()
+}
+
+// body renders the items of the current page, one per line. itemLines contains the
+// line of every item, -1 for the items not matching the query
+func (m *model) body() ([]string, []int) {
+	lines := []string{}
+	itemLines := make([]int, m.itemCount())
+
+	for i := range itemLines {
+		itemLines[i] = -1
 	}
 
 	if m.page == viewListsPage {
@@ -369,21 +963,33 @@ func (m *model) View() string {
 		}
 
 		for i, listItem := range m.lists {
+			if !m.matches(i) {
+				continue
+			}
+
 			cursor := " "
 			if m.cursorLists == i {
 				cursor = ">"
 			}
 
-			color.New(color.FgHiGreen).Fprint(s, cursor)
-			s.WriteString(fmt.Sprintf(" %-"+fmt.Sprint(longest)+"s\n", listItem.Name))
+			positions, _ := fuzzyMatch(m.query, listItem.Name)
+
+			s := &strings.Builder{}
+			s.WriteString(m.theme.cursor.Render(cursor))
+			s.WriteString(" " + m.highlight(listItem.Name, positions, longest))
+
+			if listItem.Smart() {
+				s.WriteString(" " + m.theme.muted.Render(listItem.Query))
+			}
+
+			itemLines[i] = len(lines)
+			lines = append(lines, s.String())
 		}
 	}
 
 	if m.page == viewTasksPage {
 		list := m.lists[m.cursorLists]
 
-		s.WriteString("  Tasks for " + list.Name + "\n\n")
-
 		longest := 0
 		for _, taskItem := range m.tasks {
 			length := utf8.RuneCountInString(taskItem.Text)
@@ -393,25 +999,130 @@ func (m *model) View() string {
 		}
 
 		for i, taskItem := range m.tasks {
+			if !m.matches(i) {
+				continue
+			}
+
 			cursor := " "
 			if m.cursorTasks == i {
 				cursor = ">"
 			}
 
-			color.New(color.FgHiGreen).Fprint(s, cursor)
-			s.WriteString(fmt.Sprintf(" %-"+fmt.Sprint(longest)+"s [", taskItem.Text))
-			if taskItem.Completed {
-				color.New(color.FgHiGreen).Fprint(s, "X")
-			} else {
-				color.New(color.FgHiRed).Fprint(s, "-")
+			positions, _ := fuzzyMatch(m.query, taskItem.Text)
+
+			s := &strings.Builder{}
+			s.WriteString(m.theme.cursor.Render(cursor))
+
+			// the column of the selection marks is only shown while selecting
+			if m.selecting() && m.isSelected(i) {
+				s.WriteString(m.theme.selected.Render("*"))
+			} else if m.selecting() {
+				s.WriteString(" ")
+			}
+			s.WriteString(" " + m.highlight(taskItem.Text, positions, longest) + " [" + m.theme.mark(taskItem) + "]")
+
+			// the tasks of smart lists belong to other lists
+			if list.Smart() {
+				s.WriteString(" " + m.listName(taskItem.ListID))
+			}
+
+			s.WriteString(m.theme.details(taskItem))
+
+			itemLines[i] = len(lines)
+			lines = append(lines, s.String())
+		}
+	}
+
+	if m.page == detailPage {
+		lines = m.detailBody()
+	}
+
+	if m.page == searchPage {
+		group := ""
+
+		longest := 0
+		for _, taskItem := range m.allTasks {
+			length := utf8.RuneCountInString(taskItem.Text)
+			if length > longest {
+				longest = length
+			}
+		}
+
+		for i, taskItem := range m.allTasks {
+			if !m.matches(i) {
+				continue
+			}
+
+			cursor := " "
+			if m.cursorSearch == i {
+				cursor = ">"
+			}
+
+			if i < len(m.groups) && m.groups[i] != group {
+				if group != "" {
+					lines = append(lines, "")
+				}
+
+				group = m.groups[i]
+				lines = append(lines, "  "+group)
+			}
+
+			// the list name is only highlighted if the task matched by it
+			textPositions, textMatched := fuzzyMatch(m.query, taskItem.Text)
+			listPositions := []int{}
+
+			if !textMatched {
+				listPositions, _ = fuzzyMatch(m.query, m.listName(taskItem.ListID))
 			}
-			s.WriteString("]\n")
+
+			s := &strings.Builder{}
+			s.WriteString(m.theme.cursor.Render(cursor))
+			s.WriteString(" " + m.highlight(taskItem.Text, textPositions, longest) + " [" + m.theme.mark(taskItem) + "] ")
+			s.WriteString(m.highlight(m.listName(taskItem.ListID), listPositions, 0) + m.theme.details(taskItem))
+
+			itemLines[i] = len(lines)
+			lines = append(lines, s.String())
 		}
 	}
 
+	return lines, itemLines
+}
+
+// footer renders the inputs and the status line below the items
+func (m *model) footer() string {
+	s := &strings.Builder{}
+
 	if m.mode == inputMode {
 		s.WriteString("\n" + m.textInput.View())
 	}
 
+	if m.mode == filterMode {
+		s.WriteString("\n" + m.filterInput.View())
+	}
+
+	if m.mode == notesMode {
+		s.WriteString(m.notes.View() + "\n\n")
+		s.WriteString(m.theme.status.Render("  ctrl+s to save, esc to cancel"))
+	}
+
+	if m.confirmation != nil {
+		s.WriteString(m.confirmationView())
+		return s.String()
+	}
+
+	if m.page == viewTasksPage && m.mode == viewMode && m.selecting() {
+		s.WriteString(m.selectionStatus())
+	}
+
+	if m.mode == searchMode {
+		s.WriteString("\n" + m.search.View())
+	} else if m.query != "" {
+		count, current := m.matchCount()
+		s.WriteString("\n" + m.theme.status.Render(fmt.Sprintf("/%s  %d/%d, %s/%s for the next/previous match, %s to clear",
+			m.query, current, count, hint(m.keys.NextMatch), hint(m.keys.PreviousMatch), hint(m.keys.Cancel))))
+	} else if m.mode == viewMode && m.keys.Help.Enabled() && !(m.page == viewTasksPage && m.selecting()) {
+		s.WriteString("\n" + m.theme.status.Render("  "+hint(m.keys.Help)+" for help"))
+	}
+
 	return s.String()
 }
@@ -0,0 +1,363 @@
+package ui_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service/fake"
+	"github.com/julez-dev/go2todo/ui"
+)
+
+// driver runs the model like the program does, but synchronously
+type driver struct {
+	t     *testing.T
+	model tea.Model
+	// pending contains the results of the commands which were still blocked when they were run
+	pending []chan tea.Msg
+}
+
+func newDriver(t *testing.T, storage *fake.Service, cfg config.UI) *driver {
+	t.Helper()
+
+	// the tests compare plain text, the theme must not ask the terminal for its background
+	cfg.Theme = "no-color"
+
+	model, err := ui.New(storage, cfg)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &driver{t: t, model: model}
+	d.run(model.Init())
+	d.send(tea.WindowSizeMsg{Width: 80, Height: 40})
+
+	return d
+}
+
+// run executes cmd and feeds its messages back into the model. Commands blocking for
+// longer than a moment, like waiting for changes of the storage, are kept for settle
+func (d *driver) run(cmd tea.Cmd) {
+	if cmd == nil {
+		return
+	}
+
+	msgs := make(chan tea.Msg, 1)
+	go func() { msgs <- cmd() }()
+
+	select {
+	case msg := <-msgs:
+		d.receive(msg)
+
+	case <-time.After(50 * time.Millisecond):
+		d.pending = append(d.pending, msgs)
+	}
+}
+
+// settle feeds the results of the blocked commands which completed in the meantime into the model
+func (d *driver) settle() {
+	pending := d.pending
+	d.pending = nil
+
+	for _, msgs := range pending {
+		select {
+		case msg := <-msgs:
+			d.receive(msg)
+
+		case <-time.After(50 * time.Millisecond):
+			d.pending = append(d.pending, msgs)
+		}
+	}
+}
+
+func (d *driver) receive(msg tea.Msg) {
+	if batch, ok := msg.(tea.BatchMsg); ok {
+		for _, cmd := range batch {
+			d.run(cmd)
+		}
+
+		return
+	}
+
+	// sequences are unexported, but they are slices of commands as well
+	if v := reflect.ValueOf(msg); v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			cmd, _ := v.Index(i).Interface().(tea.Cmd)
+			d.run(cmd)
+		}
+
+		return
+	}
+
+	if msg != nil {
+		d.send(msg)
+	}
+}
+
+func (d *driver) send(msg tea.Msg) {
+	model, cmd := d.model.Update(msg)
+	d.model = model
+	d.run(cmd)
+}
+
+// press sends the keys, special keys like enter are written by their name
+func (d *driver) press(keys ...string) {
+	for _, k := range keys {
+		d.send(keyMsg(k))
+	}
+}
+
+// typ types text into the focused input and submits it
+func (d *driver) typ(text string) {
+	for _, r := range text {
+		d.send(keyMsg(string(r)))
+	}
+
+	d.press("enter")
+}
+
+func (d *driver) view() string {
+	return d.model.View()
+}
+
+func (d *driver) assertView(contains ...string) {
+	d.t.Helper()
+
+	view := d.view()
+
+	for _, s := range contains {
+		if !strings.Contains(view, s) {
+			d.t.Errorf("view does not contain %q:\n%s", s, view)
+		}
+	}
+}
+
+func (d *driver) assertNotView(s string) {
+	d.t.Helper()
+
+	if view := d.view(); strings.Contains(view, s) {
+		d.t.Errorf("view contains %q:\n%s", s, view)
+	}
+}
+
+func keyMsg(k string) tea.KeyMsg {
+	special := map[string]tea.KeyType{
+		"enter":      tea.KeyEnter,
+		"esc":        tea.KeyEsc,
+		"up":         tea.KeyUp,
+		"down":       tea.KeyDown,
+		"left":       tea.KeyLeft,
+		"right":      tea.KeyRight,
+		"backspace":  tea.KeyBackspace,
+		"shift+down": tea.KeyShiftDown,
+		"ctrl+a":     tea.KeyCtrlA,
+		"ctrl+s":     tea.KeyCtrlS,
+		"pgup":       tea.KeyPgUp,
+		"pgdown":     tea.KeyPgDown,
+	}
+
+	if typ, ok := special[k]; ok {
+		return tea.KeyMsg{Type: typ}
+	}
+
+	if k == " " {
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(k)}
+	}
+
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+// seed creates the lists Work with the tasks write report and call Bob, and Home without tasks
+func seed(t *testing.T) (*fake.Service, *lists.List) {
+	t.Helper()
+
+	storage := fake.New()
+	ctx := context.Background()
+
+	work, err := storage.StoreList(ctx, &lists.List{Name: "Work"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = storage.StoreList(ctx, &lists.List{Name: "Home"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, text := range []string{"write report", "call Bob"} {
+		_, err = storage.StoreTask(ctx, &tasks.Task{ListID: work.ID, Text: text})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return storage, work
+}
+
+func TestNavigation(t *testing.T) {
+	storage, _ := seed(t)
+	d := newDriver(t, storage, config.UI{})
+
+	d.assertView("> Work", "  Home")
+
+	d.press("j")
+	d.assertView("  Work", "> Home")
+
+	d.press("k", "enter")
+	d.assertView("Tasks for Work", "> write report [-]", "  call Bob     [-]")
+
+	d.press("down")
+	d.assertView("  write report [-]", "> call Bob     [-]")
+
+	d.press("enter")
+	d.assertView("call Bob [-]", "list Work", "No notes")
+
+	d.press("esc")
+	d.assertView("Tasks for Work")
+
+	d.press("h")
+	d.assertView("> Work", "  Home")
+	d.assertNotView("Tasks for")
+}
+
+func TestCreateListAndTask(t *testing.T) {
+	storage, _ := seed(t)
+	d := newDriver(t, storage, config.UI{})
+
+	d.press("i")
+	d.typ("Errands")
+	d.assertView("Errands")
+
+	all, _ := storage.GetLists(context.Background())
+
+	if len(all) != 3 || all[2].Name != "Errands" {
+		t.Fatalf("lists = %v, want Errands as third list", all)
+	}
+
+	d.press("G", "enter")
+	d.assertView("Tasks for Errands")
+
+	// q is typed into the input instead of quitting
+	d.press("i")
+	d.typ("buy quinoa")
+	d.assertView("> buy quinoa [-]")
+
+	created, _ := storage.GetTasks(context.Background(), all[2].ID)
+
+	if len(created) != 1 || created[0].Text != "buy quinoa" {
+		t.Fatalf("tasks = %v, want buy quinoa", created)
+	}
+
+	// esc drops the input without creating anything
+	d.press("i", "x", "esc")
+
+	created, _ = storage.GetTasks(context.Background(), all[2].ID)
+
+	if len(created) != 1 {
+		t.Errorf("esc created a task, got %d tasks", len(created))
+	}
+}
+
+func TestToggleTask(t *testing.T) {
+	storage, work := seed(t)
+	d := newDriver(t, storage, config.UI{})
+
+	d.press("enter", " ")
+	d.assertView("> write report [X]")
+
+	stored, _ := storage.GetTasks(context.Background(), work.ID)
+
+	if !stored[0].Completed {
+		t.Errorf("write report is not completed in the storage")
+	}
+
+	d.press(" ")
+	d.assertView("> write report [-]")
+}
+
+func TestDeleteTask(t *testing.T) {
+	storage, work := seed(t)
+	d := newDriver(t, storage, config.UI{})
+
+	d.press("enter", "d")
+	d.assertView("delete task write report?")
+
+	// anything but y keeps the task
+	d.press("n")
+	d.assertNotView("delete task")
+	d.assertView("write report")
+
+	d.press("d", "y")
+	d.assertNotView("write report")
+	d.assertView("> call Bob")
+
+	remaining, _ := storage.GetTasks(context.Background(), work.ID)
+
+	if len(remaining) != 1 || remaining[0].Text != "call Bob" {
+		t.Errorf("tasks = %v, want only call Bob", remaining)
+	}
+}
+
+func TestDeleteListWithoutConfirmation(t *testing.T) {
+	storage, work := seed(t)
+	d := newDriver(t, storage, config.UI{Confirm: map[string]bool{ui.ConfirmDeleteList: false}})
+
+	d.press("d")
+	d.assertNotView("Work")
+	d.assertView("> Home")
+
+	remaining, _ := storage.GetTasks(context.Background(), work.ID)
+
+	if len(remaining) != 0 {
+		t.Errorf("the tasks of the deleted list remain: %v", remaining)
+	}
+}
+
+func TestDeleteListAsksWithTaskCount(t *testing.T) {
+	storage, _ := seed(t)
+	d := newDriver(t, storage, config.UI{})
+
+	d.press("d")
+	d.assertView("delete list Work and 2 tasks?")
+
+	d.press("esc")
+	d.assertView("> Work")
+}
+
+func TestStorageError(t *testing.T) {
+	storage, _ := seed(t)
+	d := newDriver(t, storage, config.UI{})
+
+	storage.Err = errors.New("disk on fire")
+	d.press("enter")
+	d.assertView("Current error: disk on fire")
+}
+
+func TestLiveChanges(t *testing.T) {
+	storage, work := seed(t)
+	d := newDriver(t, storage, config.UI{})
+
+	d.press("enter")
+
+	// a change made by another process arrives through the watch
+	_, err := storage.StoreTask(context.Background(), &tasks.Task{ListID: work.ID, Text: "from elsewhere"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	storage.Notify()
+	d.settle()
+	d.assertView("from elsewhere")
+}
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"context"
+	"testing"
+
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service/fake"
+)
+
+func TestUpdateTaskResponse(t *testing.T) {
+	m, err := New(fake.New(), config.UI{Theme: ThemeNoColor})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.tasks = []*tasks.Task{{ID: "1", Text: "write report"}, {ID: "2", Text: "call Bob"}}
+	m.cursorTasks = 0
+
+	// the cursor moved away from the task before its update arrived
+	m.Update(&updateTaskResponse{task: &tasks.Task{ID: "2", Text: "call Bob", Completed: true}})
+
+	if m.tasks[0].Text != "write report" || !m.tasks[1].Completed {
+		t.Errorf("tasks = %v %v, want call Bob completed", m.tasks[0], m.tasks[1])
+	}
+
+	// the task is gone after a reload, the response is dropped
+	m.tasks = []*tasks.Task{}
+	m.Update(&updateTaskResponse{task: &tasks.Task{ID: "1", Completed: true}})
+
+	if len(m.tasks) != 0 {
+		t.Errorf("tasks = %v, want none", m.tasks)
+	}
+}
+
+func TestCommandsCopyState(t *testing.T) {
+	storage := fake.New()
+	m, err := New(storage, config.UI{Theme: ThemeNoColor})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing to fetch or toggle without lists or tasks
+	if m.getTasks() != nil || m.toggleTask() != nil {
+		t.Fatal("commands were returned for empty lists and tasks")
+	}
+
+	m.cursorLists = 1
+	m.lists = []*lists.List{{ID: "work"}}
+
+	if m.getTasks() != nil {
+		t.Error("getTasks returned a command for a cursor after the last list")
+	}
+
+	task, _ := storage.StoreTask(context.Background(), &tasks.Task{ListID: "work", Text: "write report"})
+	m.tasks = []*tasks.Task{task}
+	cmd := m.toggleTask()
+
+	// the tasks are reloaded before the command runs
+	m.tasks = nil
+	msg, ok := cmd().(*updateTaskResponse)
+
+	if !ok || msg.task.ID != task.ID || !msg.task.Completed {
+		t.Errorf("toggle = %#v, want write report completed", msg)
+	}
+}
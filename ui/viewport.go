@@ -0,0 +1,273 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/julez-dev/go2todo/repo/tasks"
+)
+
+const (
+	// taskPageSize is the number of tasks of a list loaded at once
+	taskPageSize = 200
+	// loadAhead is the distance of the cursor to the last loaded task at which the next page is loaded
+	loadAhead = 20
+)
+
+type moreTasksResponse struct {
+	listID string
+	offset int
+	tasks  []*tasks.Task
+	total  int
+}
+
+// loadMoreTasks fetches up to limit tasks of the open list after the loaded ones
+func (m *model) loadMoreTasks(limit int) tea.Cmd {
+	if m.loading || len(m.tasks) >= m.totalTasks || m.cursorLists >= len(m.lists) {
+		return nil
+	}
+
+	m.loading = true
+	listID, offset := m.lists[m.cursorLists].ID, len(m.tasks)
+
+	return func() tea.Msg {
+		page, total, err := m.storage.GetTasksPage(context.TODO(), listID, offset, limit)
+
+		if err != nil {
+			return &errorResponse{err: err}
+		}
+
+		return &moreTasksResponse{listID: listID, offset: offset, tasks: page, total: total}
+	}
+}
+
+// addTasks appends a page of tasks, unless the open list changed since it was requested
+func (m *model) addTasks(msg *moreTasksResponse) tea.Cmd {
+	m.loading = false
+
+	if m.page != viewTasksPage || m.cursorLists >= len(m.lists) || m.lists[m.cursorLists].ID != msg.listID || len(m.tasks) != msg.offset {
+		return nil
+	}
+
+	m.tasks = append(m.tasks, msg.tasks...)
+	m.totalTasks = msg.total
+
+	if m.selectAll {
+		m.selectLoaded()
+	}
+
+	// tasks were deleted in the meantime, there is nothing left to load
+	if len(msg.tasks) == 0 {
+		m.totalTasks = len(m.tasks)
+	}
+
+	if m.toEnd {
+		if len(m.tasks) < m.totalTasks {
+			return m.loadMoreTasks(m.totalTasks - len(m.tasks))
+		}
+
+		m.toEnd = false
+		m.cursorTasks = len(m.tasks) - 1
+	}
+
+	return m.selectJumpTarget()
+}
+
+// selectJumpTarget selects the task the global search jumped to, loading further pages until it arrived
+func (m *model) selectJumpTarget() tea.Cmd {
+	if m.jumpTo == "" {
+		return nil
+	}
+
+	if index := indexOfTask(m.tasks, m.jumpTo); index >= 0 {
+		m.cursorTasks = index
+		m.jumpTo = ""
+
+		return nil
+	}
+
+	if len(m.tasks) < m.totalTasks {
+		return m.loadMoreTasks(taskPageSize)
+	}
+
+	m.jumpTo = ""
+
+	return nil
+}
+
+// loadNextPage loads the next page of tasks once the cursor comes close to the last loaded one
+func (m *model) loadNextPage() tea.Cmd {
+	if m.page != viewTasksPage || m.cursorTasks < len(m.tasks)-loadAhead {
+		return nil
+	}
+
+	return m.loadMoreTasks(taskPageSize)
+}
+
+// offset returns the scroll offset of the current page, the index of its first visible line
+func (m *model) offset() *int {
+	switch m.page {
+	case viewTasksPage:
+		return &m.offsetTasks
+	case searchPage:
+		return &m.offsetSearch
+	case detailPage:
+		return &m.offsetDetail
+	}
+
+	return &m.offsetLists
+}
+
+// bodyHeight returns the number of lines available for the items of the current page,
+// 0 if the size of the terminal is unknown
+func (m *model) bodyHeight(lines int) int {
+	if m.height == 0 {
+		return 0
+	}
+
+	height := m.height - strings.Count(m.header(), "\n") - strings.Count(m.footer(), "\n") - 1
+
+	// the scroll indicator takes a line of its own
+	if lines > height {
+		height--
+	}
+
+	if height < 1 {
+		height = 1
+	}
+
+	return height
+}
+
+// scroll moves the viewport of the current page so that the cursor stays visible
+func (m *model) scroll() {
+	lines, itemLines := m.body()
+	height := m.bodyHeight(len(lines))
+	offset := m.offset()
+
+	if height == 0 || len(lines) <= height {
+		*offset = 0
+		return
+	}
+
+	if cursor := *m.cursor(); cursor < len(itemLines) && itemLines[cursor] >= 0 {
+		line := itemLines[cursor]
+
+		// the header of a group is shown together with its first task
+		if m.page == searchPage && cursor < len(m.groups) && (cursor == 0 || m.groups[cursor-1] != m.groups[cursor]) {
+			line--
+		}
+
+		if line < *offset {
+			*offset = line
+		}
+
+		if itemLines[cursor] >= *offset+height {
+			*offset = itemLines[cursor] - height + 1
+		}
+	}
+
+	*offset = clampCursor(*offset, len(lines)-height+1)
+}
+
+// movePage scrolls a page of the viewport in the direction of delta, the cursor moves along
+func (m *model) movePage(delta int) {
+	lines, _ := m.body()
+	height := m.bodyHeight(len(lines))
+
+	if height == 0 {
+		height = 10
+	}
+
+	*m.offset() += delta * height
+
+	for i := 0; i < height; i++ {
+		m.move(delta, false)
+	}
+}
+
+// moveToEdge moves the cursor to the first or, if last is set, the last matching item.
+// The remaining tasks of a list are loaded first
+func (m *model) moveToEdge(last bool) tea.Cmd {
+	// the detail page has no cursor, its notes are scrolled instead
+	if m.page == detailPage {
+		lines, _ := m.body()
+		m.offsetDetail = 0
+
+		if last {
+			m.offsetDetail = len(lines)
+		}
+
+		return nil
+	}
+
+	cursor := m.cursor()
+
+	if !last {
+		*cursor = 0
+		m.keepCursorOnMatch()
+
+		return nil
+	}
+
+	if m.page == viewTasksPage && len(m.tasks) < m.totalTasks {
+		m.toEnd = true
+		return m.loadMoreTasks(m.totalTasks - len(m.tasks))
+	}
+
+	*cursor = m.itemCount() - 1
+	m.keepCursorOnMatch()
+
+	return nil
+}
+
+// indicator describes the visible part of the items, like ↑↓ 21-40 of 120
+func (m *model) indicator(lines int, itemLines []int, offset, height int) string {
+	count, first, last := 0, 0, 0
+
+	// the notes of the detail page are counted in lines
+	if m.page == detailPage {
+		count, first, last = lines, offset+1, offset+height
+
+		if last > lines {
+			last = lines
+		}
+	}
+
+	for i, line := range itemLines {
+		if line < 0 || !m.matches(i) {
+			continue
+		}
+
+		count++
+
+		if line >= offset && line < offset+height {
+			if first == 0 {
+				first = count
+			}
+
+			last = count
+		}
+	}
+
+	arrows := ""
+
+	if offset > 0 {
+		arrows += "↑"
+	}
+
+	if offset+height < lines {
+		arrows += "↓"
+	}
+
+	total := fmt.Sprint(count)
+
+	// the tasks of a list are only loaded while scrolling
+	if m.page == viewTasksPage && m.query == "" && len(m.tasks) < m.totalTasks {
+		total = fmt.Sprint(m.totalTasks)
+	}
+
+	return m.theme.status.Render(fmt.Sprintf("  %s %d-%d of %s", arrows, first, last, total))
+}
@@ -0,0 +1,93 @@
+package ui_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/repo/lists"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service/fake"
+)
+
+// seedLong creates the list Long with the tasks task 001 to task 250, more than a page of tasks
+func seedLong(t *testing.T) *fake.Service {
+	t.Helper()
+
+	storage := fake.New()
+	ctx := context.Background()
+	long, _ := storage.StoreList(ctx, &lists.List{Name: "Long"})
+
+	for i := 1; i <= 250; i++ {
+		_, err := storage.StoreTask(ctx, &tasks.Task{ListID: long.ID, Text: fmt.Sprintf("task %03d", i)})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return storage
+}
+
+func TestScroll(t *testing.T) {
+	d := newDriver(t, seedLong(t), config.UI{})
+
+	// the count includes the tasks which are not loaded yet
+	d.press("enter")
+	d.assertView("> task 001", "task 035", "↓ 1-35 of 250")
+	d.assertNotView("task 036")
+
+	// the viewport follows the cursor
+	for i := 0; i < 35; i++ {
+		d.press("j")
+	}
+
+	d.assertView("> task 036", "↑↓ 2-36 of 250")
+	d.assertNotView("task 001")
+
+	d.press("pgdown")
+	d.assertView("> task 071", "↑↓ 37-71 of 250")
+
+	d.press("pgup")
+	d.assertView("> task 036", "↑↓ 2-36 of 250")
+
+	// the last tasks are loaded before the cursor moves to the end
+	d.press("G")
+	d.assertView("> task 250", "↑ 216-250 of 250")
+
+	d.press("g")
+	d.assertView("> task 001", "↓ 1-35 of 250")
+
+	// a smaller terminal shows fewer tasks
+	d.send(tea.WindowSizeMsg{Width: 80, Height: 20})
+	d.assertView("task 015", "↓ 1-15 of 250")
+	d.assertNotView("task 016")
+}
+
+func TestScrollNotes(t *testing.T) {
+	storage, work := seed(t)
+	lines := []string{}
+
+	for i := 1; i <= 60; i++ {
+		lines = append(lines, fmt.Sprintf("line %02d", i))
+	}
+
+	// a blank line between the lines keeps them paragraphs of their own, the notes have 119 lines
+	storage.StoreTask(context.Background(), &tasks.Task{ListID: work.ID, Text: "write mail", Notes: strings.Join(lines, "\n\n")})
+	d := newDriver(t, storage, config.UI{})
+
+	d.press("enter", "G", "enter")
+	d.assertView("line 01", "↓ 1-34 of 119")
+	d.assertNotView("line 60")
+
+	d.press("G")
+	d.assertView("line 60", "↑ 86-119 of 119")
+	d.assertNotView("line 01")
+
+	d.press("g")
+	d.assertView("line 01")
+	d.assertNotView("line 60")
+}
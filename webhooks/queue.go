@@ -0,0 +1,161 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julez-dev/go2todo/fileutil"
+)
+
+// delivery is a pending request to a webhook, stored as one file in the queue directory
+type delivery struct {
+	ID string `json:"id"`
+	// Hook is the index of the webhook in the configuration, URL guards against a changed configuration
+	Hook        int             `json:"hook"`
+	URL         string          `json:"url"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// claimedExt is appended to the file of a delivery while a dispatcher sends it
+const claimedExt = ".sending"
+
+// staleClaim is the age after which a claim is considered left over by a crashed process,
+// it is far longer than an attempt takes
+const staleClaim = 10 * time.Minute
+
+// queue persists the pending deliveries, so they survive restarts. Several processes
+// may share the directory, each delivery is claimed before it is sent
+type queue struct {
+	dir string
+}
+
+func newQueue(dir string) (*queue, error) {
+	err := os.MkdirAll(dir, 0o755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &queue{dir: dir}, nil
+}
+
+// fileName sorts the deliveries by their creation
+func (q *queue) fileName(d *delivery) string {
+	return filepath.Join(q.dir, d.CreatedAt.UTC().Format("20060102T150405.000000000")+"-"+d.ID+".json")
+}
+
+func (q *queue) save(d *delivery) error {
+	data, err := json.Marshal(d)
+
+	if err != nil {
+		return err
+	}
+
+	return fileutil.WriteFileAtomic(q.fileName(d), data)
+}
+
+// claim renames the file of the delivery, so no other dispatcher sends it at the same
+// time. It reports false if another dispatcher claimed or finished it first
+func (q *queue) claim(d *delivery) (bool, error) {
+	claimed := q.fileName(d) + claimedExt
+	err := os.Rename(q.fileName(d), claimed)
+
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	// the renamed file keeps its time, the claim is aged from now on
+	now := time.Now()
+
+	return true, os.Chtimes(claimed, now, now)
+}
+
+// requeue saves the claimed delivery for its next attempt and releases the claim
+func (q *queue) requeue(d *delivery) error {
+	err := q.save(d)
+
+	if err != nil {
+		return err
+	}
+
+	return q.remove(d)
+}
+
+// remove deletes the claimed delivery
+func (q *queue) remove(d *delivery) error {
+	err := os.Remove(q.fileName(d) + claimedExt)
+
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// pending returns all queued deliveries which are not claimed, oldest first. Stale claims
+// are released again, unreadable files are skipped
+func (q *queue) pending() ([]*delivery, error) {
+	entries, err := ioutil.ReadDir(q.dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	deliveries := []*delivery{}
+
+	for _, entry := range entries {
+		name := entry.Name()
+
+		// temporary files of WriteFileAtomic start with a dot
+		if entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		if filepath.Ext(name) == claimedExt && time.Since(entry.ModTime()) > staleClaim {
+			released := strings.TrimSuffix(name, claimedExt)
+
+			if os.Rename(filepath.Join(q.dir, name), filepath.Join(q.dir, released)) != nil {
+				continue
+			}
+
+			name = released
+		}
+
+		if filepath.Ext(name) != ".json" {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(q.dir, name))
+
+		if err != nil {
+			continue
+		}
+
+		d := &delivery{}
+
+		if json.Unmarshal(data, d) != nil {
+			continue
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,406 @@
+// Package webhooks sends the events of the storage to the configured URLs.
+// Deliveries go through a queue on disk and are retried with exponential
+// backoff, every attempt is recorded in a delivery log. Several processes may
+// deliver from the same queue, every delivery is sent by one of them
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/service"
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// SignatureHeader contains "sha256=" followed by the hex encoded HMAC-SHA256 of the body
+	SignatureHeader = "X-Go2todo-Signature"
+	EventHeader     = "X-Go2todo-Event"
+	DeliveryHeader  = "X-Go2todo-Delivery"
+)
+
+type Dispatcher struct {
+	hooks []config.Webhook
+	types [][]service.EventType
+	queue *queue
+
+	logFile *os.File
+	logL    *sync.Mutex
+
+	wake chan struct{}
+
+	// HTTPClient sends the deliveries, its timeout applies to every attempt
+	HTTPClient *http.Client
+	// MaxAttempts is the number of attempts before a delivery is given up
+	MaxAttempts int
+	// Backoff is the wait before the first retry, it doubles with every further one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// ErrorLog receives errors of the queue, failed deliveries only go to the delivery log
+	ErrorLog *log.Logger
+}
+
+// New creates a dispatcher keeping its queue and the deliveries.log in dir
+func New(dir string, hooks []config.Webhook) (*Dispatcher, error) {
+	types := make([][]service.EventType, len(hooks))
+
+	for i, hook := range hooks {
+		if !strings.HasPrefix(hook.URL, "http://") && !strings.HasPrefix(hook.URL, "https://") {
+			return nil, fmt.Errorf("webhooks: %q is not a http or https URL", hook.URL)
+		}
+
+		parsed, err := service.ParseEventTypes(hook.Events)
+
+		if err != nil {
+			return nil, fmt.Errorf("webhooks: %w", err)
+		}
+
+		types[i] = parsed
+	}
+
+	q, err := newQueue(filepath.Join(dir, "queue"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	logFile, err := os.OpenFile(filepath.Join(dir, "deliveries.log"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Dispatcher{
+		hooks:   hooks,
+		types:   types,
+		queue:   q,
+		logFile: logFile,
+		logL:    &sync.Mutex{},
+		wake:    make(chan struct{}, 1),
+		HTTPClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		MaxAttempts: 10,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Hour,
+		ErrorLog:    log.New(ioutil.Discard, "", 0),
+	}, nil
+}
+
+// Close closes the delivery log
+func (d *Dispatcher) Close() error {
+	return d.logFile.Close()
+}
+
+// Enqueue queues a delivery of the event for every webhook interested in it.
+// It is meant to be registered as after hook of a service.EventBus
+func (d *Dispatcher) Enqueue(event *service.Event) {
+	payload, err := json.Marshal(event)
+
+	if err != nil {
+		d.ErrorLog.Printf("webhooks: %v", err)
+		return
+	}
+
+	queued := false
+
+	for i, hook := range d.hooks {
+		if !wants(d.types[i], event.Type) {
+			continue
+		}
+
+		now := time.Now()
+
+		err = d.queue.save(&delivery{
+			ID:          uuid.NewV4().String(),
+			Hook:        i,
+			URL:         hook.URL,
+			Event:       string(event.Type),
+			Payload:     payload,
+			NextAttempt: now,
+			CreatedAt:   now,
+		})
+
+		if err != nil {
+			d.ErrorLog.Printf("webhooks: could not queue delivery to %s: %v", hook.URL, err)
+			continue
+		}
+
+		queued = true
+	}
+
+	if queued {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func wants(types []service.EventType, t service.EventType) bool {
+	if len(types) == 0 {
+		return true
+	}
+
+	for _, wanted := range types {
+		if wanted == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Run sends the queued deliveries until ctx is done, including the ones
+// left over from previous runs
+func (d *Dispatcher) Run(ctx context.Context) {
+	for {
+		wait := d.deliverDue(ctx)
+
+		timer := time.NewTimer(wait)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-d.wake:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// deliverDue attempts all deliveries which are due and returns the time until the next one is
+func (d *Dispatcher) deliverDue(ctx context.Context) time.Duration {
+	pending, err := d.queue.pending()
+
+	if err != nil {
+		d.ErrorLog.Printf("webhooks: %v", err)
+		return d.Backoff
+	}
+
+	wait := d.MaxBackoff
+
+	for _, delivery := range pending {
+		if ctx.Err() != nil {
+			return 0
+		}
+
+		if until := time.Until(delivery.NextAttempt); until > 0 {
+			if until < wait {
+				wait = until
+			}
+
+			continue
+		}
+
+		claimed, err := d.queue.claim(delivery)
+
+		if err != nil {
+			d.ErrorLog.Printf("webhooks: could not claim delivery %s: %v", delivery.ID, err)
+			continue
+		}
+
+		// another process using the same queue sends it
+		if !claimed {
+			continue
+		}
+
+		d.attempt(ctx, delivery)
+
+		// the delivery is either done or scheduled again
+		if until := time.Until(delivery.NextAttempt); delivery.Attempts < d.MaxAttempts && until < wait {
+			wait = until
+		}
+	}
+
+	if wait < 0 {
+		wait = 0
+	}
+
+	return wait
+}
+
+// attempt sends the claimed delivery once and requeues or removes it
+func (d *Dispatcher) attempt(ctx context.Context, delivery *delivery) {
+	hook, ok := d.hook(delivery)
+
+	// the webhook was removed or changed in the configuration since the event was queued
+	if !ok {
+		d.finish(delivery)
+		return
+	}
+
+	delivery.Attempts++
+	started := time.Now()
+	status, err := d.send(ctx, hook, delivery)
+
+	entry := &logEntry{
+		Time:     started,
+		Delivery: delivery.ID,
+		URL:      delivery.URL,
+		Event:    delivery.Event,
+		Attempt:  delivery.Attempts,
+		Status:   status,
+		Duration: time.Since(started).String(),
+	}
+
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	retry := err != nil && retryable(status)
+
+	switch {
+	case err == nil:
+		entry.Result = "delivered"
+	case !retry:
+		entry.Result = "failed"
+	case delivery.Attempts >= d.MaxAttempts:
+		entry.Result = "given up"
+		retry = false
+	default:
+		entry.Result = "retrying"
+	}
+
+	d.log(entry)
+
+	if !retry {
+		d.finish(delivery)
+		return
+	}
+
+	delivery.NextAttempt = time.Now().Add(d.backoff(delivery.Attempts))
+
+	err = d.queue.requeue(delivery)
+
+	if err != nil {
+		d.ErrorLog.Printf("webhooks: could not requeue delivery %s: %v", delivery.ID, err)
+	}
+}
+
+func (d *Dispatcher) finish(delivery *delivery) {
+	err := d.queue.remove(delivery)
+
+	if err != nil {
+		d.ErrorLog.Printf("webhooks: could not remove delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// hook returns the webhook the delivery was queued for. Several webhooks may share
+// the URL with different secrets, so they are told apart by their index
+func (d *Dispatcher) hook(delivery *delivery) (config.Webhook, bool) {
+	if delivery.Hook < 0 || delivery.Hook >= len(d.hooks) || d.hooks[delivery.Hook].URL != delivery.URL {
+		return config.Webhook{}, false
+	}
+
+	return d.hooks[delivery.Hook], true
+}
+
+// backoff returns the wait after the given number of failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.Backoff
+
+	for i := 1; i < attempts && wait < d.MaxBackoff; i++ {
+		wait *= 2
+	}
+
+	if wait > d.MaxBackoff {
+		wait = d.MaxBackoff
+	}
+
+	return wait
+}
+
+// retryable reports whether a failure with the status may succeed later,
+// status is 0 if no response was received
+func retryable(status int) bool {
+	return status == 0 || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+// send posts the payload and returns the status of the response
+func (d *Dispatcher) send(ctx context.Context, hook config.Webhook, delivery *delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go2todo-webhooks")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+
+	if hook.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(hook.Secret, delivery.Payload))
+	}
+
+	resp, err := d.HTTPClient.Do(req)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// Sign returns the value of the signature header for the body. Receivers should
+// compute it themselves and compare both with a constant time comparison
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// logEntry is one line of the delivery log
+type logEntry struct {
+	Time     time.Time `json:"time"`
+	Delivery string    `json:"delivery"`
+	URL      string    `json:"url"`
+	Event    string    `json:"event"`
+	Attempt  int       `json:"attempt"`
+	Status   int       `json:"status,omitempty"`
+	Duration string    `json:"duration"`
+	Result   string    `json:"result"`
+	Error    string    `json:"error,omitempty"`
+}
+
+func (d *Dispatcher) log(entry *logEntry) {
+	data, err := json.Marshal(entry)
+
+	if err != nil {
+		return
+	}
+
+	d.logL.Lock()
+	defer d.logL.Unlock()
+
+	_, err = d.logFile.Write(append(data, '\n'))
+
+	if err != nil {
+		d.ErrorLog.Printf("webhooks: could not write delivery log: %v", err)
+	}
+}
@@ -0,0 +1,274 @@
+package webhooks_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/julez-dev/go2todo/config"
+	"github.com/julez-dev/go2todo/repo/tasks"
+	"github.com/julez-dev/go2todo/service"
+	"github.com/julez-dev/go2todo/webhooks"
+)
+
+// request is a delivery received by the receiver
+type request struct {
+	time      time.Time
+	secret    string
+	delivery  string
+	event     string
+	signature string
+	body      []byte
+}
+
+// receiver records the requests and answers them with the next status of its list, 200 once it is empty
+type receiver struct {
+	*httptest.Server
+
+	l        *sync.Mutex
+	requests []*request
+	statuses []int
+	// secrets are the secrets to try when matching the signature of a request
+	secrets []string
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+
+	r := &receiver{l: &sync.Mutex{}, statuses: statuses}
+
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+
+		received := &request{
+			time:      time.Now(),
+			delivery:  req.Header.Get(webhooks.DeliveryHeader),
+			event:     req.Header.Get(webhooks.EventHeader),
+			signature: req.Header.Get(webhooks.SignatureHeader),
+			body:      body,
+		}
+
+		r.l.Lock()
+		defer r.l.Unlock()
+
+		for _, secret := range r.secrets {
+			if hmac.Equal([]byte(received.signature), []byte(webhooks.Sign(secret, body))) {
+				received.secret = secret
+			}
+		}
+
+		r.requests = append(r.requests, received)
+
+		status := http.StatusOK
+
+		if len(r.statuses) > 0 {
+			status = r.statuses[0]
+			r.statuses = r.statuses[1:]
+		}
+
+		w.WriteHeader(status)
+	}))
+
+	t.Cleanup(r.Close)
+
+	return r
+}
+
+// wait returns the requests once n were received
+func (r *receiver) wait(t *testing.T, n int) []*request {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		r.l.Lock()
+		received := append([]*request{}, r.requests...)
+		r.l.Unlock()
+
+		if len(received) >= n {
+			return received
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	t.Fatalf("fewer than %d requests were received", n)
+
+	return nil
+}
+
+func newDispatcher(t *testing.T, dir string, hooks ...config.Webhook) *webhooks.Dispatcher {
+	t.Helper()
+
+	dispatcher, err := webhooks.New(dir, hooks)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { dispatcher.Close() })
+
+	dispatcher.Backoff = 20 * time.Millisecond
+	dispatcher.MaxBackoff = time.Second
+
+	return dispatcher
+}
+
+// run sends the deliveries of the dispatchers until the test ends
+func run(t *testing.T, dispatchers ...*webhooks.Dispatcher) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wg := &sync.WaitGroup{}
+
+	for _, dispatcher := range dispatchers {
+		wg.Add(1)
+
+		go func(dispatcher *webhooks.Dispatcher) {
+			defer wg.Done()
+
+			dispatcher.Run(ctx)
+		}(dispatcher)
+	}
+
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+}
+
+func created(text string) *service.Event {
+	return &service.Event{Type: service.TaskCreated, Task: &tasks.Task{ID: text, Text: text}}
+}
+
+func TestSignature(t *testing.T) {
+	r := newReceiver(t)
+	r.secrets = []string{"first", "second"}
+
+	// both webhooks share the URL, each delivery is signed with the secret of its own webhook
+	dispatcher := newDispatcher(t, t.TempDir(),
+		config.Webhook{URL: r.URL, Secret: "first"},
+		config.Webhook{URL: r.URL, Secret: "second"},
+		config.Webhook{URL: r.URL},
+	)
+	run(t, dispatcher)
+
+	dispatcher.Enqueue(created("write report"))
+
+	received := r.wait(t, 3)
+	secrets := map[string]int{}
+
+	for _, req := range received {
+		if req.event != string(service.TaskCreated) || !strings.Contains(string(req.body), "write report") {
+			t.Errorf("received %s %s, want task.created of write report", req.event, req.body)
+		}
+
+		secrets[req.secret]++
+	}
+
+	// the webhook without secret sends no signature
+	if secrets["first"] != 1 || secrets["second"] != 1 || secrets[""] != 1 {
+		t.Errorf("signed with %v, want first, second and one unsigned", secrets)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	r := newReceiver(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+	dispatcher := newDispatcher(t, t.TempDir(), config.Webhook{URL: r.URL})
+	run(t, dispatcher)
+
+	dispatcher.Enqueue(created("write report"))
+
+	received := r.wait(t, 3)
+
+	if received[0].delivery == "" || received[1].delivery != received[0].delivery || received[2].delivery != received[0].delivery {
+		t.Errorf("deliveries %s, %s, %s, want the same one retried", received[0].delivery, received[1].delivery, received[2].delivery)
+	}
+
+	// the wait doubles after every failed attempt
+	for i, want := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond} {
+		if wait := received[i+1].time.Sub(received[i].time); wait < want {
+			t.Errorf("retry %d after %v, want at least %v", i+1, wait, want)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := len(r.wait(t, 3)); n != 3 {
+		t.Errorf("%d requests, want no more after the delivery succeeded", n)
+	}
+}
+
+func TestGiveUp(t *testing.T) {
+	dir := t.TempDir()
+	r := newReceiver(t, http.StatusBadRequest, http.StatusBadGateway, http.StatusBadGateway)
+	dispatcher := newDispatcher(t, dir, config.Webhook{URL: r.URL})
+	dispatcher.MaxAttempts = 2
+	run(t, dispatcher)
+
+	// client errors are not retried
+	dispatcher.Enqueue(created("rejected"))
+	r.wait(t, 1)
+
+	dispatcher.Enqueue(created("unreachable"))
+	r.wait(t, 3)
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := len(r.wait(t, 3)); n != 3 {
+		t.Errorf("%d requests, want 3", n)
+	}
+
+	log, err := ioutil.ReadFile(filepath.Join(dir, "deliveries.log"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, result := range []string{`"result":"failed"`, `"result":"retrying"`, `"result":"given up"`} {
+		if !strings.Contains(string(log), result) {
+			t.Errorf("the delivery log misses %s:\n%s", result, log)
+		}
+	}
+
+	queued, _ := ioutil.ReadDir(filepath.Join(dir, "queue"))
+
+	if len(queued) != 0 {
+		t.Errorf("%d deliveries are left in the queue, want none", len(queued))
+	}
+}
+
+func TestSharedQueue(t *testing.T) {
+	dir := t.TempDir()
+	r := newReceiver(t)
+	hook := config.Webhook{URL: r.URL}
+
+	// every process of go2todo runs a dispatcher on the same directory
+	first := newDispatcher(t, dir, hook)
+	second := newDispatcher(t, dir, hook)
+
+	for i := 0; i < 20; i++ {
+		first.Enqueue(created("task"))
+	}
+
+	run(t, first, second)
+
+	r.wait(t, 20)
+	time.Sleep(100 * time.Millisecond)
+
+	received := r.wait(t, 20)
+	deliveries := map[string]int{}
+
+	for _, req := range received {
+		deliveries[req.delivery]++
+	}
+
+	if len(received) != 20 || len(deliveries) != 20 {
+		t.Errorf("%d requests of %d deliveries, want each of the 20 sent once", len(received), len(deliveries))
+	}
+}